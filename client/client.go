@@ -8,25 +8,51 @@ import (
 	"strings"
 
 	"github.com/nilayrajderkar/redis-implementation/resp"
-	"github.com/nilayrajderkar/redis-implementation/server"
 )
 
-func toString(resp interface{}) string {
-	switch v := resp.(type) {
-	case string:
-		return v
-	case *string:
-		if v != nil {
-			return *v
+func toString(value resp.Value) string {
+	switch value.Kind() {
+	case resp.SimpleString, resp.Bulk, resp.Verbatim:
+		return value.Str()
+	case resp.Integer:
+		return fmt.Sprintf("(integer) %d", value.Int())
+	case resp.Error:
+		return "(error) " + value.Str()
+	case resp.Null, resp.NullArray:
+		return "(nil)"
+	case resp.Array, resp.Set, resp.Push:
+		return listToString(value.Elems())
+	case resp.Map:
+		pairs := value.Elems()
+		if len(pairs) == 0 {
+			return "(empty hash)"
 		}
-	case []byte:
-		return string(v)
-	default:
-		return fmt.Sprintf("%v", v)
+		lines := make([]string, len(pairs)/2)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%d# %s => %s", i+1, toString(pairs[2*i]), toString(pairs[2*i+1]))
+		}
+		return strings.Join(lines, "\n")
+	case resp.Double:
+		return fmt.Sprintf("(double) %v", value.Float())
+	case resp.Boolean:
+		return fmt.Sprintf("(%t)", value.Bool())
+	case resp.BigNumber:
+		return "(big number) " + value.Str()
 	}
 	return ""
 }
 
+func listToString(elements []resp.Value) string {
+	if len(elements) == 0 {
+		return "(empty array)"
+	}
+	lines := make([]string, len(elements))
+	for i, element := range elements {
+		lines[i] = fmt.Sprintf("%d) %s", i+1, toString(element))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func StartClient() error {
 	// Connect to Redis server
 	conn, err := net.Dial("tcp", "localhost:6379")
@@ -35,6 +61,8 @@ func StartClient() error {
 	}
 	defer conn.Close()
 
+	reader := resp.NewReader(conn)
+
 	fmt.Println("Connected to Redis server. Type your commands (press Ctrl+C to quit):")
 
 	// Create a scanner to read user input
@@ -83,23 +111,25 @@ func StartClient() error {
 		}
 
 		// Convert each part into an array element, removing quotes if present
-		args := make([]interface{}, len(parts))
+		args := make([]resp.Value, len(parts))
 		for i, part := range parts {
 			// Remove surrounding quotes if present
 			if len(part) >= 2 && part[0] == '"' && part[len(part)-1] == '"' {
 				part = part[1 : len(part)-1]
 			}
-			args[i] = part
+			args[i] = resp.NewBulkString(part)
 		}
 
 		// Serialize the array of command and arguments
-		serializedInput := resp.Serialize(args)
+		serializedInput := resp.Serialize(resp.NewArray(args...))
+
+		if _, err := conn.Write([]byte(serializedInput)); err != nil {
+			return fmt.Errorf("failed to send command: %v", err)
+		}
 
-		deserializedResponse := server.HandleRequest(serializedInput)
-		response, err := resp.Deserialize(deserializedResponse)
+		response, _, err := reader.ReadValue()
 		if err != nil {
-			fmt.Printf("Error deserializing response: %v\n", err)
-			continue
+			return fmt.Errorf("failed to read response: %v", err)
 		}
 
 		fmt.Println(toString(response))
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+	"github.com/nilayrajderkar/redis-implementation/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":6379", "TCP address to listen on")
+	maxBulkLen := flag.Int("proto-max-bulk-len", resp.DefaultLimits.MaxBulkLen, "largest bulk string a client may send, in bytes")
+	maxMultibulkLen := flag.Int("proto-max-multibulk-len", resp.DefaultLimits.MaxMultibulkLen, "largest number of arguments a client may send in one command")
+	timeout := flag.Int("timeout", 0, "close the connection of clients idle for this many seconds, 0 to never close it")
+	flag.Parse()
+
+	srv := server.New(server.Config{
+		Limits: resp.Limits{
+			MaxBulkLen:      *maxBulkLen,
+			MaxMultibulkLen: *maxMultibulkLen,
+		},
+		Timeout: time.Duration(*timeout) * time.Second,
+	})
+
+	log.Printf("Redis server listening on %s", *addr)
+	if err := srv.ListenAndServe(*addr); err != nil {
+		log.Fatal(err)
+	}
+}
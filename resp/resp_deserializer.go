@@ -2,181 +2,302 @@ package resp
 
 import (
 	"errors"
+	"math/big"
 	"strconv"
 	"strings"
 )
 
-func deserializeString(s string) (*string, error) {
+// Each deserialize function parses the value at the start of s and returns
+// it along with the number of bytes it took up, so that aggregates can
+// carry on with their next element right after it.
+
+// deserializer holds the limits enforced while parsing one input, and how
+// deeply nested the value being parsed is
+type deserializer struct {
+	limits Limits
+	depth  int
+}
+
+func newDeserializer(limits Limits) *deserializer {
+	return &deserializer{limits: limits.withDefaults()}
+}
+
+// headerLine returns the text between the type byte and the first \r\n in s,
+// and the position right after that \r\n
+func headerLine(s string) (string, int, bool) {
 	index := strings.Index(s, "\r\n")
 	if index == -1 {
-		return nil, errors.New("invalid input string")
+		return "", 0, false
 	}
-	result := s[1:index]
-	return &result, nil
+	return s[1:index], index + 2, true
 }
 
-func deserializeInteger(s string) (*int, error) {
-	index := strings.Index(s, "\r\n")
-	if index == -1 {
-		return nil, errors.New("invalid input string for integer type")
+func deserializeString(s string) (Value, int, error) {
+	line, end, ok := headerLine(s)
+	if !ok {
+		return Value{}, 0, errors.New("invalid input string")
+	}
+	return NewSimpleString(line), end, nil
+}
+
+func deserializeInteger(s string) (Value, int, error) {
+	line, end, ok := headerLine(s)
+	if !ok {
+		return Value{}, 0, errors.New("invalid input string for integer type")
 	}
 
-	integerValue, err := strconv.Atoi(s[1:index])
+	integerValue, err := strconv.ParseInt(line, 10, 64)
 	if err != nil {
-		return nil, errors.New("cannot convert to integer, invalid value")
+		return Value{}, 0, errors.New("cannot convert to integer, invalid value")
 	}
-	return &integerValue, nil
+	return NewInteger(integerValue), end, nil
 }
 
-func deserializeBulkString(s string) (*string, error) {
-	index := strings.Index(s, "\r\n")
-	if index == -1 {
-		return nil, errors.New("invalid input string for bulk string type")
+func (d *deserializer) deserializeBulkString(s string) (Value, int, error) {
+	content, end, err := bulkContent(s, d.limits.MaxBulkLen)
+	if err != nil {
+		return Value{}, 0, err
+	}
+	return NewBulkString(content), end, nil
+}
+
+// bulkContent returns the content of a length prefixed string such as a
+// bulk string or a verbatim string. The content is located by its length
+// alone, so it may hold any bytes, \r\n included.
+func bulkContent(s string, maxLen int) (string, int, error) {
+	line, start, ok := headerLine(s)
+	if !ok {
+		return "", 0, errors.New("invalid input string for bulk string type")
 	}
-	// first character is the length of the string
-	length, err := strconv.Atoi(s[1:index])
+	length, err := strconv.Atoi(line)
 	if err != nil {
-		return nil, errors.New("invalid length for bulk string")
+		return "", 0, errors.New("invalid length for bulk string")
+	}
+	if length < 0 {
+		return "", 0, errors.New("invalid length for bulk string")
 	}
-	if length == -1 {
-		return nil, errors.New("key does not exist")
+	if length > maxLen {
+		return "", 0, errors.New("bulk string is too long")
 	}
 
 	// Check for second \r\n for the bulk string content
-	expectedEnd := index + 2 + length + 2
+	expectedEnd := start + length + 2
 	if expectedEnd > len(s) {
-		return nil, errors.New("bulk string is too short")
+		return "", 0, errors.New("bulk string is too short")
 	}
-	if s[index+2+length:expectedEnd] != "\r\n" {
-		return nil, errors.New("bulk string is not properly terminated")
+	if s[start+length:expectedEnd] != "\r\n" {
+		return "", 0, errors.New("bulk string is not properly terminated")
 	}
-	result := s[index+2 : index+2+length]
-	return &result, nil
+	return s[start : start+length], expectedEnd, nil
 }
 
-func deserializeError(s string) (*string, error) {
-	index := strings.Index(s, "\r\n")
-	if index == -1 {
-		return nil, errors.New("invalid input string for error type")
+func deserializeError(s string) (Value, int, error) {
+	line, end, ok := headerLine(s)
+	if !ok {
+		return Value{}, 0, errors.New("invalid input string for error type")
 	}
-	return nil, errors.New(s[1:index])
+	return NewError(line), end, nil
 }
 
-func deserializeArray(s string) (*[]interface{}, error) {
-	index := strings.Index(s, "\r\n")
-	if index == -1 {
-		return nil, errors.New("invalid input string for array type")
-	}
-	numberOfElements, err := strconv.Atoi(s[1:index])
+func (d *deserializer) deserializeArray(s string) (Value, int, error) {
+	elements, end, err := d.deserializeAggregate(s, 1)
 	if err != nil {
-		return nil, errors.New("invalid number of elements for array")
+		return Value{}, 0, err
 	}
-	result := make([]interface{}, 0, numberOfElements)
-	currentPos := index + 2
-
-	for i := 0; i < numberOfElements; i++ {
-		if currentPos >= len(s) {
-			return nil, errors.New("unexpected end of array")
-		}
+	return NewArray(elements...), end, nil
+}
 
-		// Process each element based on its type
-		elementType := s[currentPos]
-		var elementEnd int
-
-		switch elementType {
-		case '$': // Bulk String
-			// Find length
-			firstNewline := strings.Index(s[currentPos:], "\r\n")
-			if firstNewline == -1 {
-				return nil, errors.New("invalid bulk string format in array")
-			}
-			length, err := strconv.Atoi(s[currentPos+1 : currentPos+firstNewline])
-			if err != nil {
-				return nil, errors.New("invalid bulk string length in array")
-			}
-			elementEnd = currentPos + firstNewline + 2 + length + 2 // Include both \r\n
-		case ':': // Integer
-			nextNewline := strings.Index(s[currentPos:], "\r\n")
-			if nextNewline == -1 {
-				return nil, errors.New("invalid integer format in array")
-			}
-			elementEnd = currentPos + nextNewline + 2
-		case '+': // Simple String
-			nextNewline := strings.Index(s[currentPos:], "\r\n")
-			if nextNewline == -1 {
-				return nil, errors.New("invalid simple string format in array")
-			}
-			elementEnd = currentPos + nextNewline + 2
-		case '-': // Error
-			nextNewline := strings.Index(s[currentPos:], "\r\n")
-			if nextNewline == -1 {
-				return nil, errors.New("invalid error format in array")
-			}
-			elementEnd = currentPos + nextNewline + 2
-		case '*': // Nested Array
-			// Find the complete nested array by counting elements
-			nestedNewline := strings.Index(s[currentPos:], "\r\n")
-			if nestedNewline == -1 {
-				return nil, errors.New("invalid nested array format")
-			}
-			nestedCount, err := strconv.Atoi(s[currentPos+1 : currentPos+nestedNewline])
-			if err != nil {
-				return nil, errors.New("invalid nested array length")
-			}
-			// Recursively process the nested array
-			nested, err := Deserialize(s[currentPos:])
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, nested)
-			// Update elementEnd based on where the nested array processing ended
-			elementEnd = len(s)
-			for tmp := currentPos; tmp < len(s); tmp++ {
-				if nestedCount == 0 {
-					elementEnd = tmp
-					break
-				}
-				if strings.HasPrefix(s[tmp:], "\r\n") {
-					nestedCount--
-				}
-			}
-		default:
-			return nil, errors.New("invalid element type in array")
-		}
+// deserializeElements deserializes count consecutive values starting at
+// s[pos:] and returns them along with the position right after the last
+// one. Nested aggregates are handled by recursing into deserialize, which
+// reports exactly how much of s each element used.
+func (d *deserializer) deserializeElements(s string, pos, count int) ([]Value, int, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > d.limits.MaxDepth {
+		return nil, 0, errors.New("aggregate is nested too deeply")
+	}
 
-		if elementEnd > len(s) {
-			return nil, errors.New("unexpected end of array element")
+	result := make([]Value, 0, min(count, maxPreallocElements))
+	for i := 0; i < count; i++ {
+		if pos >= len(s) {
+			return nil, 0, errors.New("unexpected end of array")
 		}
-
-		element, err := Deserialize(s[currentPos:elementEnd])
+		element, n, err := d.deserialize(s[pos:])
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-
 		result = append(result, element)
-		currentPos = elementEnd
+		pos += n
 	}
+	return result, pos, nil
+}
 
-	return &result, nil
+func deserializeNull(s string) (Value, int, error) {
+	if !strings.HasPrefix(s, "_\r\n") {
+		return Value{}, 0, errors.New("invalid input string for null type")
+	}
+	return NewNull(), 3, nil
+}
+
+func parseDouble(s string) (float64, error) {
+	double, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errors.New("cannot convert to double, invalid value")
+	}
+	return double, nil
 }
 
-func Deserialize(s string) (interface{}, error) {
+func deserializeDouble(s string) (Value, int, error) {
+	line, end, ok := headerLine(s)
+	if !ok {
+		return Value{}, 0, errors.New("invalid input string for double type")
+	}
+	double, err := parseDouble(line)
+	if err != nil {
+		return Value{}, 0, err
+	}
+	return NewDouble(double), end, nil
+}
+
+func deserializeBoolean(s string) (Value, int, error) {
+	switch {
+	case strings.HasPrefix(s, "#t\r\n"):
+		return NewBoolean(true), 4, nil
+	case strings.HasPrefix(s, "#f\r\n"):
+		return NewBoolean(false), 4, nil
+	}
+	return Value{}, 0, errors.New("invalid input string for boolean type")
+}
+
+func deserializeBigNumber(s string) (Value, int, error) {
+	line, end, ok := headerLine(s)
+	if !ok {
+		return Value{}, 0, errors.New("invalid input string for big number type")
+	}
+	bigNumber, ok := new(big.Int).SetString(line, 10)
+	if !ok {
+		return Value{}, 0, errors.New("cannot convert to big number, invalid value")
+	}
+	return NewBigNumber(bigNumber), end, nil
+}
+
+func (d *deserializer) deserializeVerbatim(s string) (Value, int, error) {
+	// Verbatim strings are laid out exactly like bulk strings
+	content, end, err := bulkContent(s, d.limits.MaxBulkLen)
+	if err != nil {
+		return Value{}, 0, err
+	}
+	if len(content) < 4 || content[3] != ':' {
+		return Value{}, 0, errors.New("invalid format for verbatim string")
+	}
+	return NewVerbatim(content[:3], content[4:]), end, nil
+}
+
+// deserializeAggregate deserializes an array, map, set, push or attribute
+// header and its elements. Maps and attributes have two elements per entry.
+func (d *deserializer) deserializeAggregate(s string, perEntry int) ([]Value, int, error) {
+	line, start, ok := headerLine(s)
+	if !ok {
+		return nil, 0, errors.New("invalid input string for aggregate type")
+	}
+	numberOfEntries, err := strconv.Atoi(line)
+	if err != nil || numberOfEntries < 0 {
+		return nil, 0, errors.New("invalid number of elements for aggregate")
+	}
+	if numberOfEntries > d.limits.MaxMultibulkLen/perEntry {
+		return nil, 0, errors.New("too many elements for aggregate")
+	}
+	return d.deserializeElements(s, start, perEntry*numberOfEntries)
+}
+
+func (d *deserializer) deserializeAttribute(s string) (Value, int, error) {
+	attributes, end, err := d.deserializeAggregate(s, 2)
+	if err != nil {
+		return Value{}, 0, err
+	}
+	// The attribute is followed by the value it describes
+	value, n, err := d.deserialize(s[end:])
+	if err != nil {
+		return Value{}, 0, err
+	}
+	return value.WithAttributes(attributes...), end + n, nil
+}
+
+// isNull reports whether s starts with a null bulk string or null array
+func isNull(s string) bool {
+	return strings.HasPrefix(s[1:], "-1\r\n")
+}
+
+// Deserialize parses the RESP value at the start of s with DefaultLimits.
+// Error replies are returned as Values of Kind Error; err is only set when
+// s is not valid RESP.
+func Deserialize(s string) (Value, error) {
+	return DeserializeWithLimits(s, DefaultLimits)
+}
+
+// DeserializeWithLimits is like Deserialize but rejects values that exceed
+// limits
+func DeserializeWithLimits(s string, limits Limits) (Value, error) {
+	value, _, err := newDeserializer(limits).deserialize(s)
+	return value, err
+}
+
+// deserialize parses the RESP value at the start of s and returns it along
+// with the number of bytes it took up
+func (d *deserializer) deserialize(s string) (Value, int, error) {
 	if len(s) == 0 {
-		return nil, errors.New("empty input string")
+		return Value{}, 0, errors.New("empty input string")
 	}
 
 	switch s[0] {
 	case '*':
-		return deserializeArray(s)
+		if isNull(s) {
+			return NewNullArray(), 5, nil
+		}
+		return d.deserializeArray(s)
 	case '$':
-		return deserializeBulkString(s)
+		if isNull(s) {
+			return NewNull(), 5, nil
+		}
+		return d.deserializeBulkString(s)
 	case ':':
 		return deserializeInteger(s)
 	case '-':
 		return deserializeError(s)
 	case '+':
 		return deserializeString(s)
+	case '_':
+		return deserializeNull(s)
+	case ',':
+		return deserializeDouble(s)
+	case '#':
+		return deserializeBoolean(s)
+	case '(':
+		return deserializeBigNumber(s)
+	case '=':
+		return d.deserializeVerbatim(s)
+	case '%':
+		elements, end, err := d.deserializeAggregate(s, 2)
+		if err != nil {
+			return Value{}, 0, err
+		}
+		return NewMap(elements...), end, nil
+	case '~':
+		elements, end, err := d.deserializeAggregate(s, 1)
+		if err != nil {
+			return Value{}, 0, err
+		}
+		return NewSet(elements...), end, nil
+	case '>':
+		elements, end, err := d.deserializeAggregate(s, 1)
+		if err != nil {
+			return Value{}, 0, err
+		}
+		return NewPush(elements...), end, nil
+	case '|':
+		return d.deserializeAttribute(s)
 	default:
-		return nil, errors.New("invalid input string")
+		return Value{}, 0, errors.New("invalid input string")
 	}
 }
@@ -1,6 +1,7 @@
 package resp
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -34,17 +35,17 @@ func Test_deserializeSimpleString(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := deserializeString(tt.args.s)
+			got, _, err := deserializeString(tt.args.s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deserialize() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && got == nil {
-				t.Errorf("deserialize() returned nil, want %v", tt.want)
+			if !tt.wantErr && got.Kind() != SimpleString {
+				t.Errorf("deserialize() returned a %v, want a simple string", got.Kind())
 				return
 			}
-			if !tt.wantErr && *got != tt.want {
-				t.Errorf("deserialize() = %v, want %v", *got, tt.want)
+			if !tt.wantErr && got.Str() != tt.want {
+				t.Errorf("deserialize() = %v, want %v", got.Str(), tt.want)
 			}
 		})
 	}
@@ -57,7 +58,7 @@ func Test_deserializeInteger(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    int
+		want    int64
 		wantErr bool
 	}{
 		{
@@ -86,17 +87,17 @@ func Test_deserializeInteger(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := deserializeInteger(tt.args.s)
+			got, _, err := deserializeInteger(tt.args.s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deserialize_integer() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && got == nil {
-				t.Errorf("deserialize_integer() returned nil, want %v", tt.want)
+			if !tt.wantErr && got.Kind() != Integer {
+				t.Errorf("deserialize_integer() returned a %v, want an integer", got.Kind())
 				return
 			}
-			if !tt.wantErr && *got != tt.want {
-				t.Errorf("deserialize_integer() = %v, want %v", *got, tt.want)
+			if !tt.wantErr && got.Int() != tt.want {
+				t.Errorf("deserialize_integer() = %v, want %v", got.Int(), tt.want)
 			}
 		})
 	}
@@ -151,17 +152,17 @@ func Test_deserializeBulkString(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := deserializeBulkString(tt.args.s)
+			got, _, err := newDeserializer(DefaultLimits).deserializeBulkString(tt.args.s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deserialize_bulk_string() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && got == nil {
-				t.Errorf("deserialize_bulk_string() returned nil, want %v", tt.want)
+			if !tt.wantErr && got.Kind() != Bulk {
+				t.Errorf("deserialize_bulk_string() returned a %v, want a bulk string", got.Kind())
 				return
 			}
-			if !tt.wantErr && *got != tt.want {
-				t.Errorf("deserialize_bulk_string() = %v, want %v", *got, tt.want)
+			if !tt.wantErr && got.Str() != tt.want {
+				t.Errorf("deserialize_bulk_string() = %v, want %v", got.Str(), tt.want)
 			}
 		})
 	}
@@ -174,7 +175,7 @@ func Test_deserializeError(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    *string
+		want    string
 		wantErr bool
 	}{
 		{
@@ -182,7 +183,8 @@ func Test_deserializeError(t *testing.T) {
 			args: args{
 				s: "-ERR some error message\r\n",
 			},
-			wantErr: true,
+			want:    "ERR some error message",
+			wantErr: false,
 		},
 		{
 			name: "it should return error message for incorrect input",
@@ -194,13 +196,13 @@ func Test_deserializeError(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := deserializeError(tt.args.s)
+			got, _, err := deserializeError(tt.args.s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deserialize_error() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("deserialize_error() = %v, want %v", got, tt.want)
+			if !tt.wantErr && (got.Kind() != Error || got.Str() != tt.want) {
+				t.Errorf("deserialize_error() = %v %q, want error %q", got.Kind(), got.Str(), tt.want)
 			}
 		})
 	}
@@ -213,7 +215,7 @@ func Test_deserializeArray(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    *[]interface{}
+		want    Value
 		wantErr bool
 	}{
 		{
@@ -221,7 +223,7 @@ func Test_deserializeArray(t *testing.T) {
 			args: args{
 				s: "*2\r\n$5\r\nhello\r\n$5\r\nworld\r\n",
 			},
-			want:    &[]interface{}{"hello", "world"},
+			want:    NewArray(NewBulkString("hello"), NewBulkString("world")),
 			wantErr: false,
 		},
 		{
@@ -229,7 +231,7 @@ func Test_deserializeArray(t *testing.T) {
 			args: args{
 				s: "*3\r\n:1\r\n$5\r\nhello\r\n+OK\r\n",
 			},
-			want:    &[]interface{}{1, "hello", "OK"},
+			want:    NewArray(NewInteger(1), NewBulkString("hello"), NewSimpleString("OK")),
 			wantErr: false,
 		},
 		{
@@ -249,34 +251,13 @@ func Test_deserializeArray(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := deserializeArray(tt.args.s)
+			got, _, err := newDeserializer(DefaultLimits).deserializeArray(tt.args.s)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deserializeArray() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
-				if got == nil {
-					t.Errorf("deserializeArray() returned nil, want %v", tt.want)
-					return
-				}
-				if len(*got) != len(*tt.want) {
-					t.Errorf("deserializeArray() returned array of length %d, want %d", len(*got), len(*tt.want))
-					return
-				}
-				for i := range *got {
-					gotVal := (*got)[i]
-					if gotPtr, ok := gotVal.(*int); ok {
-						gotVal = *gotPtr
-					}
-					if gotPtr, ok := gotVal.(*string); ok {
-						gotVal = *gotPtr
-					}
-
-					if !reflect.DeepEqual(gotVal, (*tt.want)[i]) {
-						t.Errorf("deserializeArray()[%d] = %v (%T), want %v (%T)",
-							i, gotVal, gotVal, (*tt.want)[i], (*tt.want)[i])
-					}
-				}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deserializeArray() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -286,49 +267,127 @@ func Test_Deserialize(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   string
-		want    interface{}
+		want    Value
 		wantErr bool
 	}{
 		{
 			name:    "should deserialize simple string",
 			input:   "+OK\r\n",
-			want:    "OK",
+			want:    NewSimpleString("OK"),
 			wantErr: false,
 		},
 		{
 			name:    "should deserialize integer",
 			input:   ":123\r\n",
-			want:    123,
+			want:    NewInteger(123),
 			wantErr: false,
 		},
 		{
 			name:    "should deserialize bulk string",
 			input:   "$5\r\nhello\r\n",
-			want:    "hello",
+			want:    NewBulkString("hello"),
 			wantErr: false,
 		},
 		{
 			name:    "should deserialize error",
 			input:   "-Error message\r\n",
-			want:    nil,
-			wantErr: true,
+			want:    NewError("Error message"),
+			wantErr: false,
 		},
 		{
 			name:    "should deserialize array",
 			input:   "*2\r\n$5\r\nhello\r\n$5\r\nworld\r\n",
-			want:    []interface{}{"hello", "world"},
+			want:    NewArray(NewBulkString("hello"), NewBulkString("world")),
 			wantErr: false,
 		},
 		{
 			name:    "should deserialize mixed array",
 			input:   "*3\r\n:1\r\n$5\r\nhello\r\n+OK\r\n",
-			want:    []interface{}{1, "hello", "OK"},
+			want:    NewArray(NewInteger(1), NewBulkString("hello"), NewSimpleString("OK")),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize null bulk string",
+			input:   "$-1\r\n",
+			want:    NewNull(),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize null array",
+			input:   "*-1\r\n",
+			want:    NewNullArray(),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize array containing nulls",
+			input:   "*3\r\n$-1\r\n*-1\r\n:1\r\n",
+			want:    NewArray(NewNull(), NewNullArray(), NewInteger(1)),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize RESP3 null",
+			input:   "_\r\n",
+			want:    NewNull(),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize double",
+			input:   ",-3.25\r\n",
+			want:    NewDouble(-3.25),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize boolean",
+			input:   "#f\r\n",
+			want:    NewBoolean(false),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize verbatim string",
+			input:   "=15\r\ntxt:Some string\r\n",
+			want:    NewVerbatim("txt", "Some string"),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize map",
+			input:   "%2\r\n+first\r\n:1\r\n+second\r\n#t\r\n",
+			want:    NewMap(NewSimpleString("first"), NewInteger(1), NewSimpleString("second"), NewBoolean(true)),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize set",
+			input:   "~2\r\n$1\r\na\r\n,2.5\r\n",
+			want:    NewSet(NewBulkString("a"), NewDouble(2.5)),
+			wantErr: false,
+		},
+		{
+			name:    "should deserialize push",
+			input:   ">2\r\n+message\r\n(12345678901234567890\r\n",
+			want:    NewPush(NewSimpleString("message"), NewBigNumber(bigFromString("12345678901234567890"))),
 			wantErr: false,
 		},
+		{
+			name:    "should deserialize attribute and the value after it",
+			input:   "|1\r\n+ttl\r\n:100\r\n$5\r\nhello\r\n",
+			want:    NewBulkString("hello").WithAttributes(NewSimpleString("ttl"), NewInteger(100)),
+			wantErr: false,
+		},
+		{
+			name:    "should return error for invalid boolean",
+			input:   "#x\r\n",
+			want:    Value{},
+			wantErr: true,
+		},
+		{
+			name:    "should return error for other negative bulk lengths",
+			input:   "$-2\r\n",
+			want:    Value{},
+			wantErr: true,
+		},
 		{
 			name:    "should return error for invalid input",
 			input:   "invalid",
-			want:    nil,
+			want:    Value{},
 			wantErr: true,
 		},
 	}
@@ -340,39 +399,140 @@ func Test_Deserialize(t *testing.T) {
 				t.Errorf("Deserialize() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
-				if got == nil {
-					t.Errorf("Deserialize() returned nil, want %v", tt.want)
-					return
-				}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Deserialize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func bigFromString(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+func Test_deserializeNested(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{
+			name:  "It should deserialize a SCAN style reply",
+			input: "*2\r\n$1\r\n0\r\n*3\r\n$3\r\nfoo\r\n$7\r\nbar\r\nba\r\n$0\r\n\r\n",
+			want: NewArray(
+				NewBulkString("0"),
+				NewArray(NewBulkString("foo"), NewBulkString("bar\r\nba"), NewBulkString("")),
+			),
+		},
+		{
+			name: "It should deserialize an XRANGE style reply",
+			input: "*2\r\n" +
+				"*2\r\n$3\r\n1-0\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n" +
+				"*2\r\n$3\r\n2-0\r\n*4\r\n$1\r\nb\r\n$1\r\n2\r\n$1\r\nc\r\n$1\r\n3\r\n",
+			want: NewArray(
+				NewArray(NewBulkString("1-0"), NewArray(NewBulkString("a"), NewBulkString("1"))),
+				NewArray(NewBulkString("2-0"), NewArray(
+					NewBulkString("b"), NewBulkString("2"), NewBulkString("c"), NewBulkString("3"),
+				)),
+			),
+		},
+		{
+			name: "It should deserialize a COMMAND INFO style reply",
+			input: "*1\r\n*4\r\n$3\r\nget\r\n:2\r\n*2\r\n+readonly\r\n+fast\r\n" +
+				"*1\r\n*2\r\n$5\r\nflags\r\n*1\r\n+RO\r\n",
+			want: NewArray(NewArray(
+				NewBulkString("get"),
+				NewInteger(2),
+				NewArray(NewSimpleString("readonly"), NewSimpleString("fast")),
+				NewArray(NewArray(NewBulkString("flags"), NewArray(NewSimpleString("RO")))),
+			)),
+		},
+		{
+			name:  "It should deserialize an EXEC style reply with errors and nulls",
+			input: "*4\r\n+OK\r\n-ERR boom\r\n*-1\r\n*1\r\n$-1\r\n",
+			want: NewArray(
+				NewSimpleString("OK"), NewError("ERR boom"), NewNullArray(), NewArray(NewNull()),
+			),
+		},
+		{
+			name:  "It should deserialize empty arrays at every level",
+			input: "*2\r\n*0\r\n*1\r\n*1\r\n*0\r\n",
+			want:  NewArray(NewArray(), NewArray(NewArray(NewArray()))),
+		},
+		{
+			name:  "It should deserialize RESP3 aggregates nested in each other",
+			input: "%1\r\n+k\r\n~2\r\n*1\r\n:1\r\n>1\r\n%0\r\n",
+			want:  NewMap(NewSimpleString("k"), NewSet(NewArray(NewInteger(1)), NewPush(NewMap()))),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Trailing data must be left alone
+			got, n, err := newDeserializer(DefaultLimits).deserialize(tt.input + "+NEXT\r\n")
+			if err != nil {
+				t.Fatalf("deserialize() error = %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("deserialize() consumed %d bytes, want %d", n, len(tt.input))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deserialize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
 
-				// Handle array type
-				if gotArray, ok := got.(*[]interface{}); ok {
-					derefArray := make([]interface{}, len(*gotArray))
-					for i, v := range *gotArray {
-						// Dereference each element if it's a pointer
-						if intPtr, ok := v.(*int); ok {
-							derefArray[i] = *intPtr
-						} else if strPtr, ok := v.(*string); ok {
-							derefArray[i] = *strPtr
-						} else {
-							derefArray[i] = v
-						}
-					}
-					got = derefArray
-				} else {
-					// Handle single value pointer types
-					if gotPtr, ok := got.(*int); ok {
-						got = *gotPtr
-					}
-					if gotPtr, ok := got.(*string); ok {
-						got = *gotPtr
-					}
-				}
+func Test_deserializeTruncatedNesting(t *testing.T) {
+	inputs := []string{
+		"*2\r\n*2\r\n:1\r\n",
+		"*1\r\n*1\r\n$5\r\nab\r\n",
+		"*1\r\n*1\r\n*1\r\n",
+	}
+	for _, input := range inputs {
+		if _, err := Deserialize(input); err == nil {
+			t.Errorf("Deserialize(%q) error = nil, want an error", input)
+		}
+	}
+}
 
-				if !reflect.DeepEqual(got, tt.want) {
-					t.Errorf("Deserialize() = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
-				}
+func Test_DeserializeWithLimits(t *testing.T) {
+	limits := Limits{MaxBulkLen: 8, MaxMultibulkLen: 4, MaxDepth: 2}
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "It should reject a huge array length without allocating it",
+			input:   "*2147483647\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "It should reject a bulk string over the limit",
+			input:   "$9\r\n123456789\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "It should reject a map with too many entries",
+			input:   "%3\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "It should reject nesting deeper than the limit",
+			input:   "*1\r\n*1\r\n*0\r\n",
+			wantErr: true,
+		},
+		{
+			name:  "It should accept values within the limits",
+			input: "*2\r\n*1\r\n$8\r\n12345678\r\n:1\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DeserializeWithLimits(tt.input, limits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeserializeWithLimits() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
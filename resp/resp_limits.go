@@ -0,0 +1,51 @@
+package resp
+
+// Limits bounds what a Reader or Deserialize accepts from a peer, so that a
+// single malformed or hostile header cannot make it allocate without bound.
+// A zero field means the matching field of DefaultLimits.
+type Limits struct {
+	// MaxBulkLen is the largest bulk string or verbatim string, in bytes
+	MaxBulkLen int
+	// MaxMultibulkLen is the largest number of elements in an aggregate.
+	// Map and attribute entries count as two elements.
+	MaxMultibulkLen int
+	// MaxDepth is how deeply aggregates may be nested. A value that is not
+	// an aggregate has depth 0.
+	MaxDepth int
+	// MaxInlineLen is the longest line, in bytes, including inline
+	// commands and the headers of typed values
+	MaxInlineLen int
+}
+
+// DefaultLimits are the limits used unless others are set. They match the
+// defaults of Redis where Redis has one.
+var DefaultLimits = Limits{
+	MaxBulkLen:      512 * 1024 * 1024,
+	MaxMultibulkLen: 1024 * 1024,
+	MaxDepth:        128,
+	MaxInlineLen:    64 * 1024,
+}
+
+// withDefaults returns l with its zero fields replaced by DefaultLimits
+func (l Limits) withDefaults() Limits {
+	if l.MaxBulkLen <= 0 {
+		l.MaxBulkLen = DefaultLimits.MaxBulkLen
+	}
+	if l.MaxMultibulkLen <= 0 {
+		l.MaxMultibulkLen = DefaultLimits.MaxMultibulkLen
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultLimits.MaxDepth
+	}
+	if l.MaxInlineLen <= 0 {
+		l.MaxInlineLen = DefaultLimits.MaxInlineLen
+	}
+	return l
+}
+
+// These cap how much is allocated up front on the word of a length header.
+// Anything larger grows as the data actually arrives.
+const (
+	maxPreallocElements = 1024
+	bulkChunkSize       = 64 * 1024
+)
@@ -0,0 +1,295 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// ProtocolError is returned by Reader when the stream does not contain
+// valid RESP. Unlike io errors, it means the peer sent garbage and the
+// connection should not be reused.
+type ProtocolError struct {
+	Reason string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.Reason
+}
+
+func protocolError(reason string) error {
+	return &ProtocolError{Reason: reason}
+}
+
+// Reader reads RESP values off a stream. Unlike Deserialize, it waits for
+// more input when only part of a value has arrived.
+type Reader struct {
+	rd     *bufio.Reader
+	limits Limits
+}
+
+// NewReader returns a Reader that reads from rd with DefaultLimits
+func NewReader(rd io.Reader) *Reader {
+	if br, ok := rd.(*bufio.Reader); ok {
+		return &Reader{rd: br, limits: DefaultLimits}
+	}
+	return &Reader{rd: bufio.NewReader(rd), limits: DefaultLimits}
+}
+
+// SetLimits sets the limits enforced on everything read from now on
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits.withDefaults()
+}
+
+// ReadValue reads one complete value and reports how many bytes of the
+// stream it consumed. Error replies are returned as Values of Kind Error,
+// not through err.
+//
+// err is io.EOF if the stream ended cleanly before a value started,
+// io.ErrUnexpectedEOF if it ended in the middle of one, and a
+// *ProtocolError if the input is malformed or exceeds the Reader's limits.
+func (r *Reader) ReadValue() (Value, int, error) {
+	return r.readValue(0)
+}
+
+// readValue reads a value nested inside depth aggregates
+func (r *Reader) readValue(depth int) (Value, int, error) {
+	line, n, err := r.readLine(true)
+	if err != nil {
+		return Value{}, n, err
+	}
+	if len(line) == 0 {
+		return Value{}, n, protocolError("empty line")
+	}
+
+	// line points into the read buffer, so it is only valid until the next
+	// read; aggregates must not look at it once their elements are read
+	typeByte, payload := line[0], line[1:]
+	switch typeByte {
+	case '+':
+		return NewSimpleString(string(payload)), n, nil
+	case '-':
+		return NewError(string(payload)), n, nil
+	case ':':
+		integerValue, err := strconv.ParseInt(string(payload), 10, 64)
+		if err != nil {
+			return Value{}, n, protocolError("invalid integer")
+		}
+		return NewInteger(integerValue), n, nil
+	case '$':
+		length, err := strconv.Atoi(string(payload))
+		if err != nil || length < -1 || length > r.limits.MaxBulkLen {
+			return Value{}, n, protocolError("invalid bulk length")
+		}
+		if length == -1 {
+			return NewNull(), n, nil
+		}
+		content, read, err := r.readBulk(length)
+		n += read
+		if err != nil {
+			return Value{}, n, err
+		}
+		return NewBulk(content), n, nil
+	case '*':
+		count, err := strconv.Atoi(string(payload))
+		if err != nil || count < -1 || count > r.limits.MaxMultibulkLen {
+			return Value{}, n, protocolError("invalid multibulk length")
+		}
+		if count == -1 {
+			return NewNullArray(), n, nil
+		}
+		elements, read, err := r.readElements(count, depth)
+		n += read
+		if err != nil {
+			return Value{}, n, err
+		}
+		return NewArray(elements...), n, nil
+	case '_':
+		if len(payload) != 0 {
+			return Value{}, n, protocolError("invalid null")
+		}
+		return NewNull(), n, nil
+	case ',':
+		double, err := parseDouble(string(payload))
+		if err != nil {
+			return Value{}, n, protocolError("invalid double")
+		}
+		return NewDouble(double), n, nil
+	case '#':
+		switch string(payload) {
+		case "t":
+			return NewBoolean(true), n, nil
+		case "f":
+			return NewBoolean(false), n, nil
+		}
+		return Value{}, n, protocolError("invalid boolean")
+	case '(':
+		bigNumber, ok := new(big.Int).SetString(string(payload), 10)
+		if !ok {
+			return Value{}, n, protocolError("invalid big number")
+		}
+		return NewBigNumber(bigNumber), n, nil
+	case '=':
+		length, err := strconv.Atoi(string(payload))
+		if err != nil || length < 4 || length > r.limits.MaxBulkLen {
+			return Value{}, n, protocolError("invalid verbatim string length")
+		}
+		content, read, err := r.readBulk(length)
+		n += read
+		if err != nil {
+			return Value{}, n, err
+		}
+		if content[3] != ':' {
+			return Value{}, n, protocolError("invalid verbatim string format")
+		}
+		return NewVerbatim(string(content[:3]), string(content[4:])), n, nil
+	case '%', '|':
+		count, err := strconv.Atoi(string(payload))
+		if err != nil || count < 0 || count > r.limits.MaxMultibulkLen/2 {
+			return Value{}, n, protocolError("invalid map length")
+		}
+		elements, read, err := r.readElements(2*count, depth)
+		n += read
+		if err != nil {
+			return Value{}, n, err
+		}
+		if typeByte == '%' {
+			return NewMap(elements...), n, nil
+		}
+		// Attributes are followed by the value they describe
+		value, read, err := r.readValue(depth)
+		n += read
+		if err != nil {
+			return Value{}, n, unexpectedEOF(err)
+		}
+		return value.WithAttributes(elements...), n, nil
+	case '~', '>':
+		count, err := strconv.Atoi(string(payload))
+		if err != nil || count < 0 || count > r.limits.MaxMultibulkLen {
+			return Value{}, n, protocolError("invalid multibulk length")
+		}
+		elements, read, err := r.readElements(count, depth)
+		n += read
+		if err != nil {
+			return Value{}, n, err
+		}
+		if typeByte == '~' {
+			return NewSet(elements...), n, nil
+		}
+		return NewPush(elements...), n, nil
+	default:
+		return Value{}, n, protocolError("invalid type byte '" + string(typeByte) + "'")
+	}
+}
+
+// readBulk reads length bytes of content followed by \r\n
+func (r *Reader) readBulk(length int) ([]byte, int, error) {
+	// The content is read a chunk at a time, so a length header alone
+	// cannot make the Reader allocate more than the peer actually sends
+	content := make([]byte, 0, min(length+2, bulkChunkSize))
+	for len(content) < length+2 {
+		chunk := min(length+2-len(content), bulkChunkSize)
+		start := len(content)
+		content = append(content, make([]byte, chunk)...)
+		read, err := io.ReadFull(r.rd, content[start:])
+		if err != nil {
+			return nil, start + read, unexpectedEOF(err)
+		}
+	}
+	n := len(content)
+	if content[length] != '\r' || content[length+1] != '\n' {
+		return nil, n, protocolError("bulk string is not properly terminated")
+	}
+	return content[:length], n, nil
+}
+
+// readElements reads the count values that make up an aggregate nested
+// inside depth others
+func (r *Reader) readElements(count, depth int) ([]Value, int, error) {
+	if depth >= r.limits.MaxDepth {
+		return nil, 0, protocolError("too deeply nested")
+	}
+	n := 0
+	result := make([]Value, 0, min(count, maxPreallocElements))
+	for i := 0; i < count; i++ {
+		element, read, err := r.readValue(depth + 1)
+		n += read
+		if err != nil {
+			return nil, n, unexpectedEOF(err)
+		}
+		result = append(result, element)
+	}
+	return result, n, nil
+}
+
+// PeekByte returns the next byte of the stream without consuming it
+func (r *Reader) PeekByte() (byte, error) {
+	b, err := r.rd.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// ReadLine reads a line of plain text, such as an inline command, and
+// returns it without its terminator. Both \r\n and a bare \n are accepted
+// as the end of the line.
+func (r *Reader) ReadLine() ([]byte, int, error) {
+	return r.readLine(false)
+}
+
+// readLine reads up to and including the next \n and returns the line
+// without its terminator. If strict is set the line must end in \r\n.
+func (r *Reader) readLine(strict bool) ([]byte, int, error) {
+	line, err := r.rd.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// The line is longer than the buffer, so keep a copy of what
+		// was read and carry on until the terminator shows up
+		line = append([]byte(nil), line...)
+		for err == bufio.ErrBufferFull && len(line) <= r.limits.MaxInlineLen {
+			var rest []byte
+			rest, err = r.rd.ReadSlice('\n')
+			line = append(line, rest...)
+		}
+	}
+	n := len(line)
+	// The terminator does not count towards the limit
+	if err == bufio.ErrBufferFull || n-2 > r.limits.MaxInlineLen {
+		return nil, n, protocolError(lineTooLong(line[0], strict))
+	}
+	if err != nil {
+		if err == io.EOF && n > 0 {
+			return nil, n, io.ErrUnexpectedEOF
+		}
+		return nil, n, err
+	}
+	if n >= 2 && line[n-2] == '\r' {
+		return line[:n-2], n, nil
+	}
+	if strict {
+		return nil, n, protocolError("line is not terminated by \\r\\n")
+	}
+	return line[:n-1], n, nil
+}
+
+// lineTooLong returns the reason a line starting with first was rejected
+// for being too long, in the words Redis uses
+func lineTooLong(first byte, strict bool) string {
+	switch {
+	case !strict:
+		return "too big inline request"
+	case first == '*':
+		return "too big mbulk count string"
+	case first == '$':
+		return "too big bulk count string"
+	}
+	return "too big line"
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,319 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_ReaderReadValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Value
+		wantN   int
+		wantErr error
+	}{
+		{
+			name:  "It should read a simple string",
+			input: "+OK\r\n",
+			want:  NewSimpleString("OK"),
+			wantN: 5,
+		},
+		{
+			name:  "It should read an integer",
+			input: ":-42\r\n",
+			want:  NewInteger(-42),
+			wantN: 6,
+		},
+		{
+			name:  "It should read a bulk string containing \\r\\n",
+			input: "$7\r\nhel\r\nlo\r\n",
+			want:  NewBulkString("hel\r\nlo"),
+			wantN: 13,
+		},
+		{
+			name:  "It should read a bulk string holding NUL and non UTF-8 bytes",
+			input: "$5\r\n\x00\xff\r\n\x00\r\n",
+			want:  NewBulk([]byte{0x00, 0xff, '\r', '\n', 0x00}),
+			wantN: 11,
+		},
+		{
+			name:  "It should read an error reply as a value",
+			input: "-ERR boom\r\n",
+			want:  NewError("ERR boom"),
+			wantN: 11,
+		},
+		{
+			name:  "It should read a nested array",
+			input: "*2\r\n*2\r\n:1\r\n$2\r\nhi\r\n+OK\r\n",
+			want:  NewArray(NewArray(NewInteger(1), NewBulkString("hi")), NewSimpleString("OK")),
+			wantN: 25,
+		},
+		{
+			name:  "It should read a null bulk string",
+			input: "$-1\r\n",
+			want:  NewNull(),
+			wantN: 5,
+		},
+		{
+			name:  "It should read a null array",
+			input: "*-1\r\n",
+			want:  NewNullArray(),
+			wantN: 5,
+		},
+		{
+			name:  "It should read RESP3 scalars",
+			input: "*5\r\n_\r\n,1e-3\r\n#t\r\n(-99999999999999999999\r\n=8\r\nmkd:# hi\r\n",
+			want: NewArray(
+				NewNull(), NewDouble(0.001), NewBoolean(true),
+				NewBigNumber(bigFromString("-99999999999999999999")), NewVerbatim("mkd", "# hi"),
+			),
+			wantN: 56,
+		},
+		{
+			name:  "It should read a map nested in a set",
+			input: "~1\r\n%1\r\n+a\r\n*1\r\n:1\r\n",
+			want:  NewSet(NewMap(NewSimpleString("a"), NewArray(NewInteger(1)))),
+			wantN: 20,
+		},
+		{
+			name:  "It should read an attribute along with its value",
+			input: "|1\r\n+key\r\n+value\r\n>1\r\n+hi\r\n",
+			want:  NewPush(NewSimpleString("hi")).WithAttributes(NewSimpleString("key"), NewSimpleString("value")),
+			wantN: 27,
+		},
+		{
+			name:  "It should only consume the first value",
+			input: "+OK\r\n+NEXT\r\n",
+			want:  NewSimpleString("OK"),
+			wantN: 5,
+		},
+		{
+			name:    "It should return io.EOF on an empty stream",
+			input:   "",
+			wantErr: io.EOF,
+		},
+		{
+			name:    "It should return io.ErrUnexpectedEOF on a partial line",
+			input:   "+OK",
+			wantN:   3,
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "It should return io.ErrUnexpectedEOF on a partial bulk string",
+			input:   "$5\r\nhel",
+			wantN:   7,
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "It should return io.ErrUnexpectedEOF on a partial array",
+			input:   "*2\r\n:1\r\n",
+			wantN:   8,
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time so every value arrives split across reads
+			reader := NewReader(iotest.OneByteReader(strings.NewReader(tt.input)))
+			got, n, err := reader.ReadValue()
+			if err != tt.wantErr {
+				t.Errorf("ReadValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if n != tt.wantN {
+				t.Errorf("ReadValue() consumed %d bytes, want %d", n, tt.wantN)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ReaderProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "It should reject an unknown type byte",
+			input: "?3\r\n",
+		},
+		{
+			name:  "It should reject an invalid integer",
+			input: ":12a\r\n",
+		},
+		{
+			name:  "It should reject an invalid bulk length",
+			input: "$abc\r\nhello\r\n",
+		},
+		{
+			name:  "It should reject a bulk string with the wrong length",
+			input: "$3\r\nhello\r\n",
+		},
+		{
+			name:  "It should reject an invalid array length",
+			input: "*x\r\n",
+		},
+		{
+			name:  "It should reject an invalid boolean",
+			input: "#yes\r\n",
+		},
+		{
+			name:  "It should reject a verbatim string without a format",
+			input: "=4\r\nabcd\r\n",
+		},
+		{
+			name:  "It should reject a line without \\r",
+			input: "+OK\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := NewReader(strings.NewReader(tt.input)).ReadValue()
+			var protocolErr *ProtocolError
+			if !errors.As(err, &protocolErr) {
+				t.Errorf("ReadValue() error = %v, want a *ProtocolError", err)
+			}
+		})
+	}
+}
+
+func Test_ReaderWaitsForPartialFrames(t *testing.T) {
+	pr, pw := io.Pipe()
+	reader := NewReader(pr)
+
+	type result struct {
+		value Value
+		err   error
+	}
+	done := make(chan result)
+	go func() {
+		value, _, err := reader.ReadValue()
+		done <- result{value, err}
+	}()
+
+	for _, chunk := range []string{"*2\r\n$4\r", "\nECHO\r\n$", "2\r\nhi", "\r\n"} {
+		if _, err := io.WriteString(pw, chunk); err != nil {
+			t.Fatalf("write error = %v", err)
+		}
+	}
+
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("ReadValue() error = %v", got.err)
+	}
+	want := NewArray(NewBulkString("ECHO"), NewBulkString("hi"))
+	if !reflect.DeepEqual(got.value, want) {
+		t.Errorf("ReadValue() = %v, want %v", got.value, want)
+	}
+}
+
+func Test_ReaderLimits(t *testing.T) {
+	limits := Limits{MaxBulkLen: 8, MaxMultibulkLen: 4, MaxDepth: 2, MaxInlineLen: 16}
+	tests := []struct {
+		name       string
+		input      string
+		wantReason string
+	}{
+		{
+			name:       "It should reject a multibulk length over the limit",
+			input:      "*2147483647\r\n",
+			wantReason: "invalid multibulk length",
+		},
+		{
+			name:       "It should count map entries twice",
+			input:      "%3\r\n",
+			wantReason: "invalid map length",
+		},
+		{
+			name:       "It should reject a set over the limit",
+			input:      "~5\r\n",
+			wantReason: "invalid multibulk length",
+		},
+		{
+			name:       "It should reject a bulk length over the limit",
+			input:      "$9\r\n",
+			wantReason: "invalid bulk length",
+		},
+		{
+			name:       "It should reject a verbatim length over the limit",
+			input:      "=9\r\n",
+			wantReason: "invalid verbatim string length",
+		},
+		{
+			name:       "It should reject aggregates nested deeper than the limit",
+			input:      "*1\r\n*1\r\n*0\r\n",
+			wantReason: "too deeply nested",
+		},
+		{
+			name:       "It should reject a bulk count line over the limit",
+			input:      "$" + strings.Repeat("0", 20) + "\r\n",
+			wantReason: "too big bulk count string",
+		},
+		{
+			name:       "It should reject a line over the limit before it ends",
+			input:      "+" + strings.Repeat("a", 5000),
+			wantReason: "too big line",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(tt.input))
+			reader.SetLimits(limits)
+			_, _, err := reader.ReadValue()
+			var protocolErr *ProtocolError
+			if !errors.As(err, &protocolErr) || protocolErr.Reason != tt.wantReason {
+				t.Errorf("ReadValue() error = %v, want reason %q", err, tt.wantReason)
+			}
+		})
+	}
+}
+
+func Test_ReaderWithinLimits(t *testing.T) {
+	reader := NewReader(strings.NewReader("*2\r\n*1\r\n$8\r\n12345678\r\n:1\r\n"))
+	reader.SetLimits(Limits{MaxBulkLen: 8, MaxMultibulkLen: 2, MaxDepth: 2})
+	got, _, err := reader.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue() error = %v", err)
+	}
+	want := NewArray(NewArray(NewBulkString("12345678")), NewInteger(1))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadValue() = %v, want %v", got, want)
+	}
+}
+
+func Test_ReaderInlineLimit(t *testing.T) {
+	reader := NewReader(strings.NewReader(strings.Repeat("a", 16) + "\n" + strings.Repeat("b", 17) + "\r\n"))
+	reader.SetLimits(Limits{MaxInlineLen: 16})
+	if _, _, err := reader.ReadLine(); err != nil {
+		t.Fatalf("ReadLine() error = %v", err)
+	}
+	_, _, err := reader.ReadLine()
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) || protocolErr.Reason != "too big inline request" {
+		t.Errorf("ReadLine() error = %v, want too big inline request", err)
+	}
+}
+
+func Test_ReaderLargeBulk(t *testing.T) {
+	// Larger than a single read chunk
+	content := strings.Repeat("x", 3*bulkChunkSize+5)
+	reader := NewReader(strings.NewReader("$" + strconv.Itoa(len(content)) + "\r\n" + content + "\r\n"))
+	got, n, err := reader.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue() error = %v", err)
+	}
+	if got.Str() != content {
+		t.Errorf("ReadValue() returned %d bytes, want %d", len(got.Str()), len(content))
+	}
+	if want := len(content) + len(strconv.Itoa(len(content))) + 5; n != want {
+		t.Errorf("ReadValue() consumed %d bytes, want %d", n, want)
+	}
+}
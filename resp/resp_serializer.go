@@ -1,15 +1,18 @@
 package resp
 
 import (
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 func serializeString(s string) string {
 	return "+" + s + "\r\n"
 }
 
-func serializeInteger(i int) string {
-	return ":" + strconv.Itoa(i) + "\r\n"
+func serializeInteger(i int64) string {
+	return ":" + strconv.FormatInt(i, 10) + "\r\n"
 }
 
 func serializeBulkString(s string) string {
@@ -20,25 +23,107 @@ func serializeError(s string) string {
 	return "-" + s + "\r\n"
 }
 
-func serializeArray(elements []interface{}) string {
-	bulkString := ""
-	bulkString += "*" + strconv.Itoa(len(elements)) + "\r\n"
+// formatDouble formats f the way d2string in Redis does: integral values
+// that fit in a long long as integers, and others as the shortest
+// representation that reads back as the same number, with an exponent only
+// for very large and very small numbers. The special values are "inf",
+// "-inf" and "nan".
+func formatDouble(f float64) string {
+	abs := math.Abs(f)
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == 0 && math.Signbit(f):
+		return "-0"
+	case f == math.Trunc(f) && abs <= math.MaxInt64/2:
+		return strconv.FormatInt(int64(f), 10)
+	case abs >= 1e-6 && abs < 1e21:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	// Like Redis, the exponent is not padded to two digits
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	return strings.Replace(strings.Replace(s, "e-0", "e-", 1), "e+0", "e+", 1)
+}
+
+func serializeDouble(f float64) string {
+	return "," + formatDouble(f) + "\r\n"
+}
+
+func serializeBoolean(b bool) string {
+	if b {
+		return "#t\r\n"
+	}
+	return "#f\r\n"
+}
+
+func serializeBigNumber(n *big.Int) string {
+	return "(" + n.String() + "\r\n"
+}
+
+func serializeVerbatim(format, text string) string {
+	return "=" + strconv.Itoa(len(format)+1+len(text)) + "\r\n" + format + ":" + text + "\r\n"
+}
+
+// serializeAggregate encodes a header with the given type byte and count
+// followed by each of the elements
+func serializeAggregate(prefix string, count int, elements []Value) string {
+	var aggregate strings.Builder
+	aggregate.WriteString(prefix + strconv.Itoa(count) + "\r\n")
 	for _, element := range elements {
-		bulkString += Serialize(element)
+		aggregate.WriteString(Serialize(element))
 	}
-	return bulkString
-}
-
-func Serialize(element interface{}) string {
-	switch element := element.(type) {
-	case string:
-		return serializeBulkString(element)
-	case int:
-		return serializeInteger(element)
-	case []interface{}:
-		return serializeArray(element)
-	case error:
-		return serializeError(element.Error())
+	return aggregate.String()
+}
+
+func serializeArray(elements []Value) string {
+	return serializeAggregate("*", len(elements), elements)
+}
+
+// Serialize returns the RESP encoding of v. Every kind is encoded
+// natively, so RESP3 kinds come out in their RESP3 form; use a Writer to
+// encode values for a connection that speaks RESP2.
+func Serialize(v Value) string {
+	var attributes string
+	if v.attrs != nil {
+		attributes = serializeAggregate("|", len(v.attrs)/2, v.attrs)
+	}
+	return attributes + serializeValue(v)
+}
+
+func serializeValue(v Value) string {
+	switch v.kind {
+	case SimpleString:
+		return serializeString(v.str)
+	case Error:
+		return serializeError(v.str)
+	case Integer:
+		return serializeInteger(v.integer)
+	case Bulk:
+		return serializeBulkString(string(v.bytes))
+	case Array:
+		return serializeArray(v.elems)
+	case Null:
+		return "$-1\r\n"
+	case NullArray:
+		return "*-1\r\n"
+	case Map:
+		return serializeAggregate("%", len(v.elems)/2, v.elems)
+	case Set:
+		return serializeAggregate("~", len(v.elems), v.elems)
+	case Double:
+		return serializeDouble(v.double)
+	case Boolean:
+		return serializeBoolean(v.Bool())
+	case BigNumber:
+		return serializeBigNumber(v.big)
+	case Verbatim:
+		return serializeVerbatim(v.format, string(v.bytes))
+	case Push:
+		return serializeAggregate(">", len(v.elems), v.elems)
 	}
 	return ""
 }
@@ -1,7 +1,9 @@
 package resp
 
 import (
-	"errors"
+	"math"
+	"math/big"
+	"reflect"
 	"testing"
 )
 
@@ -35,7 +37,7 @@ func Test_serializeString(t *testing.T) {
 func Test_serializeInteger(t *testing.T) {
 	tests := []struct {
 		name string
-		arg  int
+		arg  int64
 		want string
 	}{
 		{
@@ -121,22 +123,22 @@ func Test_serializeError(t *testing.T) {
 func Test_serializeArray(t *testing.T) {
 	tests := []struct {
 		name string
-		arg  []interface{}
+		arg  []Value
 		want string
 	}{
 		{
 			name: "It should serialize array of strings",
-			arg:  []interface{}{"hello", "world"},
+			arg:  []Value{NewBulkString("hello"), NewBulkString("world")},
 			want: "*2\r\n$5\r\nhello\r\n$5\r\nworld\r\n",
 		},
 		{
 			name: "It should serialize array of mixed types",
-			arg:  []interface{}{1, "hello", errors.New("error")},
+			arg:  []Value{NewInteger(1), NewBulkString("hello"), NewError("error")},
 			want: "*3\r\n:1\r\n$5\r\nhello\r\n-error\r\n",
 		},
 		{
 			name: "It should serialize empty array",
-			arg:  []interface{}{},
+			arg:  []Value{},
 			want: "*0\r\n",
 		},
 	}
@@ -153,32 +155,97 @@ func Test_serializeArray(t *testing.T) {
 func Test_Serialize(t *testing.T) {
 	tests := []struct {
 		name string
-		arg  interface{}
+		arg  Value
 		want string
 	}{
 		{
 			name: "It should serialize string",
-			arg:  "hello",
+			arg:  NewBulkString("hello"),
 			want: "$5\r\nhello\r\n",
 		},
 		{
 			name: "It should serialize integer",
-			arg:  123,
+			arg:  NewInteger(123),
 			want: ":123\r\n",
 		},
 		{
 			name: "It should serialize array",
-			arg:  []interface{}{"hello", 123},
+			arg:  NewArray(NewBulkString("hello"), NewInteger(123)),
 			want: "*2\r\n$5\r\nhello\r\n:123\r\n",
 		},
 		{
 			name: "It should serialize error",
-			arg:  errors.New("error message"),
+			arg:  NewError("error message"),
 			want: "-error message\r\n",
 		},
 		{
-			name: "It should return empty string for unsupported type",
-			arg:  3.14,
+			name: "It should serialize null bulk string",
+			arg:  NewNull(),
+			want: "$-1\r\n",
+		},
+		{
+			name: "It should serialize null array",
+			arg:  NewNullArray(),
+			want: "*-1\r\n",
+		},
+		{
+			name: "It should serialize array containing null",
+			arg:  NewArray(NewNull(), NewBulkString("hello")),
+			want: "*2\r\n$-1\r\n$5\r\nhello\r\n",
+		},
+		{
+			name: "It should serialize double",
+			arg:  NewDouble(1.5),
+			want: ",1.5\r\n",
+		},
+		{
+			name: "It should serialize integral doubles without an exponent",
+			arg:  NewArray(NewDouble(1700000000000), NewDouble(-1234567), NewDouble(1e20)),
+			want: "*3\r\n,1700000000000\r\n,-1234567\r\n,100000000000000000000\r\n",
+		},
+		{
+			name: "It should only use an exponent for very large and very small doubles",
+			arg:  NewArray(NewDouble(1234567.25), NewDouble(0.000001), NewDouble(1.5e-7), NewDouble(2e21)),
+			want: "*4\r\n,1234567.25\r\n,0.000001\r\n,1.5e-7\r\n,2e+21\r\n",
+		},
+		{
+			name: "It should serialize infinite double",
+			arg:  NewDouble(math.Inf(-1)),
+			want: ",-inf\r\n",
+		},
+		{
+			name: "It should serialize boolean",
+			arg:  NewBoolean(true),
+			want: "#t\r\n",
+		},
+		{
+			name: "It should serialize big number",
+			arg:  NewBigNumber(new(big.Int).Lsh(big.NewInt(1), 100)),
+			want: "(1267650600228229401496703205376\r\n",
+		},
+		{
+			name: "It should serialize verbatim string",
+			arg:  NewVerbatim("txt", "Some string"),
+			want: "=15\r\ntxt:Some string\r\n",
+		},
+		{
+			name: "It should serialize map",
+			arg:  NewMap(NewBulkString("first"), NewInteger(1), NewBulkString("second"), NewSet(NewBulkString("a"))),
+			want: "%2\r\n$5\r\nfirst\r\n:1\r\n$6\r\nsecond\r\n~1\r\n$1\r\na\r\n",
+		},
+		{
+			name: "It should serialize push",
+			arg:  NewPush(NewBulkString("message"), NewBulkString("hello")),
+			want: ">2\r\n$7\r\nmessage\r\n$5\r\nhello\r\n",
+		},
+		{
+			name: "It should serialize attribute followed by its value",
+			arg:  NewBulkString("hello").WithAttributes(NewBulkString("ttl"), NewInteger(100)),
+			want: "|1\r\n$3\r\nttl\r\n:100\r\n$5\r\nhello\r\n",
+		},
+		{
+			name: "It should return empty string for the zero value",
+			arg:  Value{},
 			want: "",
 		},
 	}
@@ -191,3 +258,16 @@ func Test_Serialize(t *testing.T) {
 		})
 	}
 }
+
+func Test_SerializeNullRoundTrip(t *testing.T) {
+	for _, null := range []Value{NewNull(), NewNullArray()} {
+		got, err := Deserialize(Serialize(null))
+		if err != nil {
+			t.Errorf("Deserialize(Serialize(%v)) error = %v", null, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, null) {
+			t.Errorf("Deserialize(Serialize(%v)) = %v", null, got)
+		}
+	}
+}
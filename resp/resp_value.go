@@ -0,0 +1,272 @@
+package resp
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Kind identifies the RESP type of a Value
+type Kind int
+
+const (
+	// Invalid is the Kind of the zero Value
+	Invalid Kind = iota
+	SimpleString
+	Error
+	Integer
+	Bulk
+	Array
+	// Null is a null bulk string ($-1), or the RESP3 null (_)
+	Null
+	// NullArray is a null array (*-1)
+	NullArray
+
+	// The kinds below only exist in RESP3
+
+	Map
+	Set
+	Double
+	Boolean
+	BigNumber
+	Verbatim
+	Push
+)
+
+var kindNames = [...]string{
+	Invalid:      "invalid",
+	SimpleString: "simple string",
+	Error:        "error",
+	Integer:      "integer",
+	Bulk:         "bulk string",
+	Array:        "array",
+	Null:         "null",
+	NullArray:    "null array",
+	Map:          "map",
+	Set:          "set",
+	Double:       "double",
+	Boolean:      "boolean",
+	BigNumber:    "big number",
+	Verbatim:     "verbatim string",
+	Push:         "push",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Value is a single RESP value of any Kind. Values are built with the
+// New* functions and inspected with the accessor methods; the zero Value
+// has Kind Invalid.
+type Value struct {
+	kind Kind
+	// str holds the text of simple strings and errors, which can never
+	// contain \r or \n
+	str string
+	// bytes holds the payload of bulk strings and verbatim strings, which
+	// may be arbitrary binary data
+	bytes  []byte
+	format string
+	// integer holds integers and booleans (1 or 0)
+	integer int64
+	double  float64
+	big     *big.Int
+	// elems holds the elements of arrays, sets and pushes, and the keys
+	// and values of maps, alternating
+	elems []Value
+	// attrs holds RESP3 attributes sent along with the value as
+	// alternating keys and values
+	attrs []Value
+}
+
+// NewSimpleString returns a simple string. s must not contain \r or \n.
+func NewSimpleString(s string) Value {
+	return Value{kind: SimpleString, str: s}
+}
+
+// NewError returns an error reply such as "ERR unknown command". msg must
+// not contain \r or \n.
+func NewError(msg string) Value {
+	return Value{kind: Error, str: msg}
+}
+
+// NewInteger returns an integer
+func NewInteger(n int64) Value {
+	return Value{kind: Integer, integer: n}
+}
+
+// NewBulk returns a bulk string holding b. b is not copied.
+func NewBulk(b []byte) Value {
+	if b == nil {
+		b = []byte{}
+	}
+	return Value{kind: Bulk, bytes: b}
+}
+
+// NewBulkString returns a bulk string holding s
+func NewBulkString(s string) Value {
+	return NewBulk([]byte(s))
+}
+
+// NewArray returns an array holding elems
+func NewArray(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{kind: Array, elems: elems}
+}
+
+// NewNull returns a null bulk string
+func NewNull() Value {
+	return Value{kind: Null}
+}
+
+// NewNullArray returns a null array
+func NewNullArray() Value {
+	return Value{kind: NullArray}
+}
+
+// NewMap returns a map. kv holds its keys and values, alternating, and
+// must have an even length.
+func NewMap(kv ...Value) Value {
+	if len(kv)%2 != 0 {
+		panic("resp: NewMap called with an odd number of values")
+	}
+	if kv == nil {
+		kv = []Value{}
+	}
+	return Value{kind: Map, elems: kv}
+}
+
+// NewSet returns a set holding elems
+func NewSet(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{kind: Set, elems: elems}
+}
+
+// NewDouble returns a double
+func NewDouble(f float64) Value {
+	return Value{kind: Double, double: f}
+}
+
+// NewBoolean returns a boolean
+func NewBoolean(b bool) Value {
+	v := Value{kind: Boolean}
+	if b {
+		v.integer = 1
+	}
+	return v
+}
+
+// NewBigNumber returns a big number
+func NewBigNumber(n *big.Int) Value {
+	return Value{kind: BigNumber, big: n}
+}
+
+// NewVerbatim returns a verbatim string. format is a three letter hint
+// for how to display text, such as "txt" or "mkd".
+func NewVerbatim(format, text string) Value {
+	return Value{kind: Verbatim, format: format, bytes: []byte(text)}
+}
+
+// NewPush returns an out of band push message holding elems
+func NewPush(elems ...Value) Value {
+	if elems == nil {
+		elems = []Value{}
+	}
+	return Value{kind: Push, elems: elems}
+}
+
+// WithAttributes returns a copy of v carrying the RESP3 attributes kv,
+// given as alternating keys and values
+func (v Value) WithAttributes(kv ...Value) Value {
+	if len(kv)%2 != 0 {
+		panic("resp: WithAttributes called with an odd number of values")
+	}
+	v.attrs = kv
+	return v
+}
+
+// Kind returns the type of v
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Str returns the text of a simple string, error, bulk string or verbatim
+// string, and the decimal digits of a big number
+func (v Value) Str() string {
+	switch v.kind {
+	case Bulk, Verbatim:
+		return string(v.bytes)
+	case BigNumber:
+		return v.big.String()
+	}
+	return v.str
+}
+
+// Bytes returns the payload of a bulk string or verbatim string without
+// copying it. For simple strings and errors it returns their text.
+func (v Value) Bytes() []byte {
+	switch v.kind {
+	case Bulk, Verbatim:
+		return v.bytes
+	case SimpleString, Error:
+		return []byte(v.str)
+	}
+	return nil
+}
+
+// Int returns the value of an integer, and 1 or 0 for a boolean
+func (v Value) Int() int64 {
+	return v.integer
+}
+
+// Float returns the value of a double
+func (v Value) Float() float64 {
+	return v.double
+}
+
+// Bool returns the value of a boolean
+func (v Value) Bool() bool {
+	return v.integer != 0
+}
+
+// BigInt returns the value of a big number
+func (v Value) BigInt() *big.Int {
+	return v.big
+}
+
+// Format returns the three letter format of a verbatim string
+func (v Value) Format() string {
+	return v.format
+}
+
+// Elems returns the elements of an array, set or push message. For a map
+// it returns the keys and values, alternating.
+func (v Value) Elems() []Value {
+	return v.elems
+}
+
+// Attributes returns the RESP3 attributes sent along with v as
+// alternating keys and values, or nil if there were none
+func (v Value) Attributes() []Value {
+	return v.attrs
+}
+
+// IsNull reports whether v is a null bulk string or a null array
+func (v Value) IsNull() bool {
+	return v.kind == Null || v.kind == NullArray
+}
+
+// Err returns the message of an error reply as an error, and nil for
+// every other kind
+func (v Value) Err() error {
+	if v.kind != Error {
+		return nil
+	}
+	return errors.New(v.str)
+}
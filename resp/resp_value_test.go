@@ -0,0 +1,160 @@
+package resp
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_ValueAccessors(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		wantKind Kind
+		wantStr  string
+		wantInt  int64
+		wantNull bool
+	}{
+		{
+			name:     "It should expose simple strings",
+			value:    NewSimpleString("OK"),
+			wantKind: SimpleString,
+			wantStr:  "OK",
+		},
+		{
+			name:     "It should expose bulk strings",
+			value:    NewBulkString("hello"),
+			wantKind: Bulk,
+			wantStr:  "hello",
+		},
+		{
+			name:     "It should expose errors",
+			value:    NewError("ERR boom"),
+			wantKind: Error,
+			wantStr:  "ERR boom",
+		},
+		{
+			name:     "It should expose integers",
+			value:    NewInteger(-5),
+			wantKind: Integer,
+			wantInt:  -5,
+		},
+		{
+			name:     "It should expose booleans as 1 or 0",
+			value:    NewBoolean(true),
+			wantKind: Boolean,
+			wantInt:  1,
+		},
+		{
+			name:     "It should expose big numbers as text",
+			value:    NewBigNumber(big.NewInt(42)),
+			wantKind: BigNumber,
+			wantStr:  "42",
+		},
+		{
+			name:     "It should expose verbatim text without the format",
+			value:    NewVerbatim("txt", "hi"),
+			wantKind: Verbatim,
+			wantStr:  "hi",
+		},
+		{
+			name:     "It should report null bulk strings as null",
+			value:    NewNull(),
+			wantKind: Null,
+			wantNull: true,
+		},
+		{
+			name:     "It should report null arrays as null",
+			value:    NewNullArray(),
+			wantKind: NullArray,
+			wantNull: true,
+		},
+		{
+			name:     "It should have an invalid zero value",
+			value:    Value{},
+			wantKind: Invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.Kind(); got != tt.wantKind {
+				t.Errorf("Kind() = %v, want %v", got, tt.wantKind)
+			}
+			if got := tt.value.Str(); got != tt.wantStr {
+				t.Errorf("Str() = %q, want %q", got, tt.wantStr)
+			}
+			if got := tt.value.Int(); got != tt.wantInt {
+				t.Errorf("Int() = %d, want %d", got, tt.wantInt)
+			}
+			if got := tt.value.IsNull(); got != tt.wantNull {
+				t.Errorf("IsNull() = %v, want %v", got, tt.wantNull)
+			}
+		})
+	}
+}
+
+func Test_ValueErr(t *testing.T) {
+	if err := NewError("ERR boom").Err(); err == nil || err.Error() != "ERR boom" {
+		t.Errorf("Err() = %v, want ERR boom", err)
+	}
+	if err := NewSimpleString("ERR boom").Err(); err != nil {
+		t.Errorf("Err() = %v for a simple string, want nil", err)
+	}
+}
+
+func Test_ValueKeepsStringKinds(t *testing.T) {
+	for _, value := range []Value{NewSimpleString("OK"), NewBulkString("OK")} {
+		got, err := Deserialize(Serialize(value))
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("Deserialize(Serialize(%v)) = %v", value.Kind(), got.Kind())
+		}
+	}
+}
+
+func Test_NewMapPanicsOnOddArguments(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewMap() with an odd number of values did not panic")
+		}
+	}()
+	NewMap(NewBulkString("key"))
+}
+
+func Test_BulkRoundTripsBinaryData(t *testing.T) {
+	payloads := [][]byte{
+		{},
+		{0x00},
+		[]byte("\r\n"),
+		[]byte("line one\r\nline two\r\n"),
+		{0xff, 0xfe, 0x00, '\r', '\n', 0x80},
+	}
+	for _, payload := range payloads {
+		value := NewArray(NewBulk(payload), NewBulkString("tail"))
+
+		got, err := Deserialize(Serialize(value))
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, value) {
+			t.Errorf("Deserialize(Serialize(%q)) = %q", payload, got.Elems()[0].Bytes())
+		}
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.WriteValue(value)
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		read, _, err := NewReader(&buf).ReadValue()
+		if err != nil {
+			t.Fatalf("ReadValue() error = %v", err)
+		}
+		if !reflect.DeepEqual(read, value) {
+			t.Errorf("ReadValue() after WriteValue(%q) = %q", payload, read.Elems()[0].Bytes())
+		}
+	}
+}
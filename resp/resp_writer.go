@@ -0,0 +1,256 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// Writer encodes RESP values straight into a buffered io.Writer, so large
+// replies never have to be built up in memory first. Nothing reaches the
+// underlying writer until the buffer fills up or Flush is called.
+//
+// A Writer speaks RESP2 until SetProtocol(3) is called. Types that only
+// exist in RESP3 are then encoded natively; under RESP2 they are written
+// the way Redis downgrades them, e.g. maps become flat arrays and doubles
+// become bulk strings.
+//
+// Write errors are sticky: once a write fails every later call is a no-op
+// and the error is reported by Flush.
+type Writer struct {
+	wr      *bufio.Writer
+	proto   int
+	scratch []byte
+	err     error
+}
+
+// NewWriter returns a RESP2 Writer that writes to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{wr: bufio.NewWriter(w), proto: 2, scratch: make([]byte, 0, 24)}
+}
+
+// SetProtocol selects the protocol version, 2 or 3, used for every
+// following write
+func (w *Writer) SetProtocol(proto int) {
+	w.proto = proto
+}
+
+// Protocol returns the protocol version in use
+func (w *Writer) Protocol() int {
+	return w.proto
+}
+
+func (w *Writer) writeString(s string) {
+	if w.err == nil {
+		_, w.err = w.wr.WriteString(s)
+	}
+}
+
+func (w *Writer) writeBytes(b []byte) {
+	if w.err == nil {
+		_, w.err = w.wr.Write(b)
+	}
+}
+
+// writePrefixed writes a type byte followed by n and \r\n
+func (w *Writer) writePrefixed(prefix byte, n int64) {
+	w.scratch = append(w.scratch[:0], prefix)
+	w.scratch = strconv.AppendInt(w.scratch, n, 10)
+	w.scratch = append(w.scratch, '\r', '\n')
+	w.writeBytes(w.scratch)
+}
+
+// WriteSimpleString writes s as a simple string. s must not contain \r or \n.
+func (w *Writer) WriteSimpleString(s string) {
+	w.writeString("+")
+	w.writeString(s)
+	w.writeString("\r\n")
+}
+
+// WriteError writes msg as an error reply. msg must not contain \r or \n.
+func (w *Writer) WriteError(msg string) {
+	w.writeString("-")
+	w.writeString(msg)
+	w.writeString("\r\n")
+}
+
+// WriteInteger writes n as an integer reply
+func (w *Writer) WriteInteger(n int64) {
+	w.writePrefixed(':', n)
+}
+
+// WriteBulk writes b as a bulk string
+func (w *Writer) WriteBulk(b []byte) {
+	w.writePrefixed('$', int64(len(b)))
+	w.writeBytes(b)
+	w.writeString("\r\n")
+}
+
+// WriteBulkString writes s as a bulk string
+func (w *Writer) WriteBulkString(s string) {
+	w.writePrefixed('$', int64(len(s)))
+	w.writeString(s)
+	w.writeString("\r\n")
+}
+
+// WriteArrayHeader starts an array of n elements. The caller must write
+// exactly n values afterwards.
+func (w *Writer) WriteArrayHeader(n int) {
+	w.writePrefixed('*', int64(n))
+}
+
+// WriteNull writes a null bulk string, or a RESP3 null
+func (w *Writer) WriteNull() {
+	if w.proto >= 3 {
+		w.writeString("_\r\n")
+		return
+	}
+	w.writeString("$-1\r\n")
+}
+
+// WriteNullArray writes a null array, or a RESP3 null
+func (w *Writer) WriteNullArray() {
+	if w.proto >= 3 {
+		w.writeString("_\r\n")
+		return
+	}
+	w.writeString("*-1\r\n")
+}
+
+// WriteMapHeader starts a map of n key/value pairs. The caller must write
+// exactly 2*n values afterwards, alternating keys and values.
+func (w *Writer) WriteMapHeader(n int) {
+	if w.proto >= 3 {
+		w.writePrefixed('%', int64(n))
+		return
+	}
+	w.writePrefixed('*', int64(2*n))
+}
+
+// WriteSetHeader starts a set of n elements
+func (w *Writer) WriteSetHeader(n int) {
+	if w.proto >= 3 {
+		w.writePrefixed('~', int64(n))
+		return
+	}
+	w.writePrefixed('*', int64(n))
+}
+
+// WritePushHeader starts a push message of n elements
+func (w *Writer) WritePushHeader(n int) {
+	if w.proto >= 3 {
+		w.writePrefixed('>', int64(n))
+		return
+	}
+	w.writePrefixed('*', int64(n))
+}
+
+// WriteDouble writes f as a double, or as a bulk string under RESP2
+func (w *Writer) WriteDouble(f float64) {
+	if w.proto >= 3 {
+		w.writeString(",")
+		w.writeString(formatDouble(f))
+		w.writeString("\r\n")
+		return
+	}
+	w.WriteBulkString(formatDouble(f))
+}
+
+// WriteBoolean writes b as a boolean, or as the integer 1 or 0 under RESP2
+func (w *Writer) WriteBoolean(b bool) {
+	switch {
+	case w.proto >= 3 && b:
+		w.writeString("#t\r\n")
+	case w.proto >= 3:
+		w.writeString("#f\r\n")
+	case b:
+		w.WriteInteger(1)
+	default:
+		w.WriteInteger(0)
+	}
+}
+
+// WriteBigNumber writes n as a big number, or as a bulk string under RESP2
+func (w *Writer) WriteBigNumber(n *big.Int) {
+	if w.proto >= 3 {
+		w.writeString("(")
+		w.writeString(n.String())
+		w.writeString("\r\n")
+		return
+	}
+	w.WriteBulkString(n.String())
+}
+
+// WriteVerbatim writes text as a verbatim string with the given three
+// letter format, or as a plain bulk string under RESP2
+func (w *Writer) WriteVerbatim(format, text string) {
+	if w.proto >= 3 {
+		w.writePrefixed('=', int64(len(format)+1+len(text)))
+		w.writeString(format)
+		w.writeString(":")
+		w.writeString(text)
+		w.writeString("\r\n")
+		return
+	}
+	w.WriteBulkString(text)
+}
+
+// WriteValue writes v encoded for the Writer's protocol version
+func (w *Writer) WriteValue(v Value) {
+	// RESP2 has no way to carry attributes, so they are dropped
+	if v.attrs != nil && w.proto >= 3 {
+		w.writePrefixed('|', int64(len(v.attrs)/2))
+		w.writeValues(v.attrs)
+	}
+
+	switch v.kind {
+	case SimpleString:
+		w.WriteSimpleString(v.str)
+	case Error:
+		w.WriteError(v.str)
+	case Integer:
+		w.WriteInteger(v.integer)
+	case Bulk:
+		w.WriteBulk(v.bytes)
+	case Array:
+		w.WriteArrayHeader(len(v.elems))
+		w.writeValues(v.elems)
+	case Null:
+		w.WriteNull()
+	case NullArray:
+		w.WriteNullArray()
+	case Map:
+		w.WriteMapHeader(len(v.elems) / 2)
+		w.writeValues(v.elems)
+	case Set:
+		w.WriteSetHeader(len(v.elems))
+		w.writeValues(v.elems)
+	case Double:
+		w.WriteDouble(v.double)
+	case Boolean:
+		w.WriteBoolean(v.Bool())
+	case BigNumber:
+		w.WriteBigNumber(v.big)
+	case Verbatim:
+		w.WriteVerbatim(v.format, string(v.bytes))
+	case Push:
+		w.WritePushHeader(len(v.elems))
+		w.writeValues(v.elems)
+	}
+}
+
+func (w *Writer) writeValues(values []Value) {
+	for _, v := range values {
+		w.WriteValue(v)
+	}
+}
+
+// Flush writes any buffered data to the underlying io.Writer and returns
+// the first error encountered since the Writer was created
+func (w *Writer) Flush() error {
+	if w.err == nil {
+		w.err = w.wr.Flush()
+	}
+	return w.err
+}
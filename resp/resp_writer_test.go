@@ -0,0 +1,187 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func Test_Writer(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w *Writer)
+		want  string
+	}{
+		{
+			name:  "It should write a simple string",
+			write: func(w *Writer) { w.WriteSimpleString("OK") },
+			want:  "+OK\r\n",
+		},
+		{
+			name:  "It should write an error",
+			write: func(w *Writer) { w.WriteError("ERR boom") },
+			want:  "-ERR boom\r\n",
+		},
+		{
+			name:  "It should write a negative integer",
+			write: func(w *Writer) { w.WriteInteger(-9223372036854775808) },
+			want:  ":-9223372036854775808\r\n",
+		},
+		{
+			name:  "It should write a binary bulk string",
+			write: func(w *Writer) { w.WriteBulk([]byte("a\r\n\x00b")) },
+			want:  "$5\r\na\r\n\x00b\r\n",
+		},
+		{
+			name:  "It should write an empty bulk string",
+			write: func(w *Writer) { w.WriteBulkString("") },
+			want:  "$0\r\n\r\n",
+		},
+		{
+			name:  "It should write a null",
+			write: func(w *Writer) { w.WriteNull() },
+			want:  "$-1\r\n",
+		},
+		{
+			name:  "It should write a null array",
+			write: func(w *Writer) { w.WriteNullArray() },
+			want:  "*-1\r\n",
+		},
+		{
+			name: "It should write an array element by element",
+			write: func(w *Writer) {
+				w.WriteArrayHeader(3)
+				w.WriteBulkString("hello")
+				w.WriteInteger(1)
+				w.WriteNull()
+			},
+			want: "*3\r\n$5\r\nhello\r\n:1\r\n$-1\r\n",
+		},
+		{
+			name: "It should write values the same way as Serialize",
+			write: func(w *Writer) {
+				w.WriteValue(NewArray(NewBulkString("hello"), NewInteger(123), NewError("error"), NewArray(), NewNullArray()))
+			},
+			want: "*5\r\n$5\r\nhello\r\n:123\r\n-error\r\n*0\r\n*-1\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			tt.write(w)
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Writer wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WriterBuffersUntilFlush(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteSimpleString("OK")
+	if buf.Len() != 0 {
+		t.Errorf("Writer wrote %q before Flush", buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if buf.String() != "+OK\r\n" {
+		t.Errorf("Writer wrote %q, want %q", buf.String(), "+OK\r\n")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func Test_WriterStickyError(t *testing.T) {
+	w := NewWriter(failingWriter{})
+	w.WriteBulkString(strings.Repeat("x", 8192))
+	w.WriteSimpleString("OK")
+	if err := w.Flush(); err == nil || err.Error() != "broken pipe" {
+		t.Errorf("Flush() error = %v, want broken pipe", err)
+	}
+}
+
+func Test_WriterProtocols(t *testing.T) {
+	tests := []struct {
+		name  string
+		value Value
+		want2 string
+		want3 string
+	}{
+		{
+			name:  "It should write nulls",
+			value: NewArray(NewNull(), NewNullArray()),
+			want2: "*2\r\n$-1\r\n*-1\r\n",
+			want3: "*2\r\n_\r\n_\r\n",
+		},
+		{
+			name:  "It should write maps",
+			value: NewMap(NewBulkString("a"), NewInteger(1)),
+			want2: "*2\r\n$1\r\na\r\n:1\r\n",
+			want3: "%1\r\n$1\r\na\r\n:1\r\n",
+		},
+		{
+			name:  "It should write sets and pushes",
+			value: NewArray(NewSet(NewBulkString("a")), NewPush(NewBulkString("b"))),
+			want2: "*2\r\n*1\r\n$1\r\na\r\n*1\r\n$1\r\nb\r\n",
+			want3: "*2\r\n~1\r\n$1\r\na\r\n>1\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "It should write doubles",
+			value: NewDouble(3.14),
+			want2: "$4\r\n3.14\r\n",
+			want3: ",3.14\r\n",
+		},
+		{
+			name:  "It should write booleans",
+			value: NewArray(NewBoolean(true), NewBoolean(false)),
+			want2: "*2\r\n:1\r\n:0\r\n",
+			want3: "*2\r\n#t\r\n#f\r\n",
+		},
+		{
+			name:  "It should write big numbers",
+			value: NewBigNumber(big.NewInt(-7)),
+			want2: "$2\r\n-7\r\n",
+			want3: "(-7\r\n",
+		},
+		{
+			name:  "It should write verbatim strings",
+			value: NewVerbatim("txt", "hi"),
+			want2: "$2\r\nhi\r\n",
+			want3: "=6\r\ntxt:hi\r\n",
+		},
+		{
+			name:  "It should only write attributes under RESP3",
+			value: NewBulkString("hi").WithAttributes(NewBulkString("a"), NewInteger(1)),
+			want2: "$2\r\nhi\r\n",
+			want3: "|1\r\n$1\r\na\r\n:1\r\n$2\r\nhi\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for proto, want := range map[int]string{2: tt.want2, 3: tt.want3} {
+				var buf bytes.Buffer
+				w := NewWriter(&buf)
+				w.SetProtocol(proto)
+				w.WriteValue(tt.value)
+				if err := w.Flush(); err != nil {
+					t.Fatalf("Flush() error = %v", err)
+				}
+				if got := buf.String(); got != want {
+					t.Errorf("RESP%d Writer wrote %q, want %q", proto, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_parseInt(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   int64
+		wantOK bool
+	}{
+		{input: "0", want: 0, wantOK: true},
+		{input: "42", want: 42, wantOK: true},
+		{input: "-42", want: -42, wantOK: true},
+		{input: "9223372036854775807", want: math.MaxInt64, wantOK: true},
+		{input: "-9223372036854775808", want: math.MinInt64, wantOK: true},
+		{input: "9223372036854775808"},
+		{input: ""},
+		{input: "-"},
+		{input: "-0"},
+		{input: "+1"},
+		{input: "01"},
+		{input: " 1"},
+		{input: "1 "},
+		{input: "1.0"},
+		{input: "abc"},
+	}
+	for _, tt := range tests {
+		got, ok := parseInt([]byte(tt.input))
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseInt(%q) = %d, %v, want %d, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
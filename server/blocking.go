@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+var (
+	errTimeoutNotFloat = errors.New("ERR timeout is not a float or out of range")
+	errTimeoutNegative = errors.New("ERR timeout is negative")
+	errTimeoutRange    = errors.New("ERR timeout is out of range")
+)
+
+// blockedState describes what a client blocked by a command such as BLPOP
+// waits for. Like in Redis, a blocked client does not hold up anyone: its
+// connection goroutine waits with the keyspace unlocked, and whichever
+// command makes one of its keys ready serves it on its behalf.
+type blockedState struct {
+	keys []string
+	// timeout is how long the client waits at most, or zero to wait
+	// forever
+	timeout time.Duration
+	// serve tries to serve the client from key, which may have become
+	// able to. It returns the reply and whether it served the client.
+	serve func(key string) (resp.Value, bool)
+	// timeoutReply is the reply when the timeout elapses
+	timeoutReply resp.Value
+
+	// reply is set to what serve returned once the client is served
+	reply resp.Value
+	// served is closed once the client is served
+	served chan struct{}
+}
+
+// parseTimeout parses the timeout argument of a blocking command, which is
+// in seconds and may have a fractional part
+func parseTimeout(arg []byte) (time.Duration, error) {
+	seconds, ok := parseFloat(arg)
+	if !ok {
+		return 0, errTimeoutNotFloat
+	}
+	if seconds < 0 {
+		return 0, errTimeoutNegative
+	}
+	if seconds*1000 > math.MaxInt64/float64(time.Millisecond) {
+		return 0, errTimeoutRange
+	}
+	return time.Duration(seconds*1000) * time.Millisecond, nil
+}
+
+// blockForKeys blocks c until serve manages to serve it from one of keys,
+// or until timeout elapses. Clients without a connection, such as those of
+// HandleRequest, cannot wait for other clients, so they get the timeout
+// reply straight away.
+func (c *client) blockForKeys(keys []string, timeout time.Duration, serve func(key string) (resp.Value, bool), timeoutReply resp.Value) {
+	if c.conn == nil {
+		c.w.WriteValue(timeoutReply)
+		return
+	}
+	c.blocked = &blockedState{
+		keys:         keys,
+		timeout:      timeout,
+		serve:        serve,
+		timeoutReply: timeoutReply,
+		served:       make(chan struct{}),
+	}
+	for _, key := range keys {
+		if !containsClient(c.db.blocked[key], c) {
+			c.db.blocked[key] = append(c.db.blocked[key], c)
+		}
+	}
+}
+
+func containsClient(clients []*client, c *client) bool {
+	for _, other := range clients {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// waitUntilUnblocked waits until b, the state of c when it blocked, is
+// served or times out, and writes the reply. It is called without holding
+// the lock of the keyspace.
+func (c *client) waitUntilUnblocked(b *blockedState) {
+	// The replies to the commands before the blocking one should not have
+	// to wait for it
+	if c.in != nil {
+		_ = c.in.flush()
+	}
+
+	var timeout <-chan time.Time
+	if b.timeout > 0 {
+		timer := c.db.clock.NewTimer(b.timeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	var gone <-chan struct{}
+	if c.in != nil {
+		var stop func()
+		gone, stop = c.in.watch()
+		defer stop()
+	}
+
+	select {
+	case <-b.served:
+	case <-timeout:
+		c.db.Lock()
+		if c.blocked == b {
+			b.reply = b.timeoutReply
+			c.db.unblock(c)
+		}
+		c.db.Unlock()
+		// A client served while its timer fired has its reply already
+		<-b.served
+	case <-gone:
+		c.db.Lock()
+		disconnected := c.blocked == b
+		if disconnected {
+			// Nobody is left to serve, so the client must not take
+			// elements away from the others
+			c.db.unblock(c)
+		}
+		c.db.Unlock()
+		if disconnected {
+			return
+		}
+		<-b.served
+	}
+	c.w.WriteValue(b.reply)
+}
+
+// unblock removes c from the clients blocked on its keys and wakes it up
+func (ks *keyspace) unblock(c *client) {
+	b := c.blocked
+	for _, key := range b.keys {
+		clients := ks.blocked[key]
+		for i, other := range clients {
+			if other == c {
+				clients = append(clients[:i], clients[i+1:]...)
+				break
+			}
+		}
+		if len(clients) == 0 {
+			delete(ks.blocked, key)
+		} else {
+			ks.blocked[key] = clients
+		}
+	}
+	c.blocked = nil
+	close(b.served)
+}
+
+// signalKeyAsReady records that key may now be able to serve the clients
+// blocked on it
+func (ks *keyspace) signalKeyAsReady(key string) {
+	if len(ks.blocked[key]) == 0 {
+		return
+	}
+	for _, ready := range ks.readyKeys {
+		if ready == key {
+			return
+		}
+	}
+	ks.readyKeys = append(ks.readyKeys, key)
+}
+
+// handleClientsBlockedOnKeys serves the clients blocked on the keys that
+// became ready, in the order they blocked, for as long as the keys can
+// serve them. Serving a client may make more keys ready, as BLMOVE pushes
+// what it pops, so it goes on until no key is left.
+func (ks *keyspace) handleClientsBlockedOnKeys() {
+	for len(ks.readyKeys) > 0 {
+		key := ks.readyKeys[0]
+		ks.readyKeys = ks.readyKeys[1:]
+		// Serving unblocks clients, which changes the queue
+		for _, c := range append([]*client(nil), ks.blocked[key]...) {
+			reply, ok := c.blocked.serve(key)
+			if !ok {
+				break
+			}
+			c.blocked.reply = reply
+			ks.unblock(c)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+// waitForBlockedClients waits until n clients are blocked on key
+func waitForBlockedClients(t *testing.T, srv *Server, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		srv.db.Lock()
+		blocked := len(srv.db.blocked[key])
+		srv.db.Unlock()
+		if blocked == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients are blocked on %s, want %d", blocked, key, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_parseTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    time.Duration
+		wantErr error
+	}{
+		{name: "It should parse whole seconds", arg: "2", want: 2 * time.Second},
+		{name: "It should parse fractions of a second", arg: "0.25", want: 250 * time.Millisecond},
+		{name: "It should parse zero as waiting forever", arg: "0", want: 0},
+		{name: "It should reject negative timeouts", arg: "-1", wantErr: errTimeoutNegative},
+		{name: "It should reject timeouts that are not numbers", arg: "soon", wantErr: errTimeoutNotFloat},
+		{name: "It should reject infinite timeouts", arg: "inf", wantErr: errTimeoutRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimeout([]byte(tt.arg))
+			if err != tt.wantErr {
+				t.Fatalf("parseTimeout() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_BlockingCommandsWithoutWaiting(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should pop from the first non empty list",
+			input: "RPUSH b 1 2\r\nBLPOP a b 0\r\nBRPOP a b 0\r\nEXISTS b\r\n",
+			want:  ":2\r\n*2\r\n$1\r\nb\r\n$1\r\n1\r\n*2\r\n$1\r\nb\r\n$1\r\n2\r\n:0\r\n",
+		},
+		{
+			name:  "It should move an element that is there",
+			input: "RPUSH src a\r\nBLMOVE src dst LEFT RIGHT 0\r\nBRPOPLPUSH dst src 0\r\n",
+			want:  ":1\r\n$1\r\na\r\n$1\r\na\r\n",
+		},
+		{
+			name:  "It should time out straight away when it cannot wait for other clients",
+			input: "BLPOP a 0\r\nBLMOVE a b LEFT LEFT 0\r\nBLMPOP 0 1 a LEFT\r\n",
+			want:  "*-1\r\n$-1\r\n*-1\r\n",
+		},
+		{
+			name:  "It should reject invalid timeouts",
+			input: "BLPOP a -1\r\nBRPOP a x\r\nBLMOVE a b LEFT LEFT x\r\nBLMPOP x 1 a LEFT\r\n",
+			want:  "-ERR timeout is negative\r\n-ERR timeout is not a float or out of range\r\n-ERR timeout is not a float or out of range\r\n-ERR timeout is not a float or out of range\r\n",
+		},
+		{
+			name:  "It should reject keys holding another type",
+			input: "SET s v\r\nBLPOP a s 0\r\nBLMOVE s a LEFT LEFT 0\r\n",
+			want:  "+OK\r\n-" + errWrongType.Error() + "\r\n-" + errWrongType.Error() + "\r\n",
+		},
+		{
+			name:  "It should pop several elements from the first non empty list with LMPOP",
+			input: "RPUSH b 1 2 3\r\nLMPOP 2 a b RIGHT COUNT 2\r\nLMPOP 2 a b LEFT COUNT 5\r\nLMPOP 2 a b LEFT\r\n",
+			want:  ":3\r\n*2\r\n$1\r\nb\r\n*2\r\n$1\r\n3\r\n$1\r\n2\r\n*2\r\n$1\r\nb\r\n*1\r\n$1\r\n1\r\n*-1\r\n",
+		},
+		{
+			name:  "It should reject invalid LMPOP arguments",
+			input: "LMPOP 0 a LEFT\r\nLMPOP 2 a LEFT\r\nLMPOP 9223372036854775807 a LEFT\r\nLMPOP 1 a UP\r\nLMPOP 1 a LEFT COUNT 0\r\nLMPOP 1 a LEFT COUNT\r\nLMPOP x a LEFT\r\n",
+			want: "-ERR numkeys should be greater than 0\r\n-ERR syntax error\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"-ERR count should be greater than 0\r\n-ERR syntax error\r\n-ERR numkeys should be greater than 0\r\n",
+		},
+		{
+			name:  "It should pop from the first non empty sorted set",
+			input: "ZADD b 1 x 2 y\r\nBZPOPMIN a b 0\r\nBZPOPMAX a b 0\r\nEXISTS b\r\nBZPOPMIN a 0\r\nBZMPOP 0 1 a MIN\r\n",
+			want: ":2\r\n*3\r\n$1\r\nb\r\n$1\r\nx\r\n$1\r\n1\r\n*3\r\n$1\r\nb\r\n$1\r\ny\r\n$1\r\n2\r\n:0\r\n" +
+				"*-1\r\n*-1\r\n",
+		},
+		{
+			name:  "It should pop several members from the first non empty sorted set with ZMPOP",
+			input: "ZADD b 1 x 2 y 3 z\r\nZMPOP 2 a b MAX COUNT 2\r\nZMPOP 2 a b MIN COUNT 5\r\nZMPOP 2 a b MIN\r\n",
+			want: ":3\r\n*2\r\n$1\r\nb\r\n*2\r\n*2\r\n$1\r\nz\r\n$1\r\n3\r\n*2\r\n$1\r\ny\r\n$1\r\n2\r\n" +
+				"*2\r\n$1\r\nb\r\n*1\r\n*2\r\n$1\r\nx\r\n$1\r\n1\r\n*-1\r\n",
+		},
+		{
+			name:  "It should reject invalid sorted set pop arguments",
+			input: "ZMPOP 0 a MIN\r\nZMPOP 1 a LEFT\r\nZMPOP 1 a MIN COUNT 0\r\nBZPOPMIN a x\r\nBZMPOP -1 1 a MIN\r\nSET s v\r\nBZPOPMAX a s 0\r\n",
+			want: "-ERR numkeys should be greater than 0\r\n-ERR syntax error\r\n-ERR count should be greater than 0\r\n" +
+				"-ERR timeout is not a float or out of range\r\n-ERR timeout is negative\r\n+OK\r\n-" + errWrongType.Error() + "\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_BlockingPopWakesUpOnPush(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	blocked := dialTestServer(t, addr)
+	pusher := dialTestServer(t, addr)
+
+	// The command after BLPOP waits for it
+	send(t, blocked, "BLPOP a q 0\r\nPING\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	send(t, pusher, "RPUSH q x\r\n")
+	expectReply(t, pusher, ":1\r\n")
+	expectReply(t, blocked, "*2\r\n$1\r\nq\r\n$1\r\nx\r\n+PONG\r\n")
+
+	send(t, pusher, "LLEN q\r\n")
+	expectReply(t, pusher, ":0\r\n")
+	waitForBlockedClients(t, srv, "a", 0)
+}
+
+func Test_BlockingSortedSetPopWakesUpOnAdd(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	popper := dialTestServer(t, addr)
+	mpopper := dialTestServer(t, addr)
+	adder := dialTestServer(t, addr)
+
+	send(t, popper, "BZPOPMIN q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	send(t, adder, "ZADD q 2 b 1 a\r\n")
+	expectReply(t, adder, ":2\r\n")
+	expectReply(t, popper, "*3\r\n$1\r\nq\r\n$1\r\na\r\n$1\r\n1\r\n")
+
+	// Storing a sorted set serves clients too
+	send(t, mpopper, "BZMPOP 0 1 d MAX COUNT 2\r\n")
+	waitForBlockedClients(t, srv, "d", 1)
+	send(t, adder, "ZUNIONSTORE d 1 q\r\n")
+	expectReply(t, adder, ":1\r\n")
+	expectReply(t, mpopper, "*2\r\n$1\r\nd\r\n*1\r\n*2\r\n$1\r\nb\r\n$1\r\n2\r\n")
+
+	send(t, adder, "EXISTS d\r\n")
+	expectReply(t, adder, ":0\r\n")
+}
+
+func Test_BlockedClientsAreServedInOrder(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	first := dialTestServer(t, addr)
+	second := dialTestServer(t, addr)
+	third := dialTestServer(t, addr)
+	pusher := dialTestServer(t, addr)
+
+	send(t, first, "BLPOP q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	send(t, second, "BRPOP q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 2)
+	send(t, third, "BLMPOP 0 1 q LEFT COUNT 5\r\n")
+	waitForBlockedClients(t, srv, "q", 3)
+
+	send(t, pusher, "RPUSH q 1 2\r\n")
+	expectReply(t, pusher, ":2\r\n")
+	expectReply(t, first, "*2\r\n$1\r\nq\r\n$1\r\n1\r\n")
+	expectReply(t, second, "*2\r\n$1\r\nq\r\n$1\r\n2\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+
+	send(t, pusher, "RPUSH q 3 4\r\n")
+	expectReply(t, pusher, ":2\r\n")
+	expectReply(t, third, "*2\r\n$1\r\nq\r\n*2\r\n$1\r\n3\r\n$1\r\n4\r\n")
+}
+
+func Test_BlockingMoveWakesUpChainedClients(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	mover := dialTestServer(t, addr)
+	popper := dialTestServer(t, addr)
+	pusher := dialTestServer(t, addr)
+
+	send(t, mover, "BLMOVE src dst RIGHT LEFT 0\r\n")
+	waitForBlockedClients(t, srv, "src", 1)
+	send(t, popper, "BLPOP dst 0\r\n")
+	waitForBlockedClients(t, srv, "dst", 1)
+
+	send(t, pusher, "RPUSH src a\r\n")
+	expectReply(t, pusher, ":1\r\n")
+	expectReply(t, mover, "$1\r\na\r\n")
+	expectReply(t, popper, "*2\r\n$3\r\ndst\r\n$1\r\na\r\n")
+}
+
+func Test_BlockingMoveWaitsForAListDestination(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	mover := dialTestServer(t, addr)
+	other := dialTestServer(t, addr)
+
+	send(t, other, "SET dst v\r\n")
+	expectReply(t, other, "+OK\r\n")
+	send(t, mover, "BLMOVE src dst LEFT LEFT 0\r\n")
+	waitForBlockedClients(t, srv, "src", 1)
+
+	// The destination holds a string, so the element stays where it is
+	send(t, other, "RPUSH src a\r\nLLEN src\r\nDEL dst\r\nRPUSH src b\r\n")
+	expectReply(t, other, ":1\r\n:1\r\n:1\r\n:2\r\n")
+	expectReply(t, mover, "$1\r\na\r\n")
+}
+
+func Test_BlockingPopTimesOut(t *testing.T) {
+	clock := newFakeClock()
+	srv, addr := startServer(t, Config{Clock: clock})
+	blocked := dialTestServer(t, addr)
+
+	send(t, blocked, "BLPOP q 1.5\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	// The timer of the client and the one of the cron
+	clock.blockUntil(2)
+	clock.Advance(1499 * time.Millisecond)
+	clock.blockUntil(2)
+	waitForBlockedClients(t, srv, "q", 1)
+
+	clock.Advance(time.Millisecond)
+	expectReply(t, blocked, "*-1\r\n")
+	waitForBlockedClients(t, srv, "q", 0)
+
+	send(t, blocked, "BLMOVE q dst LEFT LEFT 1\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	clock.blockUntil(2)
+	clock.Advance(time.Second)
+	expectReply(t, blocked, "$-1\r\n")
+}
+
+func Test_BlockedClientsDoNotTimeOutWhenIdle(t *testing.T) {
+	clock := newFakeClock()
+	srv, addr := startServer(t, Config{Clock: clock, Timeout: time.Second})
+	blocked := dialTestServer(t, addr)
+
+	send(t, blocked, "BLPOP q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	clock.blockUntil(1)
+	clock.Advance(2 * time.Second)
+	clock.blockUntil(1)
+
+	pusher := dialTestServer(t, addr)
+
+	send(t, pusher, "RPUSH q x\r\n")
+	expectReply(t, pusher, ":1\r\n")
+	expectReply(t, blocked, "*2\r\n$1\r\nq\r\n$1\r\nx\r\n")
+}
+
+func Test_DisconnectedBlockedClientsAreNotServed(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	blocked, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	pusher := dialTestServer(t, addr)
+
+	send(t, blocked, "BLPOP q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	blocked.Close()
+	waitForBlockedClients(t, srv, "q", 0)
+
+	send(t, pusher, "RPUSH q x\r\nLLEN q\r\n")
+	expectReply(t, pusher, ":1\r\n:1\r\n")
+}
+
+func Test_BlockedClientsCannotQueueUnboundedInput(t *testing.T) {
+	srv, addr := startServer(t, Config{Limits: resp.Limits{MaxBulkLen: 16, MaxInlineLen: 32}})
+	blocked := dialTestServer(t, addr)
+	pusher := dialTestServer(t, addr)
+
+	send(t, blocked, "BLPOP q 0\r\n")
+	waitForBlockedClients(t, srv, "q", 1)
+	send(t, blocked, strings.Repeat("PING\r\n", 100))
+	waitForBlockedClients(t, srv, "q", 0)
+
+	_ = blocked.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if got, err := io.ReadAll(blocked); err != nil || len(got) != 0 {
+		t.Errorf("reading from the blocked client = %q, %v, want the connection closed", got, err)
+	}
+	send(t, pusher, "RPUSH q x\r\nLLEN q\r\n")
+	expectReply(t, pusher, ":1\r\n:1\r\n")
+}
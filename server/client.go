@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+var lastClientID atomic.Int64
+
+// client holds the state of a single connection
+type client struct {
+	id   int64
+	name string
+	// db is the keyspace the client's commands operate on
+	db *keyspace
+	// w is where replies are written. Its protocol version is the one
+	// negotiated with HELLO.
+	w *resp.Writer
+	// conn is the connection of the client, or nil for requests handled
+	// by HandleRequest
+	conn net.Conn
+	// in reads the commands of the client off conn
+	in *flushingReader
+	// lastInteraction is the unix time in nanoseconds at which the client
+	// last sent a command, which tells when it has been idle for too long
+	lastInteraction atomic.Int64
+	// blocked describes what the client waits for while a blocking command
+	// such as BLPOP has it blocked, and is nil otherwise. It is guarded by
+	// the lock of db.
+	blocked *blockedState
+}
+
+func newClient(db *keyspace, w *resp.Writer) *client {
+	return &client{id: lastClientID.Add(1), db: db, w: w}
+}
+
+// touch records that the client sent a command at t
+func (c *client) touch(t time.Time) {
+	c.lastInteraction.Store(t.UnixNano())
+}
+
+// idle returns how long the client has not sent a command for at t
+func (c *client) idle(t time.Time) time.Duration {
+	return time.Duration(t.UnixNano() - c.lastInteraction.Load())
+}
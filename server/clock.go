@@ -0,0 +1,47 @@
+package server
+
+import "time"
+
+// Clock is where a Server gets the time from. Everything time dependent,
+// such as key expiry, idle client timeouts and the background tasks, goes
+// through it, so tests can substitute a clock they move forward themselves
+// instead of sleeping.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTimer returns a Timer that sends the current time on its channel
+	// once d has elapsed
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event that fires on a Clock, like a time.Timer
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It reports whether it stopped
+	// the timer, or false if the timer had already fired or been stopped.
+	Stop() bool
+	// Reset makes the timer fire once d has elapsed, as if it had just
+	// been created. It must only be called on stopped or expired timers
+	// whose channel has been drained.
+	Reset(d time.Duration) bool
+}
+
+// systemClock is the Clock of the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
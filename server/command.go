@@ -0,0 +1,193 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors shared by many commands
+var (
+	errSyntax     = errors.New("ERR syntax error")
+	errWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger = errors.New("ERR value is not an integer or out of range")
+)
+
+// commandFlag describes how a command behaves. Clients learn about these
+// through COMMAND, and the server uses them to decide what may run where.
+type commandFlag uint
+
+const (
+	// flagWrite marks commands that may modify the keyspace
+	flagWrite commandFlag = 1 << iota
+	// flagReadonly marks commands that only read the keyspace
+	flagReadonly
+	// flagAdmin marks administrative commands
+	flagAdmin
+	// flagNoscript marks commands that cannot be called from scripts
+	flagNoscript
+	// flagFast marks commands that run in O(1) or O(log N) time
+	flagFast
+	// flagBlocking marks commands that may block the client
+	flagBlocking
+	// flagPubsub marks publish/subscribe commands
+	flagPubsub
+)
+
+// commandHandler executes a command. args holds the command name followed
+// by its arguments, and has already been checked against the arity.
+type commandHandler func(c *client, args [][]byte)
+
+// command is an entry of the command table
+type command struct {
+	// name is the lower case name the command is registered under. The
+	// name of a subcommand is prefixed by its container's name and a |,
+	// as in "command|info".
+	name string
+	// handler runs the command. A container command whose arity requires
+	// a subcommand never runs on its own, and has none.
+	handler commandHandler
+	// arity is the number of arguments, counting the command name. A
+	// negative arity -N means at least N.
+	arity int
+	flags commandFlag
+	// firstKey and lastKey are the positions of the first and last key
+	// arguments, and step the distance between keys. A negative lastKey
+	// counts from the end, so -1 is the last argument. Commands without
+	// keys leave all three at zero.
+	firstKey int
+	lastKey  int
+	step     int
+	// keyNumIndex is the position of the numkeys argument of commands such
+	// as LMPOP, whose keys are the numkeys arguments that follow it. It is
+	// zero for other commands.
+	keyNumIndex int
+	// subcommands maps the lower case names of the subcommands of a
+	// container command such as COMMAND to their entries
+	subcommands map[string]*command
+
+	// The fields below document the command for COMMAND DOCS
+
+	summary    string
+	since      string
+	group      string
+	complexity string
+}
+
+// commands is the command table, indexed by lower case name. It is filled
+// in by registerCommand from the init functions of the files implementing
+// each group of commands, and is read-only once the server is running.
+var commands = map[string]*command{}
+
+// registerCommand adds cmd to the command table
+func registerCommand(cmd *command) {
+	if _, ok := commands[cmd.name]; ok {
+		panic("server: command " + cmd.name + " registered twice")
+	}
+	commands[cmd.name] = cmd
+}
+
+// lookupCommand returns the command called name, ignoring case, or nil if
+// there is none
+func lookupCommand(name []byte) *command {
+	return commands[strings.ToLower(string(name))]
+}
+
+// resolveCommand returns the command args invoke. That is a subcommand when
+// args[0] is a container command and there is a second argument. If there
+// is no such command it returns the error to reply with instead.
+func resolveCommand(args [][]byte) (*command, string) {
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		return nil, unknownCommandError(args)
+	}
+	if cmd.subcommands == nil || len(args) < 2 {
+		return cmd, ""
+	}
+	sub := cmd.subcommands[strings.ToLower(string(args[1]))]
+	if sub == nil {
+		return nil, sanitizeError(fmt.Sprintf("ERR unknown subcommand '%s'. Try %s HELP.", truncate(args[1], 128), strings.ToUpper(cmd.name)))
+	}
+	return sub, ""
+}
+
+// checkArity reports whether argc arguments, counting the command name,
+// are acceptable for cmd
+func (cmd *command) checkArity(argc int) bool {
+	if cmd.arity >= 0 {
+		return argc == cmd.arity
+	}
+	return argc >= -cmd.arity
+}
+
+// keyPositions returns the positions in args of the keys cmd operates on
+func (cmd *command) keyPositions(args [][]byte) []int {
+	var positions []int
+	if cmd.firstKey != 0 {
+		last := cmd.lastKey
+		if last < 0 {
+			last += len(args)
+		}
+		for i := cmd.firstKey; i <= last && i < len(args); i += cmd.step {
+			positions = append(positions, i)
+		}
+	}
+	if cmd.keyNumIndex != 0 && cmd.keyNumIndex < len(args) {
+		numKeys, _ := parseInt(args[cmd.keyNumIndex])
+		for i := cmd.keyNumIndex + 1; int64(i-cmd.keyNumIndex) <= numKeys && i < len(args); i++ {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// dispatch looks up the command named by args[0], checks its arity and
+// runs it
+func dispatch(c *client, args [][]byte) {
+	cmd, errMsg := resolveCommand(args)
+	if cmd == nil {
+		c.w.WriteError(errMsg)
+		return
+	}
+	if !cmd.checkArity(len(args)) {
+		c.w.WriteError(wrongArityError(cmd.name))
+		return
+	}
+	c.db.Lock()
+	cmd.handler(c, args)
+	c.db.handleClientsBlockedOnKeys()
+	blocked := c.blocked
+	c.db.Unlock()
+
+	if blocked != nil {
+		c.waitUntilUnblocked(blocked)
+	}
+}
+
+// unknownCommandError returns the error Redis replies with for an unknown
+// command, which quotes the first few arguments to help spot typos
+func unknownCommandError(args [][]byte) string {
+	var beginning strings.Builder
+	for i := 1; i < len(args) && beginning.Len() < 128; i++ {
+		fmt.Fprintf(&beginning, "'%s' ", truncate(args[i], 128-beginning.Len()))
+	}
+	return sanitizeError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", truncate(args[0], 128), beginning.String()))
+}
+
+// sanitizeError replaces line breaks in msg with spaces. Errors quoting
+// arguments may hold anything, but an error reply cannot span lines.
+func sanitizeError(msg string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(msg)
+}
+
+func wrongArityError(name string) string {
+	return "ERR wrong number of arguments for '" + name + "' command"
+}
+
+// truncate returns at most n bytes of b as a string
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		b = b[:n]
+	}
+	return string(b)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_dispatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should look commands up ignoring case",
+			input: "*1\r\n$4\r\npInG\r\n",
+			want:  "+PONG\r\n",
+		},
+		{
+			name:  "It should reply to PING with its message",
+			input: "*2\r\n$4\r\nPING\r\n$2\r\nhi\r\n",
+			want:  "$2\r\nhi\r\n",
+		},
+		{
+			name:  "It should reject too many arguments to PING",
+			input: "*3\r\n$4\r\nPING\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:  "-ERR wrong number of arguments for 'ping' command\r\n",
+		},
+		{
+			name:  "It should reject too few arguments to a fixed arity command",
+			input: "*1\r\n$4\r\nECHO\r\n",
+			want:  "-ERR wrong number of arguments for 'echo' command\r\n",
+		},
+		{
+			name:  "It should reject too many arguments to a fixed arity command",
+			input: "*3\r\n$4\r\nECHO\r\n$1\r\na\r\n$1\r\nb\r\n",
+			want:  "-ERR wrong number of arguments for 'echo' command\r\n",
+		},
+		{
+			name:  "It should quote the arguments of unknown commands",
+			input: "*3\r\n$4\r\nNOPE\r\n$1\r\na\r\n$3\r\nb c\r\n",
+			want:  "-ERR unknown command 'NOPE', with args beginning with: 'a' 'b c' \r\n",
+		},
+		{
+			name:  "It should keep unknown command errors on one line",
+			input: "*2\r\n$4\r\nNOPE\r\n$4\r\na\r\nb\r\n",
+			want:  "-ERR unknown command 'NOPE', with args beginning with: 'a  b' \r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_unknownCommandErrorTruncatesArguments(t *testing.T) {
+	args := [][]byte{[]byte("NOPE"), []byte(strings.Repeat("a", 100)), []byte(strings.Repeat("b", 100)), []byte("c")}
+	got := unknownCommandError(args)
+	want := "ERR unknown command 'NOPE', with args beginning with: '" + strings.Repeat("a", 100) + "' '" + strings.Repeat("b", 25) + "' "
+	if got != want {
+		t.Errorf("unknownCommandError() = %q, want %q", got, want)
+	}
+}
+
+func Test_commandCheckArity(t *testing.T) {
+	tests := []struct {
+		name  string
+		arity int
+		argc  int
+		want  bool
+	}{
+		{name: "It should accept the exact count", arity: 2, argc: 2, want: true},
+		{name: "It should reject fewer than the exact count", arity: 2, argc: 1, want: false},
+		{name: "It should reject more than the exact count", arity: 2, argc: 3, want: false},
+		{name: "It should accept the minimum count", arity: -2, argc: 2, want: true},
+		{name: "It should accept more than the minimum count", arity: -2, argc: 5, want: true},
+		{name: "It should reject fewer than the minimum count", arity: -2, argc: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &command{arity: tt.arity}
+			if got := cmd.checkArity(tt.argc); got != tt.want {
+				t.Errorf("checkArity(%d) = %v, want %v", tt.argc, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_commandKeyPositions(t *testing.T) {
+	args := func(n int) [][]byte {
+		return make([][]byte, n)
+	}
+	tests := []struct {
+		name string
+		cmd  *command
+		args [][]byte
+		want []int
+	}{
+		{
+			name: "It should return nothing for commands without keys",
+			cmd:  &command{},
+			args: args(2),
+			want: nil,
+		},
+		{
+			name: "It should return a single key",
+			cmd:  &command{firstKey: 1, lastKey: 1, step: 1},
+			args: args(3),
+			want: []int{1},
+		},
+		{
+			name: "It should count a negative last key from the end",
+			cmd:  &command{firstKey: 1, lastKey: -1, step: 1},
+			args: args(4),
+			want: []int{1, 2, 3},
+		},
+		{
+			name: "It should skip values between keys",
+			cmd:  &command{firstKey: 1, lastKey: -1, step: 2},
+			args: args(5),
+			want: []int{1, 3},
+		},
+		{
+			name: "It should stop before a trailing argument",
+			cmd:  &command{firstKey: 1, lastKey: -2, step: 1},
+			args: args(4),
+			want: []int{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.keyPositions(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keyPositions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_commandTable(t *testing.T) {
+	check := func(name string, cmd *command) {
+		if cmd.name != name {
+			t.Errorf("command %q is registered as %q", cmd.name, name)
+		}
+		// A container that requires a subcommand never runs on its own
+		needsHandler := cmd.subcommands == nil || cmd.arity == -1
+		if (needsHandler && cmd.handler == nil) || cmd.arity == 0 {
+			t.Errorf("command %q has no handler or arity", name)
+		}
+		if cmd.firstKey != 0 && cmd.step <= 0 {
+			t.Errorf("command %q has keys but no step", name)
+		}
+		if cmd.summary == "" || cmd.since == "" || cmd.group == "" || cmd.complexity == "" {
+			t.Errorf("command %q is not documented", name)
+		}
+	}
+	for name, cmd := range commands {
+		if name != strings.ToLower(name) {
+			t.Errorf("command %q is not registered in lower case", name)
+		}
+		check(name, cmd)
+		for subName, sub := range cmd.subcommands {
+			check(name+"|"+subName, sub)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name: "ping", handler: pingCommand, arity: -1, flags: flagFast,
+		summary: "Returns the server's liveliness response.", since: "1.0.0", group: "connection", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "echo", handler: echoCommand, arity: 2, flags: flagFast,
+		summary: "Returns the given string.", since: "1.0.0", group: "connection", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hello", handler: helloCommand, arity: -1, flags: flagNoscript | flagFast,
+		summary: "Handshakes with the Redis server.", since: "6.0.0", group: "connection", complexity: "O(1)",
+	})
+}
+
+// pingCommand implements PING [message]
+func pingCommand(c *client, args [][]byte) {
+	if len(args) > 2 {
+		c.w.WriteError(wrongArityError("ping"))
+		return
+	}
+	if len(args) == 2 {
+		c.w.WriteBulk(args[1])
+		return
+	}
+	c.w.WriteSimpleString("PONG")
+}
+
+// echoCommand implements ECHO message
+func echoCommand(c *client, args [][]byte) {
+	c.w.WriteBulk(args[1])
+}
+
+const (
+	serverName    = "redis"
+	serverVersion = "7.4.0"
+)
+
+// helloCommand implements HELLO [protover [AUTH username password] [SETNAME clientname]].
+// It switches the connection to the requested protocol version and replies
+// with a map describing the server, encoded in that version.
+func helloCommand(c *client, args [][]byte) {
+	args = args[1:]
+	proto := c.w.Protocol()
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			c.w.WriteError("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if version != 2 && version != 3 {
+			c.w.WriteError("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = version
+	}
+
+	name := c.name
+	for i := 1; i < len(args); i++ {
+		option := string(args[i])
+		remaining := len(args) - i - 1
+		switch {
+		case strings.EqualFold(option, "AUTH") && remaining >= 2:
+			// There is no access control, so only the default user exists
+			// and it does not need a password
+			if string(args[i+1]) != "default" {
+				c.w.WriteError("WRONGPASS invalid username-password pair or user is disabled.")
+				return
+			}
+			i += 2
+		case strings.EqualFold(option, "SETNAME") && remaining >= 1:
+			if !validClientName(string(args[i+1])) {
+				c.w.WriteError("ERR Client names cannot contain spaces, newlines or special characters.")
+				return
+			}
+			name = string(args[i+1])
+			i++
+		default:
+			c.w.WriteError(sanitizeError("ERR Syntax error in HELLO option '" + truncate(args[i], 128) + "'"))
+			return
+		}
+	}
+
+	c.name = name
+	c.w.SetProtocol(proto)
+	c.w.WriteMapHeader(7)
+	c.w.WriteBulkString("server")
+	c.w.WriteBulkString(serverName)
+	c.w.WriteBulkString("version")
+	c.w.WriteBulkString(serverVersion)
+	c.w.WriteBulkString("proto")
+	c.w.WriteInteger(int64(proto))
+	c.w.WriteBulkString("id")
+	c.w.WriteInteger(c.id)
+	c.w.WriteBulkString("mode")
+	c.w.WriteBulkString("standalone")
+	c.w.WriteBulkString("role")
+	c.w.WriteBulkString("master")
+	c.w.WriteBulkString("modules")
+	c.w.WriteArrayHeader(0)
+}
+
+// validClientName reports whether name only holds printable characters
+// other than space, like Redis requires
+func validClientName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package server
+
+import "time"
+
+// defaultHz is how many times a second the background tasks run unless the
+// Config says otherwise
+const defaultHz = 10
+
+// cron runs the background tasks of the server hz times a second until
+// stop is closed
+func (s *Server) cron(stop <-chan struct{}) {
+	period := s.cronPeriod()
+	timer := s.clock.NewTimer(period)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C():
+			s.serverCron()
+			timer.Reset(period)
+		}
+	}
+}
+
+// serverCron runs the background tasks once
+func (s *Server) serverCron() {
+	s.db.Lock()
+	s.clientsCron()
+	// Like Redis, spend at most a quarter of each period expiring keys so
+	// commands are not held up for long
+	deadline := s.clock.Now().Add(s.cronPeriod() / 4)
+	s.db.activeExpireCycle(deadline)
+	s.db.activeExpireHashFields(deadline)
+	s.db.Unlock()
+}
+
+// clientsCron closes the connections of the clients that have been idle
+// for longer than the configured timeout. Blocked clients only time out
+// when their blocking command says so. It expects the lock of the keyspace
+// to be held.
+func (s *Server) clientsCron() {
+	if s.cfg.Timeout <= 0 {
+		return
+	}
+	current := s.clock.Now()
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for c := range s.clients {
+		if c.blocked == nil && c.idle(current) > s.cfg.Timeout {
+			// handleConnection notices the connection is closed and
+			// removes the client
+			c.conn.Close()
+		}
+	}
+}
+
+func (s *Server) cronPeriod() time.Duration {
+	hz := s.cfg.Hz
+	if hz <= 0 {
+		hz = defaultHz
+	}
+	return time.Second / time.Duration(hz)
+}
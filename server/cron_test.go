@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_serverCronExpiresKeysNobodyReads(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("SET k v PX 20\r\nSET live v PX 60000\r\n")
+
+	clock.Advance(20 * time.Millisecond)
+	srv.serverCron()
+	if _, ok := srv.db.data["k"]; ok {
+		t.Errorf("serverCron() did not remove the expired key")
+	}
+	if _, ok := srv.db.data["live"]; !ok {
+		t.Errorf("serverCron() removed a key that has not expired")
+	}
+}
+
+func Test_serverCronExpiresHashFieldsNobodyReads(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("HSET h a 1 b 2\r\nHPEXPIRE h 20 FIELDS 1 a\r\n")
+
+	clock.Advance(20 * time.Millisecond)
+	srv.serverCron()
+	if h := srv.db.data["h"].(*hash); h.len() != 1 {
+		t.Errorf("serverCron() left %d fields, want the field that has not expired", h.len())
+	}
+}
+
+func Test_ServeTimesOutIdleClients(t *testing.T) {
+	clock := newFakeClock()
+	addr := startConfiguredServer(t, Config{Clock: clock, Timeout: time.Second})
+	idle := dialTestServer(t, addr)
+	active := dialTestServer(t, addr)
+	for _, conn := range []net.Conn{idle, active} {
+		send(t, conn, "PING\r\n")
+		expectReply(t, conn, "+PONG\r\n")
+	}
+
+	// Wait for the cron to be waiting on the clock before every step
+	clock.blockUntil(1)
+	clock.Advance(600 * time.Millisecond)
+	clock.blockUntil(1)
+	send(t, active, "PING\r\n")
+	expectReply(t, active, "+PONG\r\n")
+	clock.Advance(600 * time.Millisecond)
+
+	_ = idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("reading from the idle client error = %v, want %v", err, io.EOF)
+	}
+	clock.blockUntil(1)
+	send(t, active, "PING\r\n")
+	expectReply(t, active, "+PONG\r\n")
+}
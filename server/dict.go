@@ -0,0 +1,132 @@
+package server
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// dictMinSize is the number of buckets of an empty dict
+const dictMinSize = 4
+
+// dict is a hash table with chained buckets, like the dict of Redis. Go
+// maps would do for lookups, but they cannot be iterated a piece at a time
+// across commands, which is what SCAN-like commands need: a dict can, with
+// the cursor scheme of Redis that returns every entry present for the
+// whole iteration even when the table grows or shrinks in between.
+type dict[V any] struct {
+	seed    maphash.Seed
+	buckets []*dictEntry[V]
+	count   int
+}
+
+type dictEntry[V any] struct {
+	key   string
+	value V
+	next  *dictEntry[V]
+}
+
+func newDict[V any]() *dict[V] {
+	return &dict[V]{seed: maphash.MakeSeed(), buckets: make([]*dictEntry[V], dictMinSize)}
+}
+
+func (d *dict[V]) len() int {
+	return d.count
+}
+
+func (d *dict[V]) bucket(key string) int {
+	return int(maphash.String(d.seed, key) & uint64(len(d.buckets)-1))
+}
+
+// get returns the value of key
+func (d *dict[V]) get(key string) (V, bool) {
+	for e := d.buckets[d.bucket(key)]; e != nil; e = e.next {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// set stores value under key, and reports whether key is new
+func (d *dict[V]) set(key string, value V) bool {
+	i := d.bucket(key)
+	for e := d.buckets[i]; e != nil; e = e.next {
+		if e.key == key {
+			e.value = value
+			return false
+		}
+	}
+	d.buckets[i] = &dictEntry[V]{key: key, value: value, next: d.buckets[i]}
+	d.count++
+	// Keep about one entry per bucket
+	if d.count > len(d.buckets) {
+		d.resize(len(d.buckets) * 2)
+	}
+	return true
+}
+
+// delete removes key, and reports whether it was there
+func (d *dict[V]) delete(key string) bool {
+	i := d.bucket(key)
+	for p := &d.buckets[i]; *p != nil; p = &(*p).next {
+		if (*p).key == key {
+			*p = (*p).next
+			d.count--
+			// Shrink tables that are mostly empty, like Redis does below
+			// 1/8 full
+			if len(d.buckets) > dictMinSize && d.count < len(d.buckets)/8 {
+				d.resize(len(d.buckets) / 2)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// forEach calls fn with every entry until it returns false
+func (d *dict[V]) forEach(fn func(key string, value V) bool) {
+	for _, e := range d.buckets {
+		for ; e != nil; e = e.next {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// resize rehashes d into size buckets, a power of two. Unlike Redis it
+// rehashes all at once rather than a bucket at a time, which the cursors
+// of scan cope with the same.
+func (d *dict[V]) resize(size int) {
+	old := d.buckets
+	d.buckets = make([]*dictEntry[V], size)
+	for _, e := range old {
+		for e != nil {
+			next := e.next
+			i := d.bucket(e.key)
+			e.next = d.buckets[i]
+			d.buckets[i] = e
+			e = next
+		}
+	}
+}
+
+// scan calls fn with the entries of the bucket cursor points at and
+// returns the cursor of the next bucket, or 0 once every bucket has been
+// visited. Starting at 0 and calling scan until it returns 0 visits every
+// entry that stays in d all along at least once. The cursor is incremented
+// on its reversed bits, so buckets that the table splits or merges when it
+// is resized are never skipped.
+func (d *dict[V]) scan(cursor uint64, fn func(key string, value V)) uint64 {
+	mask := uint64(len(d.buckets) - 1)
+	for e := d.buckets[cursor&mask]; e != nil; e = e.next {
+		fn(e.key, e.value)
+	}
+	// Set the bits above the mask so incrementing the reversed cursor
+	// carries into the bits of the mask
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_dictGrowsAndShrinks(t *testing.T) {
+	d := newDict[int]()
+	for i := 0; i < 1000; i++ {
+		if !d.set(strconv.Itoa(i), i) {
+			t.Fatalf("set(%d) reported an existing key", i)
+		}
+	}
+	if d.set("7", 70) {
+		t.Errorf("set(7) again reported a new key")
+	}
+	if d.len() != 1000 || len(d.buckets) < 1000 {
+		t.Errorf("len() = %d with %d buckets, want 1000 with at least as many buckets", d.len(), len(d.buckets))
+	}
+	for i := 0; i < 990; i++ {
+		if !d.delete(strconv.Itoa(i)) {
+			t.Fatalf("delete(%d) = false", i)
+		}
+	}
+	if d.delete("0") {
+		t.Errorf("delete(0) again = true")
+	}
+	if len(d.buckets) > 128 {
+		t.Errorf("%d buckets for %d keys, want the table to shrink", len(d.buckets), d.len())
+	}
+	for i := 990; i < 1000; i++ {
+		if v, ok := d.get(strconv.Itoa(i)); !ok || v != i {
+			t.Errorf("get(%d) = %d, %v", i, v, ok)
+		}
+	}
+}
+
+func Test_dictScan(t *testing.T) {
+	tests := []struct {
+		name string
+		// during is called between the calls to scan, with how many calls
+		// there have been
+		during func(d *dict[int], calls int)
+	}{
+		{
+			name:   "It should visit every key of a dict that does not change",
+			during: func(d *dict[int], calls int) {},
+		},
+		{
+			name: "It should visit every key present all along while the dict grows",
+			during: func(d *dict[int], calls int) {
+				// Stop growing at some point, or the scan never catches up
+				if calls > 50 {
+					return
+				}
+				for i := 0; i < 40; i++ {
+					d.set("new"+strconv.Itoa(calls)+"-"+strconv.Itoa(i), 0)
+				}
+			},
+		},
+		{
+			name: "It should visit every key present all along while the dict shrinks",
+			during: func(d *dict[int], calls int) {
+				for i := calls * 20; i < calls*20+20; i++ {
+					d.delete("gone" + strconv.Itoa(i))
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDict[int]()
+			for i := 0; i < 100; i++ {
+				d.set("key"+strconv.Itoa(i), i)
+			}
+			for i := 0; i < 2000; i++ {
+				d.set("gone"+strconv.Itoa(i), i)
+			}
+
+			seen := map[string]bool{}
+			cursor, calls := uint64(0), 0
+			for {
+				cursor = d.scan(cursor, func(key string, value int) { seen[key] = true })
+				calls++
+				if cursor == 0 {
+					break
+				}
+				tt.during(d, calls)
+			}
+			for i := 0; i < 100; i++ {
+				if key := "key" + strconv.Itoa(i); !seen[key] {
+					t.Errorf("scan did not visit %q", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name: "del", handler: delCommand, arity: -2, flags: flagWrite, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Deletes one or more keys.", since: "1.0.0", group: "generic",
+		complexity: "O(N) where N is the number of keys that will be removed",
+	})
+	registerCommand(&command{
+		name: "exists", handler: existsCommand, arity: -2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Determines whether one or more keys exist.", since: "1.0.0", group: "generic",
+		complexity: "O(N) where N is the number of keys to check",
+	})
+	registerCommand(&command{
+		name: "expire", handler: expireCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the expiration time of a key in seconds.", since: "1.0.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "pexpire", handler: pexpireCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the expiration time of a key in milliseconds.", since: "2.6.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "expireat", handler: expireAtCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the expiration time of a key to a Unix timestamp.", since: "1.2.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "pexpireat", handler: pexpireAtCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.", since: "2.6.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "ttl", handler: ttlCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time in seconds of a key.", since: "1.0.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "pttl", handler: pttlCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time in milliseconds of a key.", since: "2.6.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "expiretime", handler: expireTimeCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time of a key as a Unix timestamp.", since: "7.0.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "pexpiretime", handler: pexpireTimeCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.", since: "7.0.0", group: "generic", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "persist", handler: persistCommand, arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes the expiration time of a key.", since: "2.2.0", group: "generic", complexity: "O(1)",
+	})
+}
+
+// delCommand implements DEL key [key ...]
+func delCommand(c *client, args [][]byte) {
+	var deleted int64
+	for _, key := range args[1:] {
+		if c.db.remove(string(key)) {
+			deleted++
+		}
+	}
+	c.w.WriteInteger(deleted)
+}
+
+// existsCommand implements EXISTS key [key ...]. A key given more than once
+// is counted every time.
+func existsCommand(c *client, args [][]byte) {
+	var count int64
+	for _, key := range args[1:] {
+		if _, ok := c.db.lookup(string(key)); ok {
+			count++
+		}
+	}
+	c.w.WriteInteger(count)
+}
+
+// expireOptions are the conditions EXPIRE and its variants take
+type expireOptions struct {
+	nx, xx, gt, lt bool
+}
+
+func parseExpireOptions(args [][]byte) (expireOptions, error) {
+	var opts expireOptions
+	for _, arg := range args {
+		switch strings.ToUpper(string(arg)) {
+		case "NX":
+			opts.nx = true
+		case "XX":
+			opts.xx = true
+		case "GT":
+			opts.gt = true
+		case "LT":
+			opts.lt = true
+		default:
+			return expireOptions{}, errors.New(sanitizeError("ERR Unsupported option " + truncate(arg, 128)))
+		}
+	}
+	if opts.nx && (opts.xx || opts.gt || opts.lt) {
+		return expireOptions{}, errors.New("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if opts.gt && opts.lt {
+		return expireOptions{}, errors.New("ERR GT and LT options at the same time are not compatible")
+	}
+	return opts, nil
+}
+
+// allows reports whether the options allow a key whose current expiry time
+// is current, if it has one, to be given the expiry time when
+func (opts expireOptions) allows(current int64, hasExpire bool, when int64) bool {
+	switch {
+	case opts.nx && hasExpire:
+		return false
+	case opts.xx && !hasExpire:
+		return false
+	// A key without an expiry time lives forever, which is greater than
+	// any time
+	case opts.gt && (!hasExpire || when <= current):
+		return false
+	case opts.lt && hasExpire && when >= current:
+		return false
+	}
+	return true
+}
+
+// expireGeneric implements EXPIRE, PEXPIRE, EXPIREAT and PEXPIREAT. unit
+// is how many milliseconds the argument counts in, and relative is set if
+// it is relative to the current time.
+func expireGeneric(c *client, args [][]byte, unit int64, relative bool) {
+	key := string(args[1])
+	opts, err := parseExpireOptions(args[3:])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	when, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+
+	invalid := "ERR invalid expire time in '" + strings.ToLower(string(args[0])) + "' command"
+	if when > math.MaxInt64/unit || when < math.MinInt64/unit {
+		c.w.WriteError(invalid)
+		return
+	}
+	when *= unit
+	if relative {
+		if when, ok = checkedAdd(when, c.db.now()); !ok {
+			c.w.WriteError(invalid)
+			return
+		}
+	}
+
+	if _, exists := c.db.lookup(key); !exists {
+		c.w.WriteInteger(0)
+		return
+	}
+	current, hasExpire := c.db.expires[key]
+	if !opts.allows(current, hasExpire, when) {
+		c.w.WriteInteger(0)
+		return
+	}
+	// A time in the past deletes the key
+	c.db.setExpire(key, when)
+	c.w.WriteInteger(1)
+}
+
+// expireCommand implements EXPIRE key seconds [NX | XX | GT | LT]
+func expireCommand(c *client, args [][]byte) {
+	expireGeneric(c, args, 1000, true)
+}
+
+// pexpireCommand implements PEXPIRE key milliseconds [NX | XX | GT | LT]
+func pexpireCommand(c *client, args [][]byte) {
+	expireGeneric(c, args, 1, true)
+}
+
+// expireAtCommand implements EXPIREAT key unix-time-seconds [NX | XX | GT | LT]
+func expireAtCommand(c *client, args [][]byte) {
+	expireGeneric(c, args, 1000, false)
+}
+
+// pexpireAtCommand implements PEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT]
+func pexpireAtCommand(c *client, args [][]byte) {
+	expireGeneric(c, args, 1, false)
+}
+
+// ttlGeneric implements TTL, PTTL, EXPIRETIME and PEXPIRETIME. It replies
+// with -2 if the key does not exist and -1 if it has no expiry time. Like
+// in Redis, a time to live in seconds is rounded to the nearest second,
+// while an expiry time in seconds is truncated.
+func ttlGeneric(c *client, args [][]byte, inMilliseconds, absolute bool) {
+	key := string(args[1])
+	if _, exists := c.db.lookup(key); !exists {
+		c.w.WriteInteger(-2)
+		return
+	}
+	when, ok := c.db.expires[key]
+	if !ok {
+		c.w.WriteInteger(-1)
+		return
+	}
+	switch {
+	case absolute && !inMilliseconds:
+		when /= 1000
+	case !absolute:
+		when = max(when-c.db.now(), 0)
+		if !inMilliseconds {
+			when = (when + 500) / 1000
+		}
+	}
+	c.w.WriteInteger(when)
+}
+
+// ttlCommand implements TTL key
+func ttlCommand(c *client, args [][]byte) {
+	ttlGeneric(c, args, false, false)
+}
+
+// pttlCommand implements PTTL key
+func pttlCommand(c *client, args [][]byte) {
+	ttlGeneric(c, args, true, false)
+}
+
+// expireTimeCommand implements EXPIRETIME key
+func expireTimeCommand(c *client, args [][]byte) {
+	ttlGeneric(c, args, false, true)
+}
+
+// pexpireTimeCommand implements PEXPIRETIME key
+func pexpireTimeCommand(c *client, args [][]byte) {
+	ttlGeneric(c, args, true, true)
+}
+
+// persistCommand implements PERSIST key
+func persistCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	if _, exists := c.db.lookup(key); !exists {
+		c.w.WriteInteger(0)
+		return
+	}
+	if c.db.persist(key) {
+		c.w.WriteInteger(1)
+		return
+	}
+	c.w.WriteInteger(0)
+}
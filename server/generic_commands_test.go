@@ -0,0 +1,162 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_GenericCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should count the keys it deletes",
+			input: "MSET a 1 b 2\r\nDEL a b c\r\nEXISTS a b\r\n",
+			want:  "+OK\r\n:2\r\n:0\r\n",
+		},
+		{
+			name:  "It should count keys given more than once",
+			input: "SET a 1\r\nEXISTS a a missing\r\n",
+			want:  "+OK\r\n:2\r\n",
+		},
+		{
+			name:  "It should require a key",
+			input: "DEL\r\nEXISTS\r\n",
+			want:  "-ERR wrong number of arguments for 'del' command\r\n-ERR wrong number of arguments for 'exists' command\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ExpireCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should set and report a time to live",
+			input: "SET k v\r\nEXPIRE k 100\r\nTTL k\r\nPERSIST k\r\nTTL k\r\nPERSIST k\r\n",
+			want:  "+OK\r\n:1\r\n:100\r\n:1\r\n:-1\r\n:0\r\n",
+		},
+		{
+			name:  "It should report missing keys",
+			input: "EXPIRE k 100\r\nTTL k\r\nPTTL k\r\nEXPIRETIME k\r\nPEXPIRETIME k\r\nPERSIST k\r\n",
+			want:  ":0\r\n:-2\r\n:-2\r\n:-2\r\n:-2\r\n:0\r\n",
+		},
+		{
+			name:  "It should report keys without a time to live",
+			input: "SET k v\r\nTTL k\r\nPTTL k\r\nEXPIRETIME k\r\nPEXPIRETIME k\r\n",
+			want:  "+OK\r\n:-1\r\n:-1\r\n:-1\r\n:-1\r\n",
+		},
+		{
+			name:  "It should report absolute expiry times",
+			input: "SET k v\r\nPEXPIREAT k 33177117420000\r\nEXPIRETIME k\r\nPEXPIRETIME k\r\nEXPIREAT k 33177117421\r\nPEXPIRETIME k\r\n",
+			want:  "+OK\r\n:1\r\n:33177117420\r\n:33177117420000\r\n:1\r\n:33177117421000\r\n",
+		},
+		{
+			name:  "It should truncate absolute expiry times in seconds",
+			input: "SET k v\r\nPEXPIREAT k 33177117420999\r\nEXPIRETIME k\r\nPEXPIREAT k 9223372036854775807\r\nEXPIRETIME k\r\n",
+			want:  "+OK\r\n:1\r\n:33177117420\r\n:1\r\n:9223372036854775\r\n",
+		},
+		{
+			name:  "It should delete keys given a time in the past",
+			input: "MSET a 1 b 2 c 3 d 4\r\nEXPIRE a -1\r\nPEXPIRE b 0\r\nEXPIREAT c 1\r\nPEXPIREAT d 1\r\nEXISTS a b c d\r\n",
+			want:  "+OK\r\n:1\r\n:1\r\n:1\r\n:1\r\n:0\r\n",
+		},
+		{
+			name:  "It should only set a new time to live with NX",
+			input: "SET k v\r\nEXPIRE k 100 NX\r\nEXPIRE k 200 NX\r\nTTL k\r\n",
+			want:  "+OK\r\n:1\r\n:0\r\n:100\r\n",
+		},
+		{
+			name:  "It should only replace a time to live with XX",
+			input: "SET k v\r\nEXPIRE k 100 XX\r\nEXPIRE k 100\r\nEXPIRE k 200 XX\r\nTTL k\r\n",
+			want:  "+OK\r\n:0\r\n:1\r\n:1\r\n:200\r\n",
+		},
+		{
+			name:  "It should only extend a time to live with GT",
+			input: "SET k v\r\nEXPIRE k 100 GT\r\nEXPIRE k 100\r\nEXPIRE k 50 GT\r\nEXPIRE k 200 GT\r\nTTL k\r\n",
+			want:  "+OK\r\n:0\r\n:1\r\n:0\r\n:1\r\n:200\r\n",
+		},
+		{
+			name:  "It should only shorten a time to live with LT",
+			input: "SET k v\r\nEXPIRE k 100 LT\r\nEXPIRE k 200 LT\r\nEXPIRE k 50 LT\r\nTTL k\r\n",
+			want:  "+OK\r\n:1\r\n:0\r\n:1\r\n:50\r\n",
+		},
+		{
+			name:  "It should reject incompatible options",
+			input: "EXPIRE k 1 NX XX\r\nEXPIRE k 1 GT LT\r\nEXPIRE k 1 SOON\r\n*4\r\n$6\r\nEXPIRE\r\n$1\r\nk\r\n$2\r\n10\r\n$9\r\nx\r\n+PWNED\r\n",
+			want: "-ERR NX and XX, GT or LT options at the same time are not compatible\r\n" +
+				"-ERR GT and LT options at the same time are not compatible\r\n-ERR Unsupported option SOON\r\n" +
+				"-ERR Unsupported option x  +PWNED\r\n",
+		},
+		{
+			name:  "It should reject invalid times",
+			input: "EXPIRE k ten\r\nEXPIRE k 9223372036854775807\r\nPEXPIRE k 9223372036854775807\r\n",
+			want: "-ERR value is not an integer or out of range\r\n-ERR invalid expire time in 'expire' command\r\n" +
+				"-ERR invalid expire time in 'pexpire' command\r\n",
+		},
+		{
+			name:  "It should remove the time to live when a key is set again",
+			input: "SET k v EX 100\r\nSET k w\r\nTTL k\r\n",
+			want:  "+OK\r\n+OK\r\n:-1\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_PTTLCountsDown(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("SET k v\r\nPEXPIRE k 100000\r\n")
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    string
+	}{
+		{
+			name:    "It should count down the time to live as time passes",
+			advance: 40 * time.Second,
+			want:    ":60000\r\n:60\r\n",
+		},
+		{
+			name:    "It should round the time to live to the nearest second",
+			advance: 59500 * time.Millisecond,
+			want:    ":500\r\n:1\r\n",
+		},
+		{
+			name:    "It should still find the key 1ms before it expires",
+			advance: 499 * time.Millisecond,
+			want:    ":1\r\n:0\r\n",
+		},
+		{
+			name:    "It should expire the key once its time to live runs out",
+			advance: time.Millisecond,
+			want:    ":-2\r\n:-2\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			if got := srv.HandleRequest("PTTL k\r\nTTL k\r\n"); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
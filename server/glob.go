@@ -0,0 +1,136 @@
+package server
+
+// stringMatch reports whether str matches the glob-style pattern, using the
+// same rules as Redis. A * matches any sequence of bytes, including none, and
+// a ? matches any single byte. [abc] matches one of the bytes in the
+// brackets, [^abc] any byte that is not, and [a-z] a range. A backslash
+// makes the byte after it match literally. If nocase is set letters match
+// regardless of case.
+func stringMatch(pattern, str []byte, nocase bool) bool {
+	skipLongerMatches := false
+	return stringMatchImpl(pattern, str, nocase, &skipLongerMatches, 0)
+}
+
+// maxMatchNesting is how deeply the stars of a pattern may nest before it
+// is taken not to match, like in Redis
+const maxMatchNesting = 1000
+
+// stringMatchImpl is stringMatch for the star nesting level nesting. Like
+// in Redis, once the rest of a pattern after a star fails to match any
+// suffix of str, it sets skipLongerMatches: matching fewer bytes with an
+// earlier star only leaves longer suffixes, which cannot match either, so
+// the search stops rather than backtracking exponentially.
+func stringMatchImpl(pattern, str []byte, nocase bool, skipLongerMatches *bool, nesting int) bool {
+	if nesting > maxMatchNesting {
+		return false
+	}
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Consecutive stars are the same as a single one
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(str); i++ {
+				if stringMatchImpl(pattern[1:], str[i:], nocase, skipLongerMatches, nesting+1) {
+					return true
+				}
+				if *skipLongerMatches {
+					return false
+				}
+			}
+			*skipLongerMatches = true
+			return false
+		case '?':
+			if len(str) == 0 {
+				return false
+			}
+			str = str[1:]
+		case '[':
+			if len(str) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchClass(pattern[1:], str[0], nocase)
+			if !matched {
+				return false
+			}
+			str = str[1:]
+			// matchClass leaves pattern on the closing bracket
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(str) == 0 || !equalByte(pattern[0], str[0], nocase) {
+				return false
+			}
+			str = str[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(str) == 0
+}
+
+// matchClass matches c against the bracketed set at the start of pattern,
+// which starts right after the opening bracket. It returns whether c is in
+// the set and the pattern starting at the closing bracket, or at its last
+// byte if the bracket is never closed.
+func matchClass(pattern []byte, c byte, nocase bool) (bool, []byte) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for {
+		if len(pattern) == 0 {
+			// An unterminated class is treated as if it ended here
+			return matched != negate, []byte{']'}
+		}
+		switch {
+		case pattern[0] == ']':
+			return matched != negate, pattern
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			pattern = pattern[1:]
+			if equalByte(pattern[0], c, nocase) {
+				matched = true
+			}
+		case len(pattern) >= 3 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			lc := c
+			if nocase {
+				start, end, lc = toLower(start), toLower(end), toLower(c)
+			}
+			if lc >= start && lc <= end {
+				matched = true
+			}
+			pattern = pattern[2:]
+		default:
+			if equalByte(pattern[0], c, nocase) {
+				matched = true
+			}
+		}
+		pattern = pattern[1:]
+	}
+}
+
+func equalByte(a, b byte, nocase bool) bool {
+	if nocase {
+		return toLower(a) == toLower(b)
+	}
+	return a == b
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
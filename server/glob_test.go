@@ -0,0 +1,60 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_stringMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		str     string
+		nocase  bool
+		want    bool
+	}{
+		{name: "It should match an identical string", pattern: "hello", str: "hello", want: true},
+		{name: "It should not match a different string", pattern: "hello", str: "hellO", want: false},
+		{name: "It should ignore case when asked to", pattern: "hello", str: "hellO", nocase: true, want: true},
+		{name: "It should match anything with a star", pattern: "*", str: "", want: true},
+		{name: "It should match a prefix", pattern: "user:*", str: "user:1000", want: true},
+		{name: "It should match stars in the middle", pattern: "h*l*o", str: "hello", want: true},
+		{name: "It should not match a missing suffix", pattern: "h*x", str: "hello", want: false},
+		{name: "It should backtrack over several stars", pattern: "*a*b*c", str: "xaxbxaxbxc", want: true},
+		{name: "It should not match when a later star runs out", pattern: "*a*b*c", str: "xaxbxaxbx", want: false},
+		{name: "It should match one byte with a question mark", pattern: "h?llo", str: "hallo", want: true},
+		{name: "It should require a byte for a question mark", pattern: "hello?", str: "hello", want: false},
+		{name: "It should match a class", pattern: "h[ae]llo", str: "hello", want: true},
+		{name: "It should not match outside a class", pattern: "h[ae]llo", str: "hillo", want: false},
+		{name: "It should match a negated class", pattern: "h[^e]llo", str: "hallo", want: true},
+		{name: "It should not match inside a negated class", pattern: "h[^e]llo", str: "hello", want: false},
+		{name: "It should match a range", pattern: "h[a-b]llo", str: "hbllo", want: true},
+		{name: "It should match a reversed range", pattern: "h[b-a]llo", str: "hallo", want: true},
+		{name: "It should match a range ignoring case", pattern: "h[A-C]llo", str: "hbllo", nocase: true, want: true},
+		{name: "It should match escaped special characters", pattern: `h\*llo`, str: "h*llo", want: true},
+		{name: "It should not treat escaped stars as wildcards", pattern: `h\*llo`, str: "hello", want: false},
+		{name: "It should match an escaped bracket in a class", pattern: `[\]]`, str: "]", want: true},
+		{name: "It should treat an unterminated class as ending the pattern", pattern: "a[bc", str: "ab", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringMatch([]byte(tt.pattern), []byte(tt.str), tt.nocase); got != tt.want {
+				t.Errorf("stringMatch(%q, %q) = %v, want %v", tt.pattern, tt.str, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_stringMatchDoesNotBacktrackExponentially(t *testing.T) {
+	pattern := []byte(strings.Repeat("*a", 20) + "*b")
+	str := []byte(strings.Repeat("a", 60))
+
+	start := time.Now()
+	if stringMatch(pattern, str, false) {
+		t.Errorf("stringMatch(%q, %q) = true, want false", pattern, str)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stringMatch() took %v, want under a second", elapsed)
+	}
+}
@@ -1,50 +1,69 @@
 package server
 
 import (
-	"errors"
+	"io"
 	"strings"
 
 	"github.com/nilayrajderkar/redis-implementation/resp"
 )
 
-// HandleRequest takes a serialized RESP string, deserializes it,
-// processes the command, and returns a serialized response
+// HandleRequest processes input with the default Config
 func HandleRequest(input string) string {
-	deserialized, err := resp.Deserialize(input)
-	if err != nil {
-		return resp.Serialize(err)
-	}
+	return defaultServer.HandleRequest(input)
+}
+
+// HandleRequest takes a serialized RESP string holding one or more
+// commands, processes them in order, and returns their serialized
+// responses concatenated together
+func (s *Server) HandleRequest(input string) string {
+	var output strings.Builder
+	w := resp.NewWriter(&output)
+	c := newClient(s.db, w)
 
-	array, ok := deserialized.(*[]interface{})
-	if !ok {
-		return resp.Serialize(errors.New("invalid command format"))
+	r := resp.NewReader(strings.NewReader(input))
+	r.SetLimits(s.cfg.Limits)
+	for {
+		command, err := readCommand(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.WriteError("ERR " + err.Error())
+			break
+		}
+		handleCommand(c, command)
 	}
 
-	// Need at least one element (the command)
-	if len(*array) == 0 {
-		return resp.Serialize(errors.New("empty command"))
+	// Writing to a strings.Builder never fails
+	_ = w.Flush()
+	return output.String()
+}
+
+// handleCommand processes an already deserialized command and writes the
+// response to the client
+func handleCommand(c *client, command resp.Value) {
+	w := c.w
+	if command.Kind() != resp.Array {
+		w.WriteError("invalid command format")
+		return
 	}
 
-	// First element should be the command string
-	command, ok := (*array)[0].(*string)
-	if !ok {
-		return resp.Serialize(errors.New("command must be a string"))
+	// Need at least one element (the command)
+	if len(command.Elems()) == 0 {
+		w.WriteError("empty command")
+		return
 	}
 
-	// Convert command to uppercase for case-insensitive comparison
-	switch strings.ToUpper(*command) {
-	case "PING":
-		return resp.Serialize("PONG")
-	case "ECHO":
-		if len(*array) < 2 {
-			return resp.Serialize(errors.New("ECHO requires an argument"))
+	// Every element should be a string. They are kept as bytes since
+	// arguments may be arbitrary binary data.
+	args := make([][]byte, len(command.Elems()))
+	for i, element := range command.Elems() {
+		if element.Kind() != resp.Bulk && element.Kind() != resp.SimpleString {
+			w.WriteError("command arguments must be strings")
+			return
 		}
-		// Echo back the second element
-		if str, ok := (*array)[1].(*string); ok {
-			return resp.Serialize(*str)
-		}
-		return resp.Serialize(errors.New("ECHO argument must be a string"))
-	default:
-		return resp.Serialize(errors.New("Unknown command '" + *command + "'"))
+		args[i] = element.Bytes()
 	}
+
+	dispatch(c, args)
 }
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// hashMaxListpackEntries is the most fields a hash keeps in a
+	// listpack, like the hash-max-listpack-entries setting of Redis
+	hashMaxListpackEntries = 128
+	// hashMaxListpackValue is the longest field or value a hash keeps in a
+	// listpack, like the hash-max-listpack-value setting of Redis
+	hashMaxListpackValue = 64
+)
+
+// hash is the hash type. Like in Redis, a small hash is stored as a
+// listpack, a flat slice of field-value pairs that is searched linearly,
+// which for a handful of fields is both smaller and faster than a hash
+// table. It is converted to a dict once it has more than
+// hashMaxListpackEntries fields or a field or value longer than
+// hashMaxListpackValue.
+type hash struct {
+	// pairs holds the fields and values, in insertion order, while dict is
+	// nil
+	pairs []hashPair
+	dict  *dict[string]
+
+	// expires maps the fields that have a time to live to the unix time in
+	// milliseconds at which they expire. It is nil while no field has one.
+	expires map[string]int64
+	// nextExpire is no later than the earliest time in expires, so the
+	// fields need only be looked at once it has passed
+	nextExpire int64
+}
+
+type hashPair struct {
+	field, value string
+}
+
+func newHash() *hash {
+	return &hash{}
+}
+
+// isListpack reports whether h is stored as a listpack
+func (h *hash) isListpack() bool {
+	return h.dict == nil
+}
+
+func (h *hash) len() int {
+	if h.isListpack() {
+		return len(h.pairs)
+	}
+	return h.dict.len()
+}
+
+func (h *hash) index(field string) int {
+	for i, pair := range h.pairs {
+		if pair.field == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the value of field
+func (h *hash) get(field string) (string, bool) {
+	if h.isListpack() {
+		if i := h.index(field); i >= 0 {
+			return h.pairs[i].value, true
+		}
+		return "", false
+	}
+	return h.dict.get(field)
+}
+
+// set sets field to value, and reports whether field is new. Unless
+// keepTTL is set, the field loses its time to live.
+func (h *hash) set(field, value string, keepTTL bool) bool {
+	if !keepTTL {
+		delete(h.expires, field)
+	}
+	if h.isListpack() {
+		if i := h.index(field); i >= 0 {
+			if len(value) <= hashMaxListpackValue {
+				h.pairs[i].value = value
+				return false
+			}
+		} else if len(h.pairs) < hashMaxListpackEntries && len(field) <= hashMaxListpackValue && len(value) <= hashMaxListpackValue {
+			h.pairs = append(h.pairs, hashPair{field, value})
+			return true
+		}
+		h.convertToDict()
+	}
+	return h.dict.set(field, value)
+}
+
+// delete removes field, and reports whether it was there
+func (h *hash) delete(field string) bool {
+	delete(h.expires, field)
+	if h.isListpack() {
+		i := h.index(field)
+		if i < 0 {
+			return false
+		}
+		h.pairs = slices.Delete(h.pairs, i, i+1)
+		return true
+	}
+	return h.dict.delete(field)
+}
+
+// forEach calls fn with every field and its value until it returns false
+func (h *hash) forEach(fn func(field, value string) bool) {
+	if h.isListpack() {
+		for _, pair := range h.pairs {
+			if !fn(pair.field, pair.value) {
+				return
+			}
+		}
+		return
+	}
+	h.dict.forEach(fn)
+}
+
+// all returns the fields and values of h
+func (h *hash) all() []hashPair {
+	if h.isListpack() {
+		return slices.Clone(h.pairs)
+	}
+	pairs := make([]hashPair, 0, h.len())
+	h.dict.forEach(func(field, value string) bool {
+		pairs = append(pairs, hashPair{field, value})
+		return true
+	})
+	return pairs
+}
+
+func (h *hash) convertToDict() {
+	h.dict = newDict[string]()
+	for _, pair := range h.pairs {
+		h.dict.set(pair.field, pair.value)
+	}
+	h.pairs = nil
+}
+
+// expireTime returns the unix time in milliseconds at which field expires,
+// if it has a time to live
+func (h *hash) expireTime(field string) (int64, bool) {
+	when, ok := h.expires[field]
+	return when, ok
+}
+
+// setExpire sets field to expire at the unix time when, in milliseconds.
+// field must exist.
+func (h *hash) setExpire(field string, when int64) {
+	if h.expires == nil {
+		h.expires = map[string]int64{}
+		h.nextExpire = when
+	}
+	h.expires[field] = when
+	h.nextExpire = min(h.nextExpire, when)
+}
+
+// persist removes the time to live of field, and reports whether it had
+// one
+func (h *hash) persist(field string) bool {
+	if _, ok := h.expires[field]; !ok {
+		return false
+	}
+	delete(h.expires, field)
+	return true
+}
+
+// expireFields deletes the fields that expire at or before the unix time
+// now, in milliseconds, and returns how many it deleted
+func (h *hash) expireFields(now int64) int {
+	if len(h.expires) == 0 || now < h.nextExpire {
+		return 0
+	}
+	deleted := 0
+	next := int64(math.MaxInt64)
+	for field, when := range h.expires {
+		if when <= now {
+			h.delete(field)
+			deleted++
+		} else {
+			next = min(next, when)
+		}
+	}
+	if len(h.expires) == 0 {
+		h.expires = nil
+	}
+	h.nextExpire = next
+	return deleted
+}
@@ -0,0 +1,867 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+var (
+	errHashNotInteger = errors.New("ERR hash value is not an integer")
+	errHashNotFloat   = errors.New("ERR hash value is not a float")
+	errInvalidCursor  = errors.New("ERR invalid cursor")
+	errNumFields      = errors.New("ERR The `numfields` parameter must match the number of arguments")
+)
+
+// hashFieldMaxExpireTime is the latest unix time in milliseconds a hash
+// field can be set to expire at, like in Redis
+const hashFieldMaxExpireTime = 1<<48 - 1
+
+func init() {
+	registerCommand(&command{
+		name: "hset", handler: hsetCommand, arity: -4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Creates or modifies the value of a field in a hash.", since: "2.0.0", group: "hash",
+		complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs.",
+	})
+	registerCommand(&command{
+		name: "hmset", handler: hmsetCommand, arity: -4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the values of multiple fields.", since: "2.0.0", group: "hash",
+		complexity: "O(N) where N is the number of fields being set.",
+	})
+	registerCommand(&command{
+		name: "hsetnx", handler: hsetnxCommand, arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the value of a field in a hash only when the field doesn't exist.", since: "2.0.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hget", handler: hgetCommand, arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the value of a field in a hash.", since: "2.0.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hmget", handler: hmgetCommand, arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the values of all fields in a hash.", since: "2.0.0", group: "hash",
+		complexity: "O(N) where N is the number of fields being requested.",
+	})
+	registerCommand(&command{
+		name: "hdel", handler: hdelCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.", since: "2.0.0",
+		group: "hash", complexity: "O(N) where N is the number of fields to be removed.",
+	})
+	registerCommand(&command{
+		name: "hexists", handler: hexistsCommand, arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Determines whether a field exists in a hash.", since: "2.0.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hlen", handler: hlenCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the number of fields in a hash.", since: "2.0.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hstrlen", handler: hstrlenCommand, arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the length of the value of a field.", since: "3.2.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hkeys", handler: hkeysCommand, arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns all fields in a hash.", since: "2.0.0", group: "hash", complexity: "O(N) where N is the size of the hash.",
+	})
+	registerCommand(&command{
+		name: "hvals", handler: hvalsCommand, arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns all values in a hash.", since: "2.0.0", group: "hash", complexity: "O(N) where N is the size of the hash.",
+	})
+	registerCommand(&command{
+		name: "hgetall", handler: hgetallCommand, arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns all fields and values in a hash.", since: "2.0.0", group: "hash", complexity: "O(N) where N is the size of the hash.",
+	})
+	registerCommand(&command{
+		name: "hincrby", handler: hincrbyCommand, arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+		since:   "2.0.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hincrbyfloat", handler: hincrbyfloatCommand, arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+		since:   "2.6.0", group: "hash", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "hrandfield", handler: hrandfieldCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns one or more random fields from a hash.", since: "6.2.0", group: "hash",
+		complexity: "O(N) where N is the number of fields returned",
+	})
+	registerCommand(&command{
+		name: "hscan", handler: hscanCommand, arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Iterates over fields and values of a hash.", since: "2.8.0", group: "hash",
+		complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+	})
+	registerCommand(&command{
+		name: "hexpire", handler: hexpireCommand, arity: -6, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Set expiry for hash field using relative time to expire (seconds)", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hpexpire", handler: hpexpireCommand, arity: -6, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Set expiry for hash field using relative time to expire (milliseconds)", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hexpireat", handler: hexpireAtCommand, arity: -6, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Set expiry for hash field using an absolute Unix timestamp (seconds)", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hpexpireat", handler: hpexpireAtCommand, arity: -6, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Set expiry for hash field using an absolute Unix timestamp (milliseconds)", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "httl", handler: httlCommand, arity: -5, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the TTL in seconds of a hash field.", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hpttl", handler: hpttlCommand, arity: -5, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the TTL in milliseconds of a hash field.", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hexpiretime", handler: hexpireTimeCommand, arity: -5, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time of a hash field as a Unix timestamp, in seconds.", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hpexpiretime", handler: hpexpireTimeCommand, arity: -5, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the expiration time of a hash field as a Unix timestamp, in msec.", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+	registerCommand(&command{
+		name: "hpersist", handler: hpersistCommand, arity: -5, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes the expiration time for each specified field", since: "7.4.0", group: "hash",
+		complexity: "O(N) where N is the number of specified fields",
+	})
+}
+
+// getHash returns the hash stored at key, or nil if there is none. It
+// returns errWrongType if key holds a value of another type.
+func (ks *keyspace) getHash(key string) (*hash, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	h, ok := value.(*hash)
+	if !ok {
+		return nil, errWrongType
+	}
+	return h, nil
+}
+
+// getOrCreateHash returns the hash stored at key, creating an empty one if
+// there is none
+func (ks *keyspace) getOrCreateHash(key string) (*hash, error) {
+	h, err := ks.getHash(key)
+	if err != nil || h != nil {
+		return h, err
+	}
+	h = newHash()
+	ks.set(key, h, false)
+	return h, nil
+}
+
+// hsetGeneric sets the field value pairs in args, which start at the
+// first field, and returns how many fields are new
+func hsetGeneric(c *client, args [][]byte) (int64, bool) {
+	if len(args)%2 != 0 {
+		c.w.WriteError(wrongArityError(strings.ToLower(string(args[0]))))
+		return 0, false
+	}
+	h, err := c.db.getOrCreateHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return 0, false
+	}
+	var added int64
+	for i := 2; i < len(args); i += 2 {
+		if h.set(string(args[i]), string(args[i+1]), false) {
+			added++
+		}
+	}
+	return added, true
+}
+
+// hsetCommand implements HSET key field value [field value ...]
+func hsetCommand(c *client, args [][]byte) {
+	if added, ok := hsetGeneric(c, args); ok {
+		c.w.WriteInteger(added)
+	}
+}
+
+// hmsetCommand implements HMSET key field value [field value ...]
+func hmsetCommand(c *client, args [][]byte) {
+	if _, ok := hsetGeneric(c, args); ok {
+		c.w.WriteSimpleString("OK")
+	}
+}
+
+// hsetnxCommand implements HSETNX key field value
+func hsetnxCommand(c *client, args [][]byte) {
+	h, err := c.db.getOrCreateHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	field := string(args[2])
+	if _, exists := h.get(field); exists {
+		c.w.WriteInteger(0)
+		return
+	}
+	h.set(field, string(args[3]), false)
+	c.w.WriteInteger(1)
+}
+
+// hgetCommand implements HGET key field
+func hgetCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		c.w.WriteNull()
+		return
+	}
+	value, ok := h.get(string(args[2]))
+	if !ok {
+		c.w.WriteNull()
+		return
+	}
+	c.w.WriteBulkString(value)
+}
+
+// hmgetCommand implements HMGET key field [field ...]
+func hmgetCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteArrayHeader(len(args) - 2)
+	for _, field := range args[2:] {
+		if h == nil {
+			c.w.WriteNull()
+			continue
+		}
+		if value, ok := h.get(string(field)); ok {
+			c.w.WriteBulkString(value)
+		} else {
+			c.w.WriteNull()
+		}
+	}
+}
+
+// hdelCommand implements HDEL key field [field ...]
+func hdelCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	h, err := c.db.getHash(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	var deleted int64
+	for _, field := range args[2:] {
+		if h.delete(string(field)) {
+			deleted++
+		}
+	}
+	if h.len() == 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteInteger(deleted)
+}
+
+// hexistsCommand implements HEXISTS key field
+func hexistsCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	if _, ok := h.get(string(args[2])); ok {
+		c.w.WriteInteger(1)
+		return
+	}
+	c.w.WriteInteger(0)
+}
+
+// hlenCommand implements HLEN key
+func hlenCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(h.len()))
+}
+
+// hstrlenCommand implements HSTRLEN key field
+func hstrlenCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	value, _ := h.get(string(args[2]))
+	c.w.WriteInteger(int64(len(value)))
+}
+
+// hgetallGeneric implements HKEYS, HVALS and HGETALL
+func hgetallGeneric(c *client, args [][]byte, withFields, withValues bool) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		h = newHash()
+	}
+	if withFields && withValues {
+		c.w.WriteMapHeader(h.len())
+	} else {
+		c.w.WriteArrayHeader(h.len())
+	}
+	h.forEach(func(field, value string) bool {
+		if withFields {
+			c.w.WriteBulkString(field)
+		}
+		if withValues {
+			c.w.WriteBulkString(value)
+		}
+		return true
+	})
+}
+
+// hkeysCommand implements HKEYS key
+func hkeysCommand(c *client, args [][]byte) {
+	hgetallGeneric(c, args, true, false)
+}
+
+// hvalsCommand implements HVALS key
+func hvalsCommand(c *client, args [][]byte) {
+	hgetallGeneric(c, args, false, true)
+}
+
+// hgetallCommand implements HGETALL key
+func hgetallCommand(c *client, args [][]byte) {
+	hgetallGeneric(c, args, true, true)
+}
+
+// hincrbyCommand implements HINCRBY key field increment
+func hincrbyCommand(c *client, args [][]byte) {
+	increment, ok := parseInt(args[3])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	h, err := c.db.getOrCreateHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	field := string(args[2])
+	var current int64
+	if value, exists := h.get(field); exists {
+		if current, ok = parseInt([]byte(value)); !ok {
+			c.w.WriteError(errHashNotInteger.Error())
+			return
+		}
+	}
+	result, ok := checkedAdd(current, increment)
+	if !ok {
+		c.w.WriteError(errOverflow.Error())
+		return
+	}
+	// Like Redis, an increment keeps the time to live of the field
+	h.set(field, strconv.FormatInt(result, 10), true)
+	c.w.WriteInteger(result)
+}
+
+// hincrbyfloatCommand implements HINCRBYFLOAT key field increment
+func hincrbyfloatCommand(c *client, args [][]byte) {
+	increment, ok := parseFloat(args[3])
+	if !ok {
+		c.w.WriteError(errNotFloat.Error())
+		return
+	}
+	key := string(args[1])
+	h, err := c.db.getHash(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	field := string(args[2])
+	var current float64
+	if h != nil {
+		if value, exists := h.get(field); exists {
+			if current, ok = parseFloat([]byte(value)); !ok {
+				c.w.WriteError(errHashNotFloat.Error())
+				return
+			}
+		}
+	}
+	result := current + increment
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		c.w.WriteError("ERR increment would produce NaN or Infinity")
+		return
+	}
+	// Only create the hash once the increment is known to succeed
+	if h == nil {
+		h, _ = c.db.getOrCreateHash(key)
+	}
+	formatted := strconv.FormatFloat(result, 'f', -1, 64)
+	h.set(field, formatted, true)
+	c.w.WriteBulkString(formatted)
+}
+
+// hrandfieldCommand implements HRANDFIELD key [count [WITHVALUES]]. A
+// negative count may return the same field several times.
+func hrandfieldCommand(c *client, args [][]byte) {
+	if len(args) > 4 || (len(args) == 4 && !strings.EqualFold(string(args[3]), "WITHVALUES")) {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	hasCount, withValues := len(args) >= 3, len(args) == 4
+	var count int64
+	if hasCount {
+		var err error
+		if count, err = parseRandomCount(args[2]); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !hasCount {
+		if h == nil {
+			c.w.WriteNull()
+			return
+		}
+		c.w.WriteBulkString(randomPairs(h.all(), 1)[0].field)
+		return
+	}
+	if h == nil || count == 0 {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+
+	pairs := h.all()
+	switch {
+	case count < 0:
+		// The pairs are written as they are picked, as there may be more
+		// of them than fit in memory
+		writeHashPairsHeader(c, int(-count), withValues)
+		for i := int64(0); i < -count; i++ {
+			writeHashPair(c, pairs[rand.IntN(len(pairs))], withValues)
+		}
+	case count >= int64(len(pairs)):
+		writeHashPairs(c, pairs, withValues)
+	default:
+		writeHashPairs(c, randomPairs(pairs, int(count)), withValues)
+	}
+}
+
+// randomPairs returns count distinct random pairs of pairs, which it
+// shuffles, and which must have at least count pairs
+func randomPairs(pairs []hashPair, count int) []hashPair {
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(pairs)-i)
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	}
+	return pairs[:count]
+}
+
+// writeHashPairs replies with the fields of pairs, and their values if
+// withValues is set. RESP3 clients get each field and value as a pair of
+// their own, like Redis does.
+func writeHashPairs(c *client, pairs []hashPair, withValues bool) {
+	writeHashPairsHeader(c, len(pairs), withValues)
+	for _, pair := range pairs {
+		writeHashPair(c, pair, withValues)
+	}
+}
+
+// writeHashPairsHeader starts the reply of writeHashPairs for n pairs
+func writeHashPairsHeader(c *client, n int, withValues bool) {
+	if withValues && c.w.Protocol() < 3 {
+		n *= 2
+	}
+	c.w.WriteArrayHeader(n)
+}
+
+// writeHashPair writes a pair of the reply of writeHashPairs
+func writeHashPair(c *client, pair hashPair, withValues bool) {
+	if withValues && c.w.Protocol() >= 3 {
+		c.w.WriteArrayHeader(2)
+	}
+	c.w.WriteBulkString(pair.field)
+	if withValues {
+		c.w.WriteBulkString(pair.value)
+	}
+}
+
+// scanOptions holds the options of the SCAN family of commands
+type scanOptions struct {
+	// match is the pattern the elements must match, or nil for all
+	match    []byte
+	count    int64
+	noValues bool
+}
+
+// parseScanOptions parses [MATCH pattern] [COUNT count] [NOVALUES]
+func parseScanOptions(args [][]byte) (scanOptions, error) {
+	opts := scanOptions{count: 10}
+	for i := 0; i < len(args); {
+		option := strings.ToUpper(string(args[i]))
+		switch {
+		case option == "COUNT" && i+1 < len(args):
+			count, ok := parseInt(args[i+1])
+			if !ok {
+				return scanOptions{}, errNotInteger
+			}
+			if count < 1 {
+				return scanOptions{}, errSyntax
+			}
+			opts.count = count
+			i += 2
+		case option == "MATCH" && i+1 < len(args):
+			opts.match = args[i+1]
+			// Everything matches *, so skip matching altogether
+			if string(opts.match) == "*" {
+				opts.match = nil
+			}
+			i += 2
+		case option == "NOVALUES":
+			opts.noValues = true
+			i++
+		default:
+			return scanOptions{}, errSyntax
+		}
+	}
+	return opts, nil
+}
+
+// parseScanCursor parses the cursor argument of the SCAN family
+func parseScanCursor(arg []byte) (uint64, error) {
+	cursor, err := strconv.ParseUint(string(arg), 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// hscanCommand implements
+// HSCAN key cursor [MATCH pattern] [COUNT count] [NOVALUES]
+func hscanCommand(c *client, args [][]byte) {
+	cursor, err := parseScanCursor(args[2])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if h == nil {
+		h = newHash()
+	}
+	opts, err := parseScanOptions(args[3:])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	var pairs []hashPair
+	collect := func(field, value string) {
+		if opts.match == nil || stringMatch(opts.match, []byte(field), false) {
+			pairs = append(pairs, hashPair{field, value})
+		}
+	}
+	if h.isListpack() {
+		// A listpack is small enough to return in one go
+		for _, pair := range h.pairs {
+			collect(pair.field, pair.value)
+		}
+		cursor = 0
+	} else {
+		// Like Redis, give up after visiting ten times as many buckets as
+		// the count asks for, in case most buckets are empty
+		maxIterations := opts.count * 10
+		for {
+			cursor = h.dict.scan(cursor, collect)
+			maxIterations--
+			if cursor == 0 || maxIterations == 0 || int64(len(pairs)) >= opts.count {
+				break
+			}
+		}
+	}
+
+	c.w.WriteArrayHeader(2)
+	c.w.WriteBulkString(strconv.FormatUint(cursor, 10))
+	if opts.noValues {
+		c.w.WriteArrayHeader(len(pairs))
+	} else {
+		c.w.WriteArrayHeader(2 * len(pairs))
+	}
+	for _, pair := range pairs {
+		c.w.WriteBulkString(pair.field)
+		if !opts.noValues {
+			c.w.WriteBulkString(pair.value)
+		}
+	}
+}
+
+// parseFields parses the FIELDS numfields field [field ...] arguments that
+// start at args[at] and returns the fields. invalid is the error for a
+// numfields that is not a positive integer.
+func parseFields(args [][]byte, at int, invalid string) ([][]byte, error) {
+	if !strings.EqualFold(string(args[at]), "FIELDS") {
+		return nil, errors.New("ERR Mandatory argument FIELDS is missing or not at the right position")
+	}
+	numFields, ok := parseInt(args[at+1])
+	if !ok || numFields < 1 {
+		return nil, errors.New(invalid)
+	}
+	fields := args[at+2:]
+	if numFields != int64(len(fields)) {
+		return nil, errNumFields
+	}
+	return fields, nil
+}
+
+// hexpireGeneric implements HEXPIRE, HPEXPIRE, HEXPIREAT and HPEXPIREAT.
+// unit is how many milliseconds the argument counts in, and relative is set
+// if it is relative to the current time. It replies for every field with
+// -2 if there is no such field, 0 if the condition is not met, 1 if the
+// time to live was set and 2 if the field was deleted because the time has
+// already passed.
+func hexpireGeneric(c *client, args [][]byte, unit int64, relative bool) {
+	key := string(args[1])
+	h, err := c.db.getHash(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	when, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	if when < 0 {
+		c.w.WriteError("ERR invalid expire time, must be >= 0")
+		return
+	}
+	invalid := "ERR invalid expire time in '" + strings.ToLower(string(args[0])) + "' command"
+	if when > hashFieldMaxExpireTime/unit {
+		c.w.WriteError(invalid)
+		return
+	}
+	when *= unit
+	var base int64
+	if relative {
+		base = c.db.now()
+	}
+	if when > hashFieldMaxExpireTime-base {
+		c.w.WriteError(invalid)
+		return
+	}
+	when += base
+
+	// The condition is optional and comes before FIELDS
+	var opts expireOptions
+	at := 3
+	switch strings.ToUpper(string(args[at])) {
+	case "NX":
+		opts.nx = true
+	case "XX":
+		opts.xx = true
+	case "GT":
+		opts.gt = true
+	case "LT":
+		opts.lt = true
+	}
+	if opts != (expireOptions{}) {
+		at++
+	}
+	fields, err := parseFields(args, at, "ERR Parameter `numFields` should be greater than 0")
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	c.w.WriteArrayHeader(len(fields))
+	if h == nil {
+		for range fields {
+			c.w.WriteInteger(-2)
+		}
+		return
+	}
+	current := c.db.now()
+	for _, arg := range fields {
+		field := string(arg)
+		if _, exists := h.get(field); !exists {
+			c.w.WriteInteger(-2)
+			continue
+		}
+		previous, hasExpire := h.expireTime(field)
+		if !opts.allows(previous, hasExpire, when) {
+			c.w.WriteInteger(0)
+			continue
+		}
+		if when <= current {
+			h.delete(field)
+			c.w.WriteInteger(2)
+			continue
+		}
+		c.db.setHashFieldExpire(key, h, field, when)
+		c.w.WriteInteger(1)
+	}
+	if h.len() == 0 {
+		c.db.remove(key)
+	}
+}
+
+// hexpireCommand implements
+// HEXPIRE key seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func hexpireCommand(c *client, args [][]byte) {
+	hexpireGeneric(c, args, 1000, true)
+}
+
+// hpexpireCommand implements
+// HPEXPIRE key milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func hpexpireCommand(c *client, args [][]byte) {
+	hexpireGeneric(c, args, 1, true)
+}
+
+// hexpireAtCommand implements
+// HEXPIREAT key unix-time-seconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func hexpireAtCommand(c *client, args [][]byte) {
+	hexpireGeneric(c, args, 1000, false)
+}
+
+// hpexpireAtCommand implements
+// HPEXPIREAT key unix-time-milliseconds [NX | XX | GT | LT] FIELDS numfields field [field ...]
+func hpexpireAtCommand(c *client, args [][]byte) {
+	hexpireGeneric(c, args, 1, false)
+}
+
+// httlGeneric implements HTTL, HPTTL, HEXPIRETIME and HPEXPIRETIME. It
+// replies for every field with -2 if there is no such field and -1 if it
+// has no expiry time. Like Redis, times in seconds are rounded up.
+func httlGeneric(c *client, args [][]byte, inMilliseconds, absolute bool) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	fields, err := parseFields(args, 2, "ERR Number of fields must be a positive integer")
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	c.w.WriteArrayHeader(len(fields))
+	for _, arg := range fields {
+		field := string(arg)
+		if h == nil {
+			c.w.WriteInteger(-2)
+			continue
+		}
+		if _, exists := h.get(field); !exists {
+			c.w.WriteInteger(-2)
+			continue
+		}
+		when, ok := h.expireTime(field)
+		if !ok {
+			c.w.WriteInteger(-1)
+			continue
+		}
+		if !absolute {
+			when -= c.db.now()
+		}
+		if !inMilliseconds {
+			when = (when + 999) / 1000
+		}
+		c.w.WriteInteger(when)
+	}
+}
+
+// httlCommand implements HTTL key FIELDS numfields field [field ...]
+func httlCommand(c *client, args [][]byte) {
+	httlGeneric(c, args, false, false)
+}
+
+// hpttlCommand implements HPTTL key FIELDS numfields field [field ...]
+func hpttlCommand(c *client, args [][]byte) {
+	httlGeneric(c, args, true, false)
+}
+
+// hexpireTimeCommand implements HEXPIRETIME key FIELDS numfields field [field ...]
+func hexpireTimeCommand(c *client, args [][]byte) {
+	httlGeneric(c, args, false, true)
+}
+
+// hpexpireTimeCommand implements HPEXPIRETIME key FIELDS numfields field [field ...]
+func hpexpireTimeCommand(c *client, args [][]byte) {
+	httlGeneric(c, args, true, true)
+}
+
+// hpersistCommand implements HPERSIST key FIELDS numfields field [field ...].
+// It replies for every field with -2 if there is no such field, -1 if it
+// has no expiry time and 1 if its expiry time was removed.
+func hpersistCommand(c *client, args [][]byte) {
+	h, err := c.db.getHash(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	fields, err := parseFields(args, 2, "ERR Number of fields must be a positive integer")
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	c.w.WriteArrayHeader(len(fields))
+	for _, arg := range fields {
+		field := string(arg)
+		if h == nil {
+			c.w.WriteInteger(-2)
+			continue
+		}
+		if _, exists := h.get(field); !exists {
+			c.w.WriteInteger(-2)
+			continue
+		}
+		if h.persist(field) {
+			c.w.WriteInteger(1)
+		} else {
+			c.w.WriteInteger(-1)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+func Test_HashCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should set fields and count the new ones",
+			input: "HSET h a 1 b 2\r\nHSET h a 3 c 4\r\nHLEN h\r\nHGET h a\r\nHGET h x\r\nHGET missing a\r\n",
+			want:  ":2\r\n:1\r\n:3\r\n$1\r\n3\r\n$-1\r\n$-1\r\n",
+		},
+		{
+			name:  "It should reject a field without a value",
+			input: "HSET h a 1 b\r\nHMSET h a\r\nEXISTS h\r\n",
+			want:  "-ERR wrong number of arguments for 'hset' command\r\n-ERR wrong number of arguments for 'hmset' command\r\n:0\r\n",
+		},
+		{
+			name:  "It should set fields with HMSET and HSETNX",
+			input: "HMSET h a 1 b 2\r\nHSETNX h a 9\r\nHSETNX h c 3\r\nHMGET h a b c d\r\nHMGET missing a\r\n",
+			want:  "+OK\r\n:0\r\n:1\r\n*4\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n$-1\r\n*1\r\n$-1\r\n",
+		},
+		{
+			name:  "It should delete fields and the emptied hash",
+			input: "HSET h a 1 b 2\r\nHDEL h a x\r\nHEXISTS h a\r\nHEXISTS h b\r\nHDEL h b\r\nEXISTS h\r\nHDEL h b\r\n",
+			want:  ":2\r\n:1\r\n:0\r\n:1\r\n:1\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should list fields and values in insertion order",
+			input: "HSET h b 1 a 22\r\nHKEYS h\r\nHVALS h\r\nHGETALL h\r\nHSTRLEN h a\r\nHSTRLEN h x\r\n",
+			want: ":2\r\n*2\r\n$1\r\nb\r\n$1\r\na\r\n*2\r\n$1\r\n1\r\n$2\r\n22\r\n" +
+				"*4\r\n$1\r\nb\r\n$1\r\n1\r\n$1\r\na\r\n$2\r\n22\r\n:2\r\n:0\r\n",
+		},
+		{
+			name:  "It should describe a missing key as an empty hash",
+			input: "HLEN h\r\nHKEYS h\r\nHGETALL h\r\nHEXISTS h a\r\nHSTRLEN h a\r\nHRANDFIELD h\r\nHRANDFIELD h 2\r\nHSCAN h 0\r\n",
+			want:  ":0\r\n*0\r\n*0\r\n:0\r\n:0\r\n$-1\r\n*0\r\n*2\r\n$1\r\n0\r\n*0\r\n",
+		},
+		{
+			name:  "It should increment integer fields",
+			input: "HINCRBY h a 5\r\nHINCRBY h a -7\r\nHGET h a\r\nHSET h s x\r\nHINCRBY h s 1\r\nHINCRBY h a x\r\nHSET h m 9223372036854775807\r\nHINCRBY h m 1\r\n",
+			want: ":5\r\n:-2\r\n$2\r\n-2\r\n:1\r\n-ERR hash value is not an integer\r\n-ERR value is not an integer or out of range\r\n" +
+				":1\r\n-ERR increment or decrement would overflow\r\n",
+		},
+		{
+			name: "It should increment float fields",
+			input: "HINCRBYFLOAT h a 10.5\r\nHINCRBYFLOAT h a 0.1\r\nHSET h s x\r\nHINCRBYFLOAT h s 1\r\nHINCRBYFLOAT h a x\r\nHINCRBYFLOAT h a 2_0\r\n" +
+				"HINCRBYFLOAT n a inf\r\nEXISTS n\r\n",
+			want: "$4\r\n10.5\r\n$4\r\n10.6\r\n:1\r\n-ERR hash value is not a float\r\n-ERR value is not a valid float\r\n" +
+				"-ERR value is not a valid float\r\n" +
+				"-ERR increment would produce NaN or Infinity\r\n:0\r\n",
+		},
+		{
+			name:  "It should return random fields",
+			input: "HSET h a 1\r\nHRANDFIELD h\r\nHRANDFIELD h -3\r\nHRANDFIELD h 3 WITHVALUES\r\nHRANDFIELD h 0\r\n",
+			want:  ":1\r\n$1\r\na\r\n*3\r\n$1\r\na\r\n$1\r\na\r\n$1\r\na\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n*0\r\n",
+		},
+		{
+			name: "It should reject invalid HRANDFIELD arguments",
+			input: "HRANDFIELD h x\r\nHRANDFIELD h 1 FOO\r\nHRANDFIELD h 1 WITHVALUES x\r\nHRANDFIELD h -9223372036854775808\r\n" +
+				"HSET h a 1\r\nHRANDFIELD h -9223372036854775807\r\nHRANDFIELD h -9223372036854775807 WITHVALUES\r\n",
+			want: "-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"-ERR value is out of range, must be between -9223372036854775807 and 9223372036854775807\r\n" +
+				":1\r\n-ERR value is out of range\r\n-ERR value is out of range\r\n",
+		},
+		{
+			name:  "It should scan a small hash in one go",
+			input: "HSET h a 1 b 2 ab 3\r\nHSCAN h 0\r\nHSCAN h 0 MATCH a* NOVALUES\r\n",
+			want: ":3\r\n*2\r\n$1\r\n0\r\n*6\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n$2\r\nab\r\n$1\r\n3\r\n" +
+				"*2\r\n$1\r\n0\r\n*2\r\n$1\r\na\r\n$2\r\nab\r\n",
+		},
+		{
+			name:  "It should reject invalid HSCAN arguments",
+			input: "HSCAN h x\r\nHSCAN h -1\r\nHSCAN h 0 COUNT x\r\nHSCAN h 0 COUNT 0\r\nHSCAN h 0 MATCH\r\nHSCAN h 0 FOO\r\n",
+			want: "-ERR invalid cursor\r\n-ERR invalid cursor\r\n-ERR value is not an integer or out of range\r\n" +
+				"-ERR syntax error\r\n-ERR syntax error\r\n-ERR syntax error\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_HashCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET str v\r\nHSET h a 1\r\n")
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"HSET str a 1", "HMSET str a 1", "HSETNX str a 1", "HGET str a", "HMGET str a", "HDEL str a",
+		"HEXISTS str a", "HLEN str", "HSTRLEN str a", "HKEYS str", "HVALS str", "HGETALL str",
+		"HINCRBY str a 1", "HINCRBYFLOAT str a 1", "HRANDFIELD str", "HSCAN str 0", "HEXPIRE str 10 FIELDS 1 a",
+		"HTTL str FIELDS 1 a", "HPERSIST str FIELDS 1 a", "GET h", "SADD h a",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+}
+
+func Test_HashFieldExpireCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should set, report and remove the time to live of fields",
+			input: "HSET h a 1 b 2\r\nHEXPIRE h 100 FIELDS 2 a c\r\nHTTL h FIELDS 3 a b c\r\nHPERSIST h FIELDS 3 a b c\r\nHTTL h FIELDS 1 a\r\n",
+			want:  ":2\r\n*2\r\n:1\r\n:-2\r\n*3\r\n:100\r\n:-1\r\n:-2\r\n*3\r\n:1\r\n:-1\r\n:-2\r\n*1\r\n:-1\r\n",
+		},
+		{
+			name:  "It should report the fields of a missing key as missing",
+			input: "HEXPIRE h 100 FIELDS 2 a b\r\nHTTL h FIELDS 1 a\r\nHPERSIST h FIELDS 1 a\r\nEXISTS h\r\n",
+			want:  "*2\r\n:-2\r\n:-2\r\n*1\r\n:-2\r\n*1\r\n:-2\r\n:0\r\n",
+		},
+		{
+			name: "It should report absolute expiry times rounded up to the second",
+			input: "HSET h a 1\r\nHPEXPIREAT h 33177117420500 FIELDS 1 a\r\nHEXPIRETIME h FIELDS 1 a\r\nHPEXPIRETIME h FIELDS 1 a\r\n" +
+				"HEXPIREAT h 33177117421 FIELDS 1 a\r\nHPEXPIRETIME h FIELDS 1 a\r\n",
+			want: ":1\r\n*1\r\n:1\r\n*1\r\n:33177117421\r\n*1\r\n:33177117420500\r\n*1\r\n:1\r\n*1\r\n:33177117421000\r\n",
+		},
+		{
+			name:  "It should delete fields given a time in the past, and the emptied hash",
+			input: "HSET h a 1 b 2 c 3\r\nHEXPIRE h 0 FIELDS 1 a\r\nHPEXPIREAT h 1 FIELDS 1 b\r\nHGETALL h\r\nHEXPIREAT h 1 FIELDS 1 c\r\nEXISTS h\r\n",
+			want:  ":3\r\n*1\r\n:2\r\n*1\r\n:2\r\n*2\r\n$1\r\nc\r\n$1\r\n3\r\n*1\r\n:2\r\n:0\r\n",
+		},
+		{
+			name: "It should only set the time to live when the condition holds",
+			input: "HSET h a 1\r\nHEXPIRE h 100 XX FIELDS 1 a\r\nHEXPIRE h 100 GT FIELDS 1 a\r\nHEXPIRE h 100 NX FIELDS 1 a\r\n" +
+				"HEXPIRE h 200 NX FIELDS 1 a\r\nHEXPIRE h 50 GT FIELDS 1 a\r\nHEXPIRE h 200 GT FIELDS 1 a\r\nHEXPIRE h 300 LT FIELDS 1 a\r\n" +
+				"HEXPIRE h 50 lt FIELDS 1 a\r\nHTTL h FIELDS 1 a\r\nHPERSIST h FIELDS 1 a\r\nHEXPIRE h 100 LT FIELDS 1 a\r\n",
+			want: ":1\r\n*1\r\n:0\r\n*1\r\n:0\r\n*1\r\n:1\r\n*1\r\n:0\r\n*1\r\n:0\r\n*1\r\n:1\r\n*1\r\n:0\r\n" +
+				"*1\r\n:1\r\n*1\r\n:50\r\n*1\r\n:1\r\n*1\r\n:1\r\n",
+		},
+		{
+			name:  "It should clear the time to live of a field that is set again but not one that is incremented",
+			input: "HSET h a 1 b 2\r\nHEXPIRE h 100 FIELDS 2 a b\r\nHSET h a 3\r\nHINCRBY h b 1\r\nHTTL h FIELDS 2 a b\r\n",
+			want:  ":2\r\n*2\r\n:1\r\n:1\r\n:0\r\n:3\r\n*2\r\n:-1\r\n:100\r\n",
+		},
+		{
+			name: "It should reject invalid arguments",
+			input: "HEXPIRE h x FIELDS 1 a\r\nHEXPIRE h -1 FIELDS 1 a\r\nHEXPIRE h 281474976711 FIELDS 1 a\r\nHPEXPIREAT h 281474976710656 FIELDS 1 a\r\n" +
+				"HEXPIRE h 10 FOO 1 a\r\nHEXPIRE h 10 NX XX FIELDS 1 a\r\nHEXPIRE h 10 FIELDS 0 a\r\nHEXPIRE h 10 FIELDS 2 a\r\n" +
+				"HTTL h FIELDS x a\r\nHPERSIST h FIELD 1 a\r\n",
+			want: "-ERR value is not an integer or out of range\r\n-ERR invalid expire time, must be >= 0\r\n" +
+				"-ERR invalid expire time in 'hexpire' command\r\n-ERR invalid expire time in 'hpexpireat' command\r\n" +
+				"-ERR Mandatory argument FIELDS is missing or not at the right position\r\n" +
+				"-ERR Mandatory argument FIELDS is missing or not at the right position\r\n" +
+				"-ERR Parameter `numFields` should be greater than 0\r\n-ERR The `numfields` parameter must match the number of arguments\r\n" +
+				"-ERR Number of fields must be a positive integer\r\n-ERR Mandatory argument FIELDS is missing or not at the right position\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_HashFieldsExpireLazily(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("HSET h a 1 b 2\r\nHPEXPIRE h 100 FIELDS 1 a\r\nHPEXPIRE h 200 FIELDS 1 b\r\n")
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    string
+	}{
+		{
+			name:    "It should still find the fields before they expire",
+			advance: 99 * time.Millisecond,
+			want:    "*2\r\n:1\r\n:101\r\n*1\r\n:1\r\n:2\r\n:1\r\n",
+		},
+		{
+			name:    "It should expire a field once its time to live runs out",
+			advance: time.Millisecond,
+			want:    "*2\r\n:-2\r\n:100\r\n*1\r\n:1\r\n:1\r\n:1\r\n",
+		},
+		{
+			name:    "It should delete the hash once its last field expires",
+			advance: 100 * time.Millisecond,
+			want:    "*2\r\n:-2\r\n:-2\r\n*1\r\n:-2\r\n:0\r\n:0\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			if got := srv.HandleRequest("HPTTL h FIELDS 2 a b\r\nHTTL h FIELDS 1 b\r\nHLEN h\r\nEXISTS h\r\n"); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_DeleteHashWithExpiredFields(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("HSET h a 1\r\nHSET g a 1 b 2\r\nHPEXPIRE h 100 FIELDS 1 a\r\nHPEXPIRE g 100 FIELDS 2 a b\r\n")
+	clock.Advance(100 * time.Millisecond)
+
+	if got, want := srv.HandleRequest("DEL h\r\nDEL g h\r\n"), ":0\r\n:0\r\n"; got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+}
+
+func Test_HashCommandsReplyWithMapsInRESP3(t *testing.T) {
+	got := New(Config{}).HandleRequest("HELLO 3\r\nHSET h a 1\r\nHGETALL h\r\nHRANDFIELD h 1 WITHVALUES\r\n")
+	if want := ":1\r\n%1\r\n$1\r\na\r\n$1\r\n1\r\n*1\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("HandleRequest() = %q, want it to end with %q", got, want)
+	}
+}
+
+func Test_HashCommandsScanLargeHashes(t *testing.T) {
+	srv := New(Config{})
+	var hset strings.Builder
+	hset.WriteString("HSET h")
+	for i := 0; i < 1000; i++ {
+		hset.WriteString(" f" + strconv.Itoa(i) + " " + strconv.Itoa(i))
+	}
+	srv.HandleRequest(hset.String() + "\r\n")
+
+	seen := map[string]bool{}
+	cursor, calls := "0", 0
+	for {
+		reply, err := resp.Deserialize(srv.HandleRequest("HSCAN h " + cursor + " COUNT 50\r\n"))
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		elems := reply.Elems()
+		cursor = elems[0].Str()
+		pairs := elems[1].Elems()
+		for i := 0; i < len(pairs); i += 2 {
+			if field := pairs[i].Str(); "f"+pairs[i+1].Str() != field {
+				t.Errorf("HSCAN returned field %q with value %q", field, pairs[i+1].Str())
+			}
+			seen[pairs[i].Str()] = true
+		}
+		calls++
+		if cursor == "0" {
+			break
+		}
+	}
+	if calls < 2 {
+		t.Errorf("HSCAN returned a hash of 1000 fields in %d calls, want it in pieces", calls)
+	}
+	if len(seen) != 1000 {
+		t.Errorf("HSCAN returned %d distinct fields, want 1000", len(seen))
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_hashEncoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		pairs        []hashPair
+		wantListpack bool
+	}{
+		{
+			name:         "It should keep small hashes in a listpack",
+			pairs:        []hashPair{{"a", "1"}, {"b", "2"}, {"a", "3"}},
+			wantListpack: true,
+		},
+		{
+			name:         "It should convert to a dict for a long value",
+			pairs:        []hashPair{{"a", "1"}, {"b", strings.Repeat("v", hashMaxListpackValue+1)}},
+			wantListpack: false,
+		},
+		{
+			name:         "It should convert to a dict for a long field",
+			pairs:        []hashPair{{strings.Repeat("f", hashMaxListpackValue+1), "1"}},
+			wantListpack: false,
+		},
+		{
+			name:         "It should convert to a dict when an existing field gets a long value",
+			pairs:        []hashPair{{"a", "1"}, {"a", strings.Repeat("v", hashMaxListpackValue+1)}},
+			wantListpack: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHash()
+			want := map[string]string{}
+			for _, pair := range tt.pairs {
+				h.set(pair.field, pair.value, false)
+				want[pair.field] = pair.value
+			}
+			if h.isListpack() != tt.wantListpack {
+				t.Errorf("isListpack() = %v, want %v", h.isListpack(), tt.wantListpack)
+			}
+			if h.len() != len(want) {
+				t.Errorf("len() = %d, want %d", h.len(), len(want))
+			}
+			for field, value := range want {
+				if got, ok := h.get(field); !ok || got != value {
+					t.Errorf("get(%q) = %q, %v, want %q", field, got, ok, value)
+				}
+			}
+		})
+	}
+}
+
+func Test_hashConvertsLargeListpacks(t *testing.T) {
+	h := newHash()
+	for i := 0; i < hashMaxListpackEntries; i++ {
+		h.set(strconv.Itoa(i), strconv.Itoa(i), false)
+	}
+	if !h.isListpack() {
+		t.Fatalf("a hash of %d fields is not a listpack", hashMaxListpackEntries)
+	}
+	h.set(strconv.Itoa(hashMaxListpackEntries), "", false)
+	if h.isListpack() {
+		t.Fatalf("a hash of %d fields is still a listpack", hashMaxListpackEntries+1)
+	}
+	pairs := h.all()
+	sort.Slice(pairs, func(i, j int) bool {
+		a, _ := strconv.Atoi(pairs[i].field)
+		b, _ := strconv.Atoi(pairs[j].field)
+		return a < b
+	})
+	for i, pair := range pairs[:hashMaxListpackEntries] {
+		if pair.field != strconv.Itoa(i) || pair.value != strconv.Itoa(i) {
+			t.Fatalf("all()[%d] = %v after the conversion", i, pair)
+		}
+	}
+}
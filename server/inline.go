@@ -0,0 +1,147 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+var errUnbalancedQuotes = &resp.ProtocolError{Reason: "unbalanced quotes in request"}
+
+// readCommand reads the next command off r. Commands normally arrive as
+// RESP arrays, but like Redis anything that does not start with '*' is
+// treated as an inline command: a single line of space separated
+// arguments, which is what telnet or netcat users type.
+func readCommand(r *resp.Reader) (resp.Value, error) {
+	for {
+		first, err := r.PeekByte()
+		if err != nil {
+			return resp.Value{}, err
+		}
+		if first == '*' {
+			command, _, err := r.ReadValue()
+			return command, err
+		}
+
+		line, _, err := r.ReadLine()
+		if err != nil {
+			return resp.Value{}, err
+		}
+		args, err := splitInlineArgs(string(line))
+		if err != nil {
+			return resp.Value{}, err
+		}
+		// Blank lines are skipped, just like Redis does
+		if len(args) == 0 {
+			continue
+		}
+
+		command := make([]resp.Value, len(args))
+		for i, arg := range args {
+			command[i] = resp.NewBulkString(arg)
+		}
+		return resp.NewArray(command...), nil
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitToInt(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// splitInlineArgs splits an inline command into its arguments following
+// the rules of Redis' sdssplitargs. Arguments are separated by spaces and
+// may be quoted: double quotes understand the escapes \n, \r, \t, \b, \a,
+// \" and \xHH, single quotes only understand \'. A closing quote must be
+// followed by a space or the end of the line.
+func splitInlineArgs(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var current strings.Builder
+		inDoubleQuotes, inSingleQuotes := false, false
+		for done := false; !done; i++ {
+			if inDoubleQuotes {
+				switch {
+				case i == len(line):
+					return nil, errUnbalancedQuotes
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' &&
+					isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					current.WriteByte(hexDigitToInt(line[i+2])<<4 | hexDigitToInt(line[i+3]))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						current.WriteByte('\n')
+					case 'r':
+						current.WriteByte('\r')
+					case 't':
+						current.WriteByte('\t')
+					case 'b':
+						current.WriteByte('\b')
+					case 'a':
+						current.WriteByte('\a')
+					default:
+						current.WriteByte(line[i])
+					}
+				case line[i] == '"':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuotes
+					}
+					done = true
+				default:
+					current.WriteByte(line[i])
+				}
+			} else if inSingleQuotes {
+				switch {
+				case i == len(line):
+					return nil, errUnbalancedQuotes
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					current.WriteByte('\'')
+					i++
+				case line[i] == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, errUnbalancedQuotes
+					}
+					done = true
+				default:
+					current.WriteByte(line[i])
+				}
+			} else {
+				switch {
+				case i == len(line) || isSpace(line[i]):
+					done = true
+				case line[i] == '"':
+					inDoubleQuotes = true
+				case line[i] == '\'':
+					inSingleQuotes = true
+				default:
+					current.WriteByte(line[i])
+				}
+			}
+		}
+		args = append(args, current.String())
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_splitInlineArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "It should split on spaces",
+			line: "SET foo bar",
+			want: []string{"SET", "foo", "bar"},
+		},
+		{
+			name: "It should ignore repeated and surrounding whitespace",
+			line: "  GET \t foo  ",
+			want: []string{"GET", "foo"},
+		},
+		{
+			name: "It should return no arguments for a blank line",
+			line: "   ",
+			want: nil,
+		},
+		{
+			name: "It should keep spaces inside double quotes",
+			line: `SET greeting "hello world"`,
+			want: []string{"SET", "greeting", "hello world"},
+		},
+		{
+			name: "It should unescape double quoted strings",
+			line: `ECHO "a\nb\t\"c\"\x41\x00"`,
+			want: []string{"ECHO", "a\nb\t\"c\"A\x00"},
+		},
+		{
+			name: "It should only unescape \\' in single quotes",
+			line: `ECHO 'it\'s \n'`,
+			want: []string{"ECHO", `it's \n`},
+		},
+		{
+			name: "It should accept empty quoted arguments",
+			line: `SET key ""`,
+			want: []string{"SET", "key", ""},
+		},
+		{
+			name: "It should join quoted parts with the unquoted prefix",
+			line: `SET ke"y 1"`,
+			want: []string{"SET", "key 1"},
+		},
+		{
+			name:    "It should reject an unterminated double quote",
+			line:    `SET key "value`,
+			wantErr: true,
+		},
+		{
+			name:    "It should reject an unterminated single quote",
+			line:    `SET key 'value`,
+			wantErr: true,
+		},
+		{
+			name:    "It should reject a closing quote followed by text",
+			line:    `SET key "value"x`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitInlineArgs(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitInlineArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitInlineArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// keyspace holds the keys of the database and their expiry times. The
+// dispatcher holds its lock for the whole of every command, so a command
+// sees and makes all of its changes atomically, like it would on the
+// single thread of Redis. Its methods expect the lock to be held.
+type keyspace struct {
+	sync.Mutex
+	// data maps each key to its value. Strings are stored as []byte, or
+	// as int64 when they hold an integer so counters do not go through
+	// text on every increment.
+	data map[string]any
+	// expires maps the keys that have a time to live to the unix time in
+	// milliseconds at which they expire
+	expires map[string]int64
+	// hexpires holds the keys of hashes that may have fields with a time
+	// to live, for the active expire cycle to look at
+	hexpires map[string]struct{}
+	// clock decides when keys expire
+	clock Clock
+
+	// blocked maps each key to the clients blocked on it, in the order
+	// they blocked
+	blocked map[string][]*client
+	// readyKeys holds the keys with blocked clients that may be able to
+	// serve them, which the dispatcher looks at once the current command
+	// is done
+	readyKeys []string
+}
+
+func newKeyspace(clock Clock) *keyspace {
+	return &keyspace{
+		data:     map[string]any{},
+		expires:  map[string]int64{},
+		hexpires: map[string]struct{}{},
+		clock:    clock,
+		blocked:  map[string][]*client{},
+	}
+}
+
+// now returns the current unix time in milliseconds
+func (ks *keyspace) now() int64 {
+	return ks.clock.Now().UnixMilli()
+}
+
+// expireIfNeeded deletes key if its time to live has run out, and reports
+// whether it did. Expired keys are removed lazily, when they are accessed.
+func (ks *keyspace) expireIfNeeded(key string) bool {
+	when, ok := ks.expires[key]
+	if !ok || when > ks.now() {
+		return false
+	}
+	delete(ks.data, key)
+	delete(ks.expires, key)
+	return true
+}
+
+// lookup returns the value of key
+func (ks *keyspace) lookup(key string) (any, bool) {
+	ks.expireIfNeeded(key)
+	value, ok := ks.data[key]
+	if h, isHash := value.(*hash); isHash && ks.expireHashFields(key, h) {
+		return nil, false
+	}
+	return value, ok
+}
+
+// expireHashFields deletes the expired fields of h, the hash stored at
+// key, and deletes key if that leaves h empty. It reports whether it
+// deleted key. Like expired keys, expired fields are removed lazily, when
+// their hash is accessed.
+func (ks *keyspace) expireHashFields(key string, h *hash) bool {
+	if h.expireFields(ks.now()) == 0 || h.len() > 0 {
+		return false
+	}
+	delete(ks.data, key)
+	delete(ks.expires, key)
+	return true
+}
+
+// setHashFieldExpire sets field of h, the hash stored at key, to expire at
+// the unix time when, in milliseconds
+func (ks *keyspace) setHashFieldExpire(key string, h *hash, field string, when int64) {
+	h.setExpire(field, when)
+	ks.hexpires[key] = struct{}{}
+}
+
+// set stores value under key, replacing any value it had. Unless keepTTL
+// is set, the key loses its time to live.
+func (ks *keyspace) set(key string, value any, keepTTL bool) {
+	if keepTTL {
+		ks.expireIfNeeded(key)
+	} else {
+		delete(ks.expires, key)
+	}
+	ks.data[key] = value
+}
+
+// remove deletes key and reports whether it existed
+func (ks *keyspace) remove(key string) bool {
+	// A key that expired, or a hash whose fields all expired, is already
+	// gone
+	if _, ok := ks.lookup(key); !ok {
+		return false
+	}
+	delete(ks.data, key)
+	delete(ks.expires, key)
+	return true
+}
+
+// setExpire sets key to expire at the unix time when, in milliseconds. A
+// time that has already passed deletes the key straight away. key must
+// exist.
+func (ks *keyspace) setExpire(key string, when int64) {
+	if when <= ks.now() {
+		delete(ks.data, key)
+		delete(ks.expires, key)
+		return
+	}
+	ks.expires[key] = when
+}
+
+// persist removes the time to live of key, and reports whether it had one
+func (ks *keyspace) persist(key string) bool {
+	if _, ok := ks.expires[key]; !ok {
+		return false
+	}
+	delete(ks.expires, key)
+	return true
+}
+
+const (
+	// activeExpireSamples is how many keys with a time to live each round
+	// of the active expire cycle looks at
+	activeExpireSamples = 20
+	// activeExpireAcceptableStale is the percentage of expired keys in a
+	// sample below which the cycle stops, as most expired keys are gone
+	activeExpireAcceptableStale = 10
+)
+
+// activeExpireCycle deletes expired keys that nobody accesses, which lazy
+// expiry would never reclaim. Like Redis it samples keys with a time to
+// live and keeps going while many of the sampled keys turn out to be
+// expired, until deadline. It returns how many keys it deleted.
+func (ks *keyspace) activeExpireCycle(deadline time.Time) int {
+	deleted := 0
+	for {
+		sampled, expired := 0, 0
+		current := ks.now()
+		// Map iteration starts at a random key, which makes for the
+		// random sample
+		for key, when := range ks.expires {
+			if sampled == activeExpireSamples {
+				break
+			}
+			sampled++
+			if when <= current {
+				delete(ks.data, key)
+				delete(ks.expires, key)
+				expired++
+			}
+		}
+		deleted += expired
+		if sampled == 0 || expired*100/sampled <= activeExpireAcceptableStale || !ks.clock.Now().Before(deadline) {
+			return deleted
+		}
+	}
+}
+
+// activeExpireHashFields deletes the expired fields of hashes that nobody
+// accesses, the way activeExpireCycle does for keys: it samples the hashes
+// with fields that have a time to live and keeps going while many of them
+// turn out to have expired fields, until deadline. It returns how many
+// fields it deleted.
+func (ks *keyspace) activeExpireHashFields(deadline time.Time) int {
+	deleted := 0
+	for {
+		sampled, expired := 0, 0
+		current := ks.now()
+		for key := range ks.hexpires {
+			if sampled == activeExpireSamples {
+				break
+			}
+			sampled++
+			h, ok := ks.data[key].(*hash)
+			// The key may have been deleted or given another value since
+			if !ok || len(h.expires) == 0 {
+				delete(ks.hexpires, key)
+				continue
+			}
+			if n := h.expireFields(current); n > 0 {
+				deleted += n
+				expired++
+				if h.len() == 0 {
+					delete(ks.data, key)
+					delete(ks.expires, key)
+				}
+			}
+			if h.expires == nil {
+				delete(ks.hexpires, key)
+			}
+		}
+		if sampled == 0 || expired*100/sampled <= activeExpireAcceptableStale || !ks.clock.Now().Before(deadline) {
+			return deleted
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_keyspaceExpiresLazily(t *testing.T) {
+	ks := newKeyspace(newFakeClock())
+	ks.set("expired", []byte("v"), false)
+	ks.set("live", []byte("v"), false)
+	ks.expires["expired"] = ks.now() - 1
+	ks.expires["live"] = ks.now() + 60000
+
+	if _, ok := ks.lookup("expired"); ok {
+		t.Errorf("lookup() found an expired key")
+	}
+	if _, ok := ks.data["expired"]; ok {
+		t.Errorf("lookup() left an expired key in the keyspace")
+	}
+	if _, ok := ks.expires["expired"]; ok {
+		t.Errorf("lookup() left the expiry of an expired key")
+	}
+	if _, ok := ks.lookup("live"); !ok {
+		t.Errorf("lookup() did not find a key that has not expired")
+	}
+}
+
+func Test_keyspaceRemove(t *testing.T) {
+	ks := newKeyspace(newFakeClock())
+	ks.set("k", []byte("v"), false)
+	ks.expires["k"] = ks.now() + 60000
+
+	if !ks.remove("k") {
+		t.Errorf("remove() = false for an existing key")
+	}
+	if _, ok := ks.expires["k"]; ok {
+		t.Errorf("remove() left the expiry of the key")
+	}
+	if ks.remove("k") {
+		t.Errorf("remove() = true for a missing key")
+	}
+
+	ks.set("expired", []byte("v"), false)
+	ks.expires["expired"] = ks.now() - 1
+	if ks.remove("expired") {
+		t.Errorf("remove() = true for an expired key")
+	}
+}
+
+func Test_keyspaceActiveExpireCycle(t *testing.T) {
+	ks := newKeyspace(newFakeClock())
+	for i := 0; i < 200; i++ {
+		key := "expired:" + strconv.Itoa(i)
+		ks.set(key, []byte("v"), false)
+		ks.expires[key] = ks.now() - 1
+	}
+	for i := 0; i < 50; i++ {
+		key := "live:" + strconv.Itoa(i)
+		ks.set(key, []byte("v"), false)
+		ks.expires[key] = ks.now() + 60000
+	}
+	ks.set("persistent", []byte("v"), false)
+
+	// Which keys get sampled is random, so only some of the expired keys
+	// are certain to go
+	if got := ks.activeExpireCycle(ks.clock.Now().Add(time.Minute)); got < activeExpireSamples {
+		t.Errorf("activeExpireCycle() deleted %d keys, want at least %d", got, activeExpireSamples)
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := ks.data["live:"+strconv.Itoa(i)]; !ok {
+			t.Fatalf("activeExpireCycle() deleted a key that has not expired")
+		}
+	}
+	if _, ok := ks.data["persistent"]; !ok {
+		t.Errorf("activeExpireCycle() deleted a key without a time to live")
+	}
+}
+
+func Test_keyspaceActiveExpireCycleDeletesEverythingExpired(t *testing.T) {
+	ks := newKeyspace(newFakeClock())
+	for i := 0; i < 500; i++ {
+		key := strconv.Itoa(i)
+		ks.set(key, []byte("v"), false)
+		ks.expires[key] = ks.now() - 1
+	}
+	if got := ks.activeExpireCycle(ks.clock.Now().Add(time.Minute)); got != 500 {
+		t.Errorf("activeExpireCycle() deleted %d keys, want 500", got)
+	}
+	if len(ks.data) != 0 || len(ks.expires) != 0 {
+		t.Errorf("activeExpireCycle() left %d keys and %d expiry times", len(ks.data), len(ks.expires))
+	}
+}
+
+func Test_keyspaceActiveExpireHashFields(t *testing.T) {
+	ks := newKeyspace(newFakeClock())
+	now := ks.now()
+	for i := 0; i < 300; i++ {
+		key := "expired:" + strconv.Itoa(i)
+		h := newHash()
+		h.set("a", "v", false)
+		ks.set(key, h, false)
+		ks.setHashFieldExpire(key, h, "a", now-1)
+	}
+	partial := newHash()
+	partial.set("a", "v", false)
+	partial.set("b", "v", false)
+	ks.set("partial", partial, false)
+	ks.setHashFieldExpire("partial", partial, "a", now-1)
+	ks.setHashFieldExpire("partial", partial, "b", now+60000)
+	// A hash with expiring fields that has been replaced since
+	ks.hexpires["replaced"] = struct{}{}
+	ks.set("replaced", []byte("v"), false)
+
+	if got := ks.activeExpireHashFields(ks.clock.Now().Add(time.Minute)); got != 301 {
+		t.Errorf("activeExpireHashFields() deleted %d fields, want 301", got)
+	}
+	if len(ks.data) != 2 {
+		t.Errorf("activeExpireHashFields() left %d keys, want 2", len(ks.data))
+	}
+	if _, ok := partial.get("b"); !ok || partial.len() != 1 {
+		t.Errorf("activeExpireHashFields() left %v, want only the field that has not expired", partial.all())
+	}
+	if _, ok := ks.hexpires["partial"]; !ok || len(ks.hexpires) != 1 {
+		t.Errorf("activeExpireHashFields() left %d hashes to look at, want only the one with expiring fields", len(ks.hexpires))
+	}
+}
+
+func Test_keyspaceExpiresWhenTheClockReachesTheExpiryTime(t *testing.T) {
+	clock := newFakeClock()
+	ks := newKeyspace(clock)
+	ks.set("k", []byte("v"), false)
+	ks.setExpire("k", ks.now()+1000)
+
+	clock.Advance(999 * time.Millisecond)
+	if _, ok := ks.lookup("k"); !ok {
+		t.Fatalf("lookup() did not find the key 1ms before it expires")
+	}
+	clock.Advance(time.Millisecond)
+	if _, ok := ks.lookup("k"); ok {
+		t.Errorf("lookup() found the key once it expired")
+	}
+}
@@ -0,0 +1,853 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+var (
+	errNoSuchKey      = errors.New("ERR no such key")
+	errIndexRange     = errors.New("ERR index out of range")
+	errNotPositive    = errors.New("ERR value is out of range, must be positive")
+	errRankZero       = errors.New("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+	errRankRange      = errors.New("ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807")
+	errCountNegative  = errors.New("ERR COUNT can't be negative")
+	errMaxlenNegative = errors.New("ERR MAXLEN can't be negative")
+)
+
+func init() {
+	registerCommand(&command{
+		name: "lpush", handler: lpushCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.", since: "1.0.0",
+		group: "list", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	})
+	registerCommand(&command{
+		name: "rpush", handler: rpushCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.", since: "1.0.0",
+		group: "list", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	})
+	registerCommand(&command{
+		name: "lpushx", handler: lpushxCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Prepends one or more elements to a list only when the list exists.", since: "2.2.0",
+		group: "list", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	})
+	registerCommand(&command{
+		name: "rpushx", handler: rpushxCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Appends an element to a list only when the list exists.", since: "2.2.0",
+		group: "list", complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	})
+	registerCommand(&command{
+		name: "lpop", handler: lpopCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+		since:   "1.0.0", group: "list", complexity: "O(N) where N is the number of elements returned",
+	})
+	registerCommand(&command{
+		name: "rpop", handler: rpopCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns and removes the last elements of a list. Deletes the list if the last element was popped.",
+		since:   "1.0.0", group: "list", complexity: "O(N) where N is the number of elements returned",
+	})
+	registerCommand(&command{
+		name: "llen", handler: llenCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the length of a list.", since: "1.0.0", group: "list", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "lrange", handler: lrangeCommand, arity: 4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns a range of elements from a list.", since: "1.0.0", group: "list",
+		complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range.",
+	})
+	registerCommand(&command{
+		name: "lindex", handler: lindexCommand, arity: 3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns an element from a list by its index.", since: "1.0.0", group: "list",
+		complexity: "O(N) where N is the number of elements to traverse to get to the element at index. This makes asking for the first or the last element of the list O(1).",
+	})
+	registerCommand(&command{
+		name: "lset", handler: lsetCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the value of an element in a list by its index.", since: "1.0.0", group: "list",
+		complexity: "O(N) where N is the length of the list. Setting either the first or the last element of the list is O(1).",
+	})
+	registerCommand(&command{
+		name: "linsert", handler: linsertCommand, arity: 5, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Inserts an element before or after another element in a list.", since: "2.2.0", group: "list",
+		complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot. This means that inserting somewhere on the left end on the list (head) can be considered O(1) and inserting somewhere on the right end (tail) is O(N).",
+	})
+	registerCommand(&command{
+		name: "lrem", handler: lremCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes elements from a list. Deletes the list if the last element was removed.", since: "1.0.0",
+		group: "list", complexity: "O(N+M) where N is the length of the list and M is the number of elements removed.",
+	})
+	registerCommand(&command{
+		name: "ltrim", handler: ltrimCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.", since: "1.0.0",
+		group: "list", complexity: "O(N) where N is the number of elements to be removed by the operation.",
+	})
+	registerCommand(&command{
+		name: "lpos", handler: lposCommand, arity: -3, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the index of matching elements in a list.", since: "6.0.6", group: "list",
+		complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time.",
+	})
+	registerCommand(&command{
+		name: "lmove", handler: lmoveCommand, arity: 5, flags: flagWrite, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+		since:   "6.2.0", group: "list", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "rpoplpush", handler: rpoplpushCommand, arity: 3, flags: flagWrite, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Returns the last element of a list after removing and pushing it to another list. Deletes the list if the last element was popped.",
+		since:   "1.2.0", group: "list", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "lmpop", handler: lmpopCommand, arity: -4, flags: flagWrite, keyNumIndex: 1,
+		summary: "Returns multiple elements from a list after removing them. Deletes the list if the last element was popped.",
+		since:   "7.0.0", group: "list", complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+	})
+	registerCommand(&command{
+		name: "blpop", handler: blpopCommand, arity: -3, flags: flagWrite | flagBlocking, firstKey: 1, lastKey: -2, step: 1,
+		summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		since:   "2.0.0", group: "list", complexity: "O(N) where N is the number of provided keys.",
+	})
+	registerCommand(&command{
+		name: "brpop", handler: brpopCommand, arity: -3, flags: flagWrite | flagBlocking, firstKey: 1, lastKey: -2, step: 1,
+		summary: "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		since:   "2.0.0", group: "list", complexity: "O(N) where N is the number of provided keys.",
+	})
+	registerCommand(&command{
+		name: "blmove", handler: blmoveCommand, arity: 6, flags: flagWrite | flagBlocking, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.",
+		since:   "6.2.0", group: "list", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "brpoplpush", handler: brpoplpushCommand, arity: 4, flags: flagWrite | flagBlocking, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Pops an element from a list, pushes it to another list and returns it. Block until an element is available otherwise. Deletes the list if the last element was popped.",
+		since:   "2.2.0", group: "list", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "blmpop", handler: blmpopCommand, arity: -5, flags: flagWrite | flagBlocking, keyNumIndex: 2,
+		summary: "Pops the first element from one of multiple lists. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		since:   "7.0.0", group: "list", complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+	})
+}
+
+// getList returns the list stored at key, or nil if there is none. It
+// returns errWrongType if key holds a value of another type.
+func (ks *keyspace) getList(key string) (*quicklist, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	l, ok := value.(*quicklist)
+	if !ok {
+		return nil, errWrongType
+	}
+	return l, nil
+}
+
+// listRange converts the start and stop arguments of LRANGE and LTRIM,
+// which may count from the end of the list, to positions in a list of
+// length n. It reports false if the range is empty.
+func listRange(startArg, stopArg []byte, n int) (int, int, bool, error) {
+	start, ok := parseInt(startArg)
+	if !ok {
+		return 0, 0, false, errNotInteger
+	}
+	stop, ok := parseInt(stopArg)
+	if !ok {
+		return 0, 0, false, errNotInteger
+	}
+	if start < 0 {
+		start = max(start+int64(n), 0)
+	}
+	if stop < 0 {
+		stop += int64(n)
+	}
+	if start > stop || start >= int64(n) {
+		return 0, 0, false, nil
+	}
+	return int(start), int(min(stop, int64(n)-1)), true, nil
+}
+
+// listIndex converts an index argument, which may count from the end of
+// the list, to a position in a list of length n. It reports false if the
+// index is out of range.
+func listIndex(arg []byte, n int) (int, bool, error) {
+	index, ok := parseInt(arg)
+	if !ok {
+		return 0, false, errNotInteger
+	}
+	if index < 0 {
+		index += int64(n)
+	}
+	if index < 0 || index >= int64(n) {
+		return 0, false, nil
+	}
+	return int(index), true, nil
+}
+
+// pushGeneric implements LPUSH, RPUSH, LPUSHX and RPUSHX. It pushes at the
+// head of the list if left is set, and only pushes to an existing list if
+// onlyExisting is set.
+func pushGeneric(c *client, args [][]byte, left, onlyExisting bool) {
+	key := string(args[1])
+	l, err := c.db.getList(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		if onlyExisting {
+			c.w.WriteInteger(0)
+			return
+		}
+		l = newQuicklist()
+		c.db.set(key, l, false)
+	}
+	for _, element := range args[2:] {
+		// Arguments point into buffers that belong to the connection
+		if left {
+			l.pushFront(bytes.Clone(element))
+		} else {
+			l.pushBack(bytes.Clone(element))
+		}
+	}
+	c.db.signalKeyAsReady(key)
+	c.w.WriteInteger(int64(l.len()))
+}
+
+// lpushCommand implements LPUSH key element [element ...]
+func lpushCommand(c *client, args [][]byte) {
+	pushGeneric(c, args, true, false)
+}
+
+// rpushCommand implements RPUSH key element [element ...]
+func rpushCommand(c *client, args [][]byte) {
+	pushGeneric(c, args, false, false)
+}
+
+// lpushxCommand implements LPUSHX key element [element ...]
+func lpushxCommand(c *client, args [][]byte) {
+	pushGeneric(c, args, true, true)
+}
+
+// rpushxCommand implements RPUSHX key element [element ...]
+func rpushxCommand(c *client, args [][]byte) {
+	pushGeneric(c, args, false, true)
+}
+
+// popGeneric implements LPOP and RPOP. Without a count it replies with the
+// popped element, and with one with an array of up to count elements.
+func popGeneric(c *client, args [][]byte, left bool) {
+	if len(args) > 3 {
+		c.w.WriteError(wrongArityError(strings.ToLower(string(args[0]))))
+		return
+	}
+	hasCount := len(args) == 3
+	var count int64
+	if hasCount {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok || count < 0 {
+			c.w.WriteError(errNotPositive.Error())
+			return
+		}
+	}
+
+	key := string(args[1])
+	l, err := c.db.getList(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		if hasCount {
+			c.w.WriteNullArray()
+		} else {
+			c.w.WriteNull()
+		}
+		return
+	}
+	if !hasCount {
+		c.w.WriteBulk(popList(c.db, key, l, left))
+		return
+	}
+	n := int(min(count, int64(l.len())))
+	c.w.WriteArrayHeader(n)
+	for i := 0; i < n; i++ {
+		c.w.WriteBulk(popList(c.db, key, l, left))
+	}
+}
+
+// popList pops an element from the head of l, or from its tail unless left
+// is set, and deletes key once l is empty. l must not be empty.
+func popList(ks *keyspace, key string, l *quicklist, left bool) []byte {
+	var value []byte
+	if left {
+		value, _ = l.popFront()
+	} else {
+		value, _ = l.popBack()
+	}
+	if l.len() == 0 {
+		ks.remove(key)
+	}
+	return value
+}
+
+// lpopCommand implements LPOP key [count]
+func lpopCommand(c *client, args [][]byte) {
+	popGeneric(c, args, true)
+}
+
+// rpopCommand implements RPOP key [count]
+func rpopCommand(c *client, args [][]byte) {
+	popGeneric(c, args, false)
+}
+
+// llenCommand implements LLEN key
+func llenCommand(c *client, args [][]byte) {
+	l, err := c.db.getList(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(l.len()))
+}
+
+// lrangeCommand implements LRANGE key start stop
+func lrangeCommand(c *client, args [][]byte) {
+	l, err := c.db.getList(string(args[1]))
+	if err == nil && l == nil {
+		l = newQuicklist()
+	}
+	var start, stop int
+	var ok bool
+	if err == nil {
+		start, stop, ok, err = listRange(args[2], args[3], l.len())
+	}
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !ok {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+	c.w.WriteArrayHeader(stop - start + 1)
+	l.forEach(start, false, func(i int, value []byte) bool {
+		c.w.WriteBulk(value)
+		return i < stop
+	})
+}
+
+// lindexCommand implements LINDEX key index
+func lindexCommand(c *client, args [][]byte) {
+	l, err := c.db.getList(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		c.w.WriteNull()
+		return
+	}
+	index, ok, err := listIndex(args[2], l.len())
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !ok {
+		c.w.WriteNull()
+		return
+	}
+	c.w.WriteBulk(l.index(index))
+}
+
+// lsetCommand implements LSET key index element
+func lsetCommand(c *client, args [][]byte) {
+	l, err := c.db.getList(string(args[1]))
+	if err == nil && l == nil {
+		err = errNoSuchKey
+	}
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	index, ok, err := listIndex(args[2], l.len())
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !ok {
+		c.w.WriteError(errIndexRange.Error())
+		return
+	}
+	l.set(index, bytes.Clone(args[3]))
+	c.w.WriteSimpleString("OK")
+}
+
+// linsertCommand implements LINSERT key BEFORE | AFTER pivot element
+func linsertCommand(c *client, args [][]byte) {
+	var after bool
+	switch strings.ToUpper(string(args[2])) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	l, err := c.db.getList(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	pivot := -1
+	l.forEach(0, false, func(i int, value []byte) bool {
+		if bytes.Equal(value, args[3]) {
+			pivot = i
+			return false
+		}
+		return true
+	})
+	if pivot < 0 {
+		c.w.WriteInteger(-1)
+		return
+	}
+	if after {
+		pivot++
+	}
+	l.insert(pivot, bytes.Clone(args[4]))
+	c.w.WriteInteger(int64(l.len()))
+}
+
+// lremCommand implements LREM key count element
+func lremCommand(c *client, args [][]byte) {
+	count, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	key := string(args[1])
+	l, err := c.db.getList(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	// A count larger than the list is the same as removing them all
+	if count > int64(l.len()) || count < -int64(l.len()) {
+		count = 0
+	}
+	removed := l.remove(args[3], int(count))
+	if l.len() == 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteInteger(int64(removed))
+}
+
+// ltrimCommand implements LTRIM key start stop
+func ltrimCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	l, err := c.db.getList(key)
+	if err == nil && l == nil {
+		l = newQuicklist()
+	}
+	var start, stop int
+	var ok bool
+	if err == nil {
+		start, stop, ok, err = listRange(args[2], args[3], l.len())
+	}
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if ok {
+		l.trim(start, stop)
+	} else if l.len() > 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteSimpleString("OK")
+}
+
+// lposOptions holds the options of LPOS
+type lposOptions struct {
+	rank     int64
+	count    int64
+	hasCount bool
+	maxlen   int64
+}
+
+func parseLposOptions(args [][]byte) (lposOptions, error) {
+	opts := lposOptions{rank: 1}
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return lposOptions{}, errSyntax
+		}
+		n, ok := parseInt(args[i+1])
+		switch option := strings.ToUpper(string(args[i])); {
+		case !ok && (option == "RANK" || option == "COUNT" || option == "MAXLEN"):
+			return lposOptions{}, errNotInteger
+		case option == "RANK":
+			if n == 0 {
+				return lposOptions{}, errRankZero
+			}
+			if n == -n {
+				// Only the smallest integer is its own opposite, and it
+				// cannot be negated to count from the end
+				return lposOptions{}, errRankRange
+			}
+			opts.rank = n
+		case option == "COUNT":
+			if n < 0 {
+				return lposOptions{}, errCountNegative
+			}
+			opts.count, opts.hasCount = n, true
+		case option == "MAXLEN":
+			if n < 0 {
+				return lposOptions{}, errMaxlenNegative
+			}
+			opts.maxlen = n
+		default:
+			return lposOptions{}, errSyntax
+		}
+	}
+	return opts, nil
+}
+
+// lposCommand implements
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len]
+func lposCommand(c *client, args [][]byte) {
+	opts, err := parseLposOptions(args[3:])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	l, err := c.db.getList(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if l == nil {
+		if opts.hasCount {
+			c.w.WriteArrayHeader(0)
+		} else {
+			c.w.WriteNull()
+		}
+		return
+	}
+
+	// A negative rank searches from the tail
+	reverse := opts.rank < 0
+	skip := opts.rank - 1
+	start := 0
+	if reverse {
+		skip = -opts.rank - 1
+		start = l.len() - 1
+	}
+	var matches []int64
+	var compared int64
+	l.forEach(start, reverse, func(i int, value []byte) bool {
+		if opts.maxlen != 0 && compared == opts.maxlen {
+			return false
+		}
+		compared++
+		if !bytes.Equal(value, args[2]) {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		matches = append(matches, int64(i))
+		// COUNT 0 means all the matches
+		return opts.hasCount && (opts.count == 0 || int64(len(matches)) < opts.count)
+	})
+
+	if !opts.hasCount {
+		if len(matches) == 0 {
+			c.w.WriteNull()
+			return
+		}
+		c.w.WriteInteger(matches[0])
+		return
+	}
+	c.w.WriteArrayHeader(len(matches))
+	for _, i := range matches {
+		c.w.WriteInteger(i)
+	}
+}
+
+// parseListEnd parses the LEFT or RIGHT argument of LMOVE, and reports
+// whether it is LEFT
+func parseListEnd(arg []byte) (bool, error) {
+	switch strings.ToUpper(string(arg)) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, errSyntax
+}
+
+// lmove pops an element from one end of the list at source and pushes it
+// to one end of the list at destination, which may be the same list. It
+// returns the element, or false if there is no list at source.
+func lmove(ks *keyspace, source, destination string, fromLeft, toLeft bool) ([]byte, bool, error) {
+	src, err := ks.getList(source)
+	if err != nil || src == nil {
+		return nil, false, err
+	}
+	dst, err := ks.getList(destination)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value := popList(ks, source, src, fromLeft)
+	if dst == nil || (source == destination && src.len() == 0) {
+		dst = newQuicklist()
+		ks.set(destination, dst, false)
+	}
+	if toLeft {
+		dst.pushFront(value)
+	} else {
+		dst.pushBack(value)
+	}
+	ks.signalKeyAsReady(destination)
+	return value, true, nil
+}
+
+// lmoveGeneric implements LMOVE and RPOPLPUSH
+func lmoveGeneric(c *client, source, destination string, fromLeft, toLeft bool) {
+	value, ok, err := lmove(c.db, source, destination, fromLeft, toLeft)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !ok {
+		c.w.WriteNull()
+		return
+	}
+	c.w.WriteBulk(value)
+}
+
+// lmoveCommand implements LMOVE source destination LEFT | RIGHT LEFT | RIGHT
+func lmoveCommand(c *client, args [][]byte) {
+	fromLeft, err := parseListEnd(args[3])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	toLeft, err := parseListEnd(args[4])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	lmoveGeneric(c, string(args[1]), string(args[2]), fromLeft, toLeft)
+}
+
+// rpoplpushCommand implements RPOPLPUSH source destination
+func rpoplpushCommand(c *client, args [][]byte) {
+	lmoveGeneric(c, string(args[1]), string(args[2]), false, true)
+}
+
+// blockingPopGeneric implements BLPOP key [key ...] timeout and BRPOP. It
+// pops from the first of the keys holding a list, or blocks until one of
+// them does.
+func blockingPopGeneric(c *client, args [][]byte, left bool) {
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	keys := make([]string, 0, len(args)-2)
+	for _, key := range args[1 : len(args)-1] {
+		keys = append(keys, string(key))
+	}
+	serve := func(key string) (resp.Value, bool) {
+		l, err := c.db.getList(key)
+		if err != nil || l == nil {
+			return resp.Value{}, false
+		}
+		value := popList(c.db, key, l, left)
+		return resp.NewArray(resp.NewBulkString(key), resp.NewBulk(value)), true
+	}
+	for _, key := range keys {
+		if _, err := c.db.getList(key); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+		if reply, ok := serve(key); ok {
+			c.w.WriteValue(reply)
+			return
+		}
+	}
+	c.blockForKeys(keys, timeout, serve, resp.NewNullArray())
+}
+
+// blpopCommand implements BLPOP key [key ...] timeout
+func blpopCommand(c *client, args [][]byte) {
+	blockingPopGeneric(c, args, true)
+}
+
+// brpopCommand implements BRPOP key [key ...] timeout
+func brpopCommand(c *client, args [][]byte) {
+	blockingPopGeneric(c, args, false)
+}
+
+// blmoveGeneric implements BLMOVE and BRPOPLPUSH. It moves an element like
+// LMOVE, or blocks until there is one at source.
+func blmoveGeneric(c *client, source, destination string, fromLeft, toLeft bool, timeoutArg []byte) {
+	timeout, err := parseTimeout(timeoutArg)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	value, ok, err := lmove(c.db, source, destination, fromLeft, toLeft)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if ok {
+		c.w.WriteBulk(value)
+		return
+	}
+	c.blockForKeys([]string{source}, timeout, func(string) (resp.Value, bool) {
+		// A destination that holds another type keeps the client blocked
+		value, ok, err := lmove(c.db, source, destination, fromLeft, toLeft)
+		if err != nil || !ok {
+			return resp.Value{}, false
+		}
+		return resp.NewBulk(value), true
+	}, resp.NewNull())
+}
+
+// blmoveCommand implements
+// BLMOVE source destination LEFT | RIGHT LEFT | RIGHT timeout
+func blmoveCommand(c *client, args [][]byte) {
+	fromLeft, err := parseListEnd(args[3])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	toLeft, err := parseListEnd(args[4])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	blmoveGeneric(c, string(args[1]), string(args[2]), fromLeft, toLeft, args[5])
+}
+
+// brpoplpushCommand implements BRPOPLPUSH source destination timeout
+func brpoplpushCommand(c *client, args [][]byte) {
+	blmoveGeneric(c, string(args[1]), string(args[2]), false, true, args[3])
+}
+
+var (
+	errNumKeys       = errors.New("ERR numkeys should be greater than 0")
+	errCountPositive = errors.New("ERR count should be greater than 0")
+)
+
+// parseMpopArgs parses the arguments of LMPOP, ZMPOP and their blocking
+// variants from numkeys on, as in numkeys key [key ...] where [COUNT count].
+// parseWhere parses the where argument, such as LEFT | RIGHT. It returns
+// the keys, what parseWhere returned and how many elements to pop.
+func parseMpopArgs(args [][]byte, parseWhere func([]byte) (bool, error)) ([]string, bool, int64, error) {
+	numKeys, ok := parseInt(args[0])
+	if !ok || numKeys <= 0 {
+		return nil, false, 0, errNumKeys
+	}
+	if numKeys >= int64(len(args))-1 {
+		return nil, false, 0, errSyntax
+	}
+	keys := make([]string, 0, numKeys)
+	for _, key := range args[1 : numKeys+1] {
+		keys = append(keys, string(key))
+	}
+	where, err := parseWhere(args[numKeys+1])
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	count := int64(1)
+	options := args[numKeys+2:]
+	if len(options) > 0 {
+		if len(options) != 2 || !strings.EqualFold(string(options[0]), "COUNT") {
+			return nil, false, 0, errSyntax
+		}
+		if count, ok = parseInt(options[1]); !ok || count <= 0 {
+			return nil, false, 0, errCountPositive
+		}
+	}
+	return keys, where, count, nil
+}
+
+// mpop pops up to count elements from the list at key, and returns the
+// reply of LMPOP. It returns false if there is no list at key.
+func mpop(ks *keyspace, key string, left bool, count int64) (resp.Value, bool, error) {
+	l, err := ks.getList(key)
+	if err != nil || l == nil {
+		return resp.Value{}, false, err
+	}
+	elements := make([]resp.Value, 0, min(count, int64(l.len())))
+	for int64(len(elements)) < count && l.len() > 0 {
+		elements = append(elements, resp.NewBulk(popList(ks, key, l, left)))
+	}
+	return resp.NewArray(resp.NewBulkString(key), resp.NewArray(elements...)), true, nil
+}
+
+// mpopGeneric implements LMPOP and BLMPOP, whose arguments from numkeys on
+// are args. It pops from the first of the keys holding a list, and blocks
+// until one of them does if blocking is set.
+func mpopGeneric(c *client, args [][]byte, blocking bool, timeout time.Duration) {
+	keys, left, count, err := parseMpopArgs(args, parseListEnd)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	for _, key := range keys {
+		reply, ok, err := mpop(c.db, key, left, count)
+		if err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+		if ok {
+			c.w.WriteValue(reply)
+			return
+		}
+	}
+	if !blocking {
+		c.w.WriteNullArray()
+		return
+	}
+	c.blockForKeys(keys, timeout, func(key string) (resp.Value, bool) {
+		reply, ok, _ := mpop(c.db, key, left, count)
+		return reply, ok
+	}, resp.NewNullArray())
+}
+
+// lmpopCommand implements LMPOP numkeys key [key ...] LEFT | RIGHT [COUNT count]
+func lmpopCommand(c *client, args [][]byte) {
+	mpopGeneric(c, args[1:], false, 0)
+}
+
+// blmpopCommand implements
+// BLMPOP timeout numkeys key [key ...] LEFT | RIGHT [COUNT count]
+func blmpopCommand(c *client, args [][]byte) {
+	timeout, err := parseTimeout(args[1])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	mpopGeneric(c, args[2:], true, timeout)
+}
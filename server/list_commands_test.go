@@ -0,0 +1,145 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ListCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should push to both ends and return the length",
+			input: "RPUSH l b c\r\nLPUSH l a z\r\nLRANGE l 0 -1\r\n",
+			want:  ":2\r\n:4\r\n*4\r\n$1\r\nz\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n",
+		},
+		{
+			name:  "It should only push to existing lists with LPUSHX and RPUSHX",
+			input: "LPUSHX l a\r\nRPUSHX l a\r\nEXISTS l\r\nRPUSH l a\r\nLPUSHX l b\r\nRPUSHX l c d\r\nLRANGE l 0 -1\r\n",
+			want:  ":0\r\n:0\r\n:0\r\n:1\r\n:2\r\n:4\r\n*4\r\n$1\r\nb\r\n$1\r\na\r\n$1\r\nc\r\n$1\r\nd\r\n",
+		},
+		{
+			name:  "It should pop from both ends and delete the emptied list",
+			input: "RPUSH l a b c\r\nLPOP l\r\nRPOP l\r\nLPOP l\r\nLPOP l\r\nEXISTS l\r\n",
+			want:  ":3\r\n$1\r\na\r\n$1\r\nc\r\n$1\r\nb\r\n$-1\r\n:0\r\n",
+		},
+		{
+			name:  "It should pop several elements with a count",
+			input: "RPUSH l a b c\r\nRPOP l 2\r\nLPOP l 0\r\nLPOP l 5\r\nLPOP l 1\r\n",
+			want:  ":3\r\n*2\r\n$1\r\nc\r\n$1\r\nb\r\n*0\r\n*1\r\n$1\r\na\r\n*-1\r\n",
+		},
+		{
+			name:  "It should reject a negative or invalid count",
+			input: "LPOP l -1\r\nRPOP l x\r\nLPOP l 1 2\r\n",
+			want:  "-ERR value is out of range, must be positive\r\n-ERR value is out of range, must be positive\r\n-ERR wrong number of arguments for 'lpop' command\r\n",
+		},
+		{
+			name:  "It should return ranges with negative and out of range indexes",
+			input: "RPUSH l a b c d\r\nLRANGE l 1 2\r\nLRANGE l -2 100\r\nLRANGE l -100 0\r\nLRANGE l 3 1\r\nLRANGE l 5 10\r\nLRANGE missing 0 -1\r\nLRANGE l a 1\r\n",
+			want:  ":4\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n*2\r\n$1\r\nc\r\n$1\r\nd\r\n*1\r\n$1\r\na\r\n*0\r\n*0\r\n*0\r\n-ERR value is not an integer or out of range\r\n",
+		},
+		{
+			name:  "It should return the length of a list",
+			input: "RPUSH l a b\r\nLLEN l\r\nLLEN missing\r\n",
+			want:  ":2\r\n:2\r\n:0\r\n",
+		},
+		{
+			name:  "It should get and set elements by index",
+			input: "RPUSH l a b c\r\nLINDEX l 0\r\nLINDEX l -1\r\nLINDEX l 3\r\nLSET l -2 x\r\nLINDEX l 1\r\nLSET l 3 y\r\nLSET missing 0 y\r\n",
+			want:  ":3\r\n$1\r\na\r\n$1\r\nc\r\n$-1\r\n+OK\r\n$1\r\nx\r\n-ERR index out of range\r\n-ERR no such key\r\n",
+		},
+		{
+			name:  "It should insert before and after a pivot",
+			input: "RPUSH l a c\r\nLINSERT l BEFORE c b\r\nLINSERT l after c d\r\nLINSERT l BEFORE x y\r\nLINSERT missing BEFORE a b\r\nLINSERT l AROUND a b\r\nLRANGE l 0 -1\r\n",
+			want:  ":2\r\n:3\r\n:4\r\n:-1\r\n:0\r\n-ERR syntax error\r\n*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\nd\r\n",
+		},
+		{
+			name:  "It should remove elements from the head, the tail or everywhere",
+			input: "RPUSH l a x b x c x\r\nLREM l 1 x\r\nLREM l -1 x\r\nLRANGE l 0 -1\r\nLREM l 0 x\r\nLREM l 0 a\r\nLREM l 10 b\r\nLREM l -10 c\r\nEXISTS l\r\n",
+			want:  ":6\r\n:1\r\n:1\r\n*4\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nx\r\n$1\r\nc\r\n:1\r\n:1\r\n:1\r\n:1\r\n:0\r\n",
+		},
+		{
+			name:  "It should trim a list and delete it when nothing is left",
+			input: "RPUSH l a b c d\r\nLTRIM l 1 -2\r\nLRANGE l 0 -1\r\nLTRIM l 5 10\r\nEXISTS l\r\nLTRIM missing 0 1\r\n",
+			want:  ":4\r\n+OK\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n+OK\r\n:0\r\n+OK\r\n",
+		},
+		{
+			name:  "It should find the position of elements",
+			input: "RPUSH l a b c 1 2 3 c c\r\nLPOS l c\r\nLPOS l c RANK 2\r\nLPOS l c RANK -1\r\nLPOS l x\r\nLPOS missing x\r\n",
+			want:  ":8\r\n:2\r\n:6\r\n:7\r\n$-1\r\n$-1\r\n",
+		},
+		{
+			name:  "It should return several positions with COUNT",
+			input: "RPUSH l a b c 1 2 3 c c\r\nLPOS l c COUNT 2\r\nLPOS l c COUNT 0\r\nLPOS l c COUNT 0 RANK -2\r\nLPOS l x COUNT 1\r\nLPOS missing x COUNT 1\r\n",
+			want:  ":8\r\n*2\r\n:2\r\n:6\r\n*3\r\n:2\r\n:6\r\n:7\r\n*2\r\n:6\r\n:2\r\n*0\r\n*0\r\n",
+		},
+		{
+			name:  "It should only compare MAXLEN elements",
+			input: "RPUSH l a b c 1 2 3 c c\r\nLPOS l c MAXLEN 2\r\nLPOS l c MAXLEN 3\r\nLPOS l a RANK -1 MAXLEN 7\r\n",
+			want:  ":8\r\n$-1\r\n:2\r\n$-1\r\n",
+		},
+		{
+			name:  "It should reject invalid LPOS options",
+			input: "LPOS l a RANK 0\r\nLPOS l a RANK -9223372036854775808\r\nLPOS l a COUNT -1\r\nLPOS l a MAXLEN -1\r\nLPOS l a RANK\r\nLPOS l a FOO 1\r\nLPOS l a COUNT x\r\n",
+			want: "-ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list\r\n" +
+				"-ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807\r\n" +
+				"-ERR COUNT can't be negative\r\n-ERR MAXLEN can't be negative\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"-ERR value is not an integer or out of range\r\n",
+		},
+		{
+			name:  "It should move elements between lists",
+			input: "RPUSH src a b c\r\nLMOVE src dst LEFT RIGHT\r\nLMOVE src dst RIGHT LEFT\r\nLRANGE dst 0 -1\r\nRPOPLPUSH src dst\r\nEXISTS src\r\nLMOVE src dst LEFT LEFT\r\nLMOVE src dst UP LEFT\r\n",
+			want:  ":3\r\n$1\r\na\r\n$1\r\nc\r\n*2\r\n$1\r\nc\r\n$1\r\na\r\n$1\r\nb\r\n:0\r\n$-1\r\n-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should rotate a list moved onto itself",
+			input: "RPUSH l a b c\r\nLMOVE l l LEFT RIGHT\r\nLRANGE l 0 -1\r\nDEL l\r\nRPUSH l a\r\nRPOPLPUSH l l\r\nLRANGE l 0 -1\r\n",
+			want:  ":3\r\n$1\r\na\r\n*3\r\n$1\r\nb\r\n$1\r\nc\r\n$1\r\na\r\n:1\r\n:1\r\n$1\r\na\r\n*1\r\n$1\r\na\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ListCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET s v\r\nRPUSH l a\r\n")
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"LPUSH s a", "RPUSHX s a", "LPOP s", "RPOP s 1", "LLEN s", "LRANGE s 0 -1", "LINDEX s 0",
+		"LSET s 0 a", "LINSERT s BEFORE a b", "LREM s 0 a", "LTRIM s 0 1", "LPOS s a",
+		"LMOVE s l LEFT LEFT", "LMOVE l s LEFT LEFT", "GET l", "APPEND l a",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+	// A failed LMOVE leaves the source alone
+	if got := srv.HandleRequest("LLEN l\r\n"); got != ":1\r\n" {
+		t.Errorf("LLEN l = %q, want %q", got, ":1\r\n")
+	}
+}
+
+func Test_ListCommandsLongList(t *testing.T) {
+	srv := New(Config{})
+	var push strings.Builder
+	push.WriteString("RPUSH l")
+	for i := 0; i < 1000; i++ {
+		push.WriteString(" e")
+	}
+	srv.HandleRequest(push.String() + "\r\nLSET l 500 middle\r\nLINSERT l AFTER middle after\r\n")
+
+	want := "$6\r\nmiddle\r\n$5\r\nafter\r\n:501\r\n:1001\r\n"
+	if got := srv.HandleRequest("LINDEX l 500\r\nLINDEX l -500\r\nLPOS l after\r\nLLEN l\r\n"); got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import "bytes"
+
+// quicklistNodeSize is the most elements a node of a quicklist holds
+const quicklistNodeSize = 128
+
+// quicklist is the list type. Like the quicklist of Redis it is a doubly
+// linked list of nodes that each hold a small slice of elements, so pushing
+// and popping at either end is O(1) while the elements stay packed together
+// instead of costing a node each. Empty nodes are unlinked straight away.
+type quicklist struct {
+	head, tail *quicklistNode
+	count      int
+}
+
+type quicklistNode struct {
+	prev, next *quicklistNode
+	entries    [][]byte
+}
+
+func newQuicklist() *quicklist {
+	return &quicklist{}
+}
+
+// len returns the number of elements in the list
+func (l *quicklist) len() int {
+	return l.count
+}
+
+// pushFront adds value at the head of the list. The list keeps value, so it
+// must not be modified afterwards.
+func (l *quicklist) pushFront(value []byte) {
+	if l.head == nil || len(l.head.entries) == quicklistNodeSize {
+		l.insertNodeAfter(nil, &quicklistNode{})
+	}
+	node := l.head
+	node.entries = append(node.entries, nil)
+	copy(node.entries[1:], node.entries)
+	node.entries[0] = value
+	l.count++
+}
+
+// pushBack adds value at the tail of the list. The list keeps value, so it
+// must not be modified afterwards.
+func (l *quicklist) pushBack(value []byte) {
+	if l.tail == nil || len(l.tail.entries) == quicklistNodeSize {
+		l.insertNodeAfter(l.tail, &quicklistNode{})
+	}
+	l.tail.entries = append(l.tail.entries, value)
+	l.count++
+}
+
+// popFront removes and returns the element at the head of the list
+func (l *quicklist) popFront() ([]byte, bool) {
+	if l.count == 0 {
+		return nil, false
+	}
+	value := l.head.entries[0]
+	l.removeEntry(l.head, 0)
+	return value, true
+}
+
+// popBack removes and returns the element at the tail of the list
+func (l *quicklist) popBack() ([]byte, bool) {
+	if l.count == 0 {
+		return nil, false
+	}
+	node := l.tail
+	value := node.entries[len(node.entries)-1]
+	l.removeEntry(node, len(node.entries)-1)
+	return value, true
+}
+
+// index returns the element at position i, which must be in range
+func (l *quicklist) index(i int) []byte {
+	node, offset := l.locate(i)
+	return node.entries[offset]
+}
+
+// set replaces the element at position i, which must be in range
+func (l *quicklist) set(i int, value []byte) {
+	node, offset := l.locate(i)
+	node.entries[offset] = value
+}
+
+// insert adds value at position i, moving the element there and the ones
+// after it one position further. i may be the length of the list.
+func (l *quicklist) insert(i int, value []byte) {
+	if i == l.count {
+		l.pushBack(value)
+		return
+	}
+	node, offset := l.locate(i)
+	if len(node.entries) == quicklistNodeSize {
+		// Split the full node in two halves
+		half := &quicklistNode{entries: append([][]byte(nil), node.entries[quicklistNodeSize/2:]...)}
+		clear(node.entries[quicklistNodeSize/2:])
+		node.entries = node.entries[:quicklistNodeSize/2]
+		l.insertNodeAfter(node, half)
+		if offset >= len(node.entries) {
+			node, offset = half, offset-len(node.entries)
+		}
+	}
+	node.entries = append(node.entries, nil)
+	copy(node.entries[offset+1:], node.entries[offset:])
+	node.entries[offset] = value
+	l.count++
+}
+
+// trim keeps the elements from position start to stop, inclusive, which
+// must be in range, and removes the others
+func (l *quicklist) trim(start, stop int) {
+	l.dropFront(start)
+	l.dropBack(l.count - (stop - start + 1))
+}
+
+// dropFront removes the first n elements
+func (l *quicklist) dropFront(n int) {
+	for n > 0 {
+		node := l.head
+		if n >= len(node.entries) {
+			n -= len(node.entries)
+			l.count -= len(node.entries)
+			l.unlink(node)
+			continue
+		}
+		clear(node.entries[:n])
+		node.entries = node.entries[n:]
+		l.count -= n
+		return
+	}
+}
+
+// dropBack removes the last n elements
+func (l *quicklist) dropBack(n int) {
+	for n > 0 {
+		node := l.tail
+		if n >= len(node.entries) {
+			n -= len(node.entries)
+			l.count -= len(node.entries)
+			l.unlink(node)
+			continue
+		}
+		keep := len(node.entries) - n
+		clear(node.entries[keep:])
+		node.entries = node.entries[:keep]
+		l.count -= n
+		return
+	}
+}
+
+// remove deletes the elements equal to value. A positive limit deletes at
+// most limit of them starting from the head, a negative one at most -limit
+// starting from the tail, and zero all of them. It returns how many
+// elements it deleted.
+func (l *quicklist) remove(value []byte, limit int) int {
+	removed := 0
+	if limit >= 0 {
+		for node := l.head; node != nil; {
+			next := node.next
+			for i := 0; i < len(node.entries); {
+				if limit != 0 && removed == limit {
+					return removed
+				}
+				if !bytes.Equal(node.entries[i], value) {
+					i++
+					continue
+				}
+				l.removeEntry(node, i)
+				removed++
+			}
+			node = next
+		}
+		return removed
+	}
+	for node := l.tail; node != nil; {
+		prev := node.prev
+		for i := len(node.entries) - 1; i >= 0; i-- {
+			if removed == -limit {
+				return removed
+			}
+			if bytes.Equal(node.entries[i], value) {
+				l.removeEntry(node, i)
+				removed++
+			}
+		}
+		node = prev
+	}
+	return removed
+}
+
+// forEach calls fn with the position and value of the elements from
+// position start, which must be in range, towards the tail, or towards the
+// head if reverse is set. It stops when fn returns false.
+func (l *quicklist) forEach(start int, reverse bool, fn func(i int, value []byte) bool) {
+	if start < 0 || start >= l.count {
+		return
+	}
+	node, offset := l.locate(start)
+	i := start
+	for node != nil {
+		if reverse {
+			for ; offset >= 0; offset-- {
+				if !fn(i, node.entries[offset]) {
+					return
+				}
+				i--
+			}
+			node = node.prev
+			if node != nil {
+				offset = len(node.entries) - 1
+			}
+			continue
+		}
+		for ; offset < len(node.entries); offset++ {
+			if !fn(i, node.entries[offset]) {
+				return
+			}
+			i++
+		}
+		node, offset = node.next, 0
+	}
+}
+
+// locate returns the node holding position i and the offset of i within
+// it, walking from whichever end of the list is closer
+func (l *quicklist) locate(i int) (*quicklistNode, int) {
+	if i < l.count/2 {
+		node := l.head
+		for i >= len(node.entries) {
+			i -= len(node.entries)
+			node = node.next
+		}
+		return node, i
+	}
+	node := l.tail
+	i = l.count - 1 - i
+	for i >= len(node.entries) {
+		i -= len(node.entries)
+		node = node.prev
+	}
+	return node, len(node.entries) - 1 - i
+}
+
+// removeEntry deletes the element at offset in node, and unlinks the node
+// if that leaves it empty
+func (l *quicklist) removeEntry(node *quicklistNode, offset int) {
+	copy(node.entries[offset:], node.entries[offset+1:])
+	node.entries[len(node.entries)-1] = nil
+	node.entries = node.entries[:len(node.entries)-1]
+	l.count--
+	if len(node.entries) == 0 {
+		l.unlink(node)
+	}
+}
+
+// insertNodeAfter links node in after prev, or at the head if prev is nil
+func (l *quicklist) insertNodeAfter(prev, node *quicklistNode) {
+	node.prev = prev
+	if prev == nil {
+		node.next = l.head
+		l.head = node
+	} else {
+		node.next = prev.next
+		prev.next = node
+	}
+	if node.next == nil {
+		l.tail = node
+	} else {
+		node.next.prev = node
+	}
+}
+
+func (l *quicklist) unlink(node *quicklistNode) {
+	if node.prev == nil {
+		l.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		l.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+	node.prev, node.next = nil, nil
+}
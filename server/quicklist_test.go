@@ -0,0 +1,174 @@
+package server
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// quicklistElements returns the elements of l from head to tail
+func quicklistElements(l *quicklist) []string {
+	elements := []string{}
+	l.forEach(0, false, func(_ int, value []byte) bool {
+		elements = append(elements, string(value))
+		return true
+	})
+	return elements
+}
+
+func Test_quicklist(t *testing.T) {
+	// Enough elements to span several nodes, so every operation crosses
+	// node boundaries
+	const n = 3*quicklistNodeSize + 7
+	tests := []struct {
+		name  string
+		apply func(l *quicklist, want []string) []string
+	}{
+		{
+			name: "It should push to both ends",
+			apply: func(l *quicklist, want []string) []string {
+				l.pushFront([]byte("front"))
+				l.pushBack([]byte("back"))
+				return append(append([]string{"front"}, want...), "back")
+			},
+		},
+		{
+			name: "It should pop from both ends",
+			apply: func(l *quicklist, want []string) []string {
+				for i := 0; i < quicklistNodeSize+1; i++ {
+					l.popFront()
+					l.popBack()
+				}
+				return want[quicklistNodeSize+1 : len(want)-quicklistNodeSize-1]
+			},
+		},
+		{
+			name: "It should insert into a full node by splitting it",
+			apply: func(l *quicklist, want []string) []string {
+				l.insert(5, []byte("x"))
+				l.insert(quicklistNodeSize+3, []byte("y"))
+				want = append(want[:5], append([]string{"x"}, want[5:]...)...)
+				return append(want[:quicklistNodeSize+3], append([]string{"y"}, want[quicklistNodeSize+3:]...)...)
+			},
+		},
+		{
+			name: "It should insert at the end",
+			apply: func(l *quicklist, want []string) []string {
+				l.insert(l.len(), []byte("z"))
+				return append(want, "z")
+			},
+		},
+		{
+			name: "It should set elements by position",
+			apply: func(l *quicklist, want []string) []string {
+				l.set(0, []byte("a"))
+				l.set(n-1, []byte("b"))
+				l.set(quicklistNodeSize*2, []byte("c"))
+				want[0], want[n-1], want[quicklistNodeSize*2] = "a", "b", "c"
+				return want
+			},
+		},
+		{
+			name: "It should trim both ends across nodes",
+			apply: func(l *quicklist, want []string) []string {
+				l.trim(quicklistNodeSize+10, n-quicklistNodeSize-3)
+				return want[quicklistNodeSize+10 : n-quicklistNodeSize-2]
+			},
+		},
+		{
+			name: "It should remove matches from the head",
+			apply: func(l *quicklist, want []string) []string {
+				for i := 0; i < n; i += 3 {
+					l.set(i, []byte("m"))
+					want[i] = "m"
+				}
+				if got := l.remove([]byte("m"), 2); got != 2 {
+					t.Errorf("remove() = %d, want 2", got)
+				}
+				return append(want[1:3], want[4:]...)
+			},
+		},
+		{
+			name: "It should remove matches from the tail",
+			apply: func(l *quicklist, want []string) []string {
+				l.set(0, []byte("m"))
+				l.set(n-1, []byte("m"))
+				l.set(n-2, []byte("m"))
+				if got := l.remove([]byte("m"), -2); got != 2 {
+					t.Errorf("remove() = %d, want 2", got)
+				}
+				return append([]string{"m"}, want[1:n-2]...)
+			},
+		},
+		{
+			name: "It should remove every match, dropping emptied nodes",
+			apply: func(l *quicklist, want []string) []string {
+				for i := 0; i < quicklistNodeSize*2; i++ {
+					l.set(i, []byte("m"))
+				}
+				if got := l.remove([]byte("m"), 0); got != quicklistNodeSize*2 {
+					t.Errorf("remove() = %d, want %d", got, quicklistNodeSize*2)
+				}
+				return want[quicklistNodeSize*2:]
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newQuicklist()
+			var want []string
+			for i := 0; i < n; i++ {
+				l.pushBack([]byte(strconv.Itoa(i)))
+				want = append(want, strconv.Itoa(i))
+			}
+			want = tt.apply(l, want)
+			if got := quicklistElements(l); !reflect.DeepEqual(got, want) {
+				t.Errorf("elements = %v, want %v", got, want)
+			}
+			if l.len() != len(want) {
+				t.Errorf("len() = %d, want %d", l.len(), len(want))
+			}
+			for i, value := range want {
+				if got := string(l.index(i)); got != value {
+					t.Fatalf("index(%d) = %q, want %q", i, got, value)
+				}
+			}
+		})
+	}
+}
+
+func Test_quicklistForEachInReverse(t *testing.T) {
+	l := newQuicklist()
+	for i := 0; i < quicklistNodeSize+2; i++ {
+		l.pushFront([]byte(strconv.Itoa(i)))
+	}
+	var got []int
+	l.forEach(quicklistNodeSize, true, func(i int, value []byte) bool {
+		if string(value) != strconv.Itoa(quicklistNodeSize+1-i) {
+			t.Fatalf("element %d = %q", i, value)
+		}
+		got = append(got, i)
+		return true
+	})
+	if len(got) != quicklistNodeSize+1 || got[0] != quicklistNodeSize || got[len(got)-1] != 0 {
+		t.Errorf("forEach() visited %v", got)
+	}
+}
+
+func Test_quicklistPopEmptiesTheList(t *testing.T) {
+	l := newQuicklist()
+	l.pushBack([]byte("a"))
+	if v, ok := l.popBack(); !ok || string(v) != "a" {
+		t.Fatalf("popBack() = %q, %v", v, ok)
+	}
+	if _, ok := l.popFront(); ok {
+		t.Errorf("popFront() on an empty list = true")
+	}
+	if l.head != nil || l.tail != nil {
+		t.Errorf("an empty list still has nodes")
+	}
+	l.pushFront([]byte("b"))
+	if got := quicklistElements(l); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("elements = %v, want [b]", got)
+	}
+}
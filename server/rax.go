@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// rax is a radix tree mapping byte strings to values, like the rax Redis
+// keeps the nodes of a stream in. A node holds the bytes of the path from
+// its parent, so a chain of nodes with a single child is compressed into
+// one, and the keys are visited in lexicographical order.
+type rax[V any] struct {
+	root *raxNode[V]
+	// size is the number of keys
+	size int
+	// nodes is the number of nodes, the root included
+	nodes int
+}
+
+type raxNode[V any] struct {
+	// prefix is the part of the key between the parent and this node
+	prefix []byte
+	// children are ordered by the first byte of their prefix
+	children []*raxNode[V]
+	// isKey is set if the path down to this node is a key, which maps to
+	// value
+	isKey bool
+	value V
+}
+
+func newRax[V any]() *rax[V] {
+	return &rax[V]{root: &raxNode[V]{}, nodes: 1}
+}
+
+// child returns the index of the child whose prefix starts with b, or
+// where it would go, and whether there is one
+func (n *raxNode[V]) child(b byte) (int, bool) {
+	return slices.BinarySearchFunc(n.children, b, func(child *raxNode[V], b byte) int {
+		return cmp.Compare(child.prefix[0], b)
+	})
+}
+
+// insert maps key to value, and reports whether key is new
+func (r *rax[V]) insert(key []byte, value V) bool {
+	n := r.root
+	for len(key) > 0 {
+		i, ok := n.child(key[0])
+		if !ok {
+			n.children = slices.Insert(n.children, i, &raxNode[V]{prefix: bytes.Clone(key), isKey: true, value: value})
+			r.nodes++
+			r.size++
+			return true
+		}
+		child := n.children[i]
+		common := 0
+		for common < len(child.prefix) && common < len(key) && child.prefix[common] == key[common] {
+			common++
+		}
+		if common < len(child.prefix) {
+			// The key leaves the prefix of child halfway, so split it
+			split := &raxNode[V]{prefix: child.prefix[:common:common], children: []*raxNode[V]{child}}
+			child.prefix = child.prefix[common:]
+			n.children[i] = split
+			r.nodes++
+			child = split
+		}
+		n, key = child, key[common:]
+	}
+	isNew := !n.isKey
+	if isNew {
+		r.size++
+	}
+	n.isKey, n.value = true, value
+	return isNew
+}
+
+// find returns the value of key
+func (r *rax[V]) find(key []byte) (V, bool) {
+	n := r.root
+	for len(key) > 0 {
+		i, ok := n.child(key[0])
+		if !ok || !bytes.HasPrefix(key, n.children[i].prefix) {
+			var zero V
+			return zero, false
+		}
+		n, key = n.children[i], key[len(n.children[i].prefix):]
+	}
+	return n.value, n.isKey
+}
+
+// remove removes key, and reports whether it was there
+func (r *rax[V]) remove(key []byte) bool {
+	// path holds the nodes from the root down to the key
+	path := []*raxNode[V]{r.root}
+	n := r.root
+	for len(key) > 0 {
+		i, ok := n.child(key[0])
+		if !ok || !bytes.HasPrefix(key, n.children[i].prefix) {
+			return false
+		}
+		n, key = n.children[i], key[len(n.children[i].prefix):]
+		path = append(path, n)
+	}
+	if !n.isKey {
+		return false
+	}
+	var zero V
+	n.isKey, n.value = false, zero
+	r.size--
+
+	// Remove the nodes that are left with neither a key nor children
+	last := len(path) - 1
+	for ; last > 0 && !path[last].isKey && len(path[last].children) == 0; last-- {
+		parent := path[last-1]
+		i, _ := parent.child(path[last].prefix[0])
+		parent.children = slices.Delete(parent.children, i, i+1)
+		r.nodes--
+	}
+	// A node left with a single child and no key is compressed into it
+	if n := path[last]; last > 0 && !n.isKey && len(n.children) == 1 {
+		child := n.children[0]
+		child.prefix = append(slices.Clip(n.prefix), child.prefix...)
+		parent := path[last-1]
+		i, _ := parent.child(n.prefix[0])
+		parent.children[i] = child
+		r.nodes--
+	}
+	return true
+}
+
+// walk calls fn with the keys and their values in order, starting from the
+// first key no lower than from, until it returns false. If reverse is set,
+// it goes in reverse order starting from the last key no greater than
+// from. A nil from starts from the first key, or the last one in reverse.
+// The key passed to fn is only valid until it returns.
+func (r *rax[V]) walk(from []byte, reverse bool, fn func(key []byte, value V) bool) {
+	r.root.walk(nil, from, from != nil, reverse, fn)
+}
+
+// walk walks the subtree of n, whose parent is at path. While bounded is
+// set, path is a prefix of from and the keys beyond from are left out.
+func (n *raxNode[V]) walk(path, from []byte, bounded, reverse bool, fn func(key []byte, value V) bool) bool {
+	path = append(path, n.prefix...)
+	if bounded {
+		c := bytes.Compare(path, from[:min(len(path), len(from))])
+		if c == 0 && len(path) > len(from) {
+			c = 1
+		}
+		if (!reverse && c < 0) || (reverse && c > 0) {
+			// The whole subtree is before from
+			return true
+		}
+		bounded = c == 0
+	}
+	// A key that is a prefix of from comes before it
+	visit := n.isKey && (!bounded || reverse || len(path) == len(from))
+
+	if !reverse && visit && !fn(path, n.value) {
+		return false
+	}
+	for i := range n.children {
+		if reverse {
+			i = len(n.children) - 1 - i
+		}
+		if !n.children[i].walk(path, from, bounded, reverse, fn) {
+			return false
+		}
+	}
+	if reverse && visit {
+		return fn(path, n.value)
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// raxKeys returns the keys of r in the order walk visits them from from
+func raxKeys(r *rax[int], from []byte, reverse bool) []string {
+	var keys []string
+	r.walk(from, reverse, func(key []byte, _ int) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	return keys
+}
+
+func Test_raxKeepsKeysInOrder(t *testing.T) {
+	r := newRax[int]()
+	want := map[string]int{}
+	for i := 0; i < 5000; i++ {
+		// Few letters make for long shared prefixes
+		key := make([]byte, 1+rand.IntN(6))
+		for j := range key {
+			key[j] = "abc"[rand.IntN(3)]
+		}
+		_, exists := want[string(key)]
+		if exists && i%2 == 0 {
+			if !r.remove(key) {
+				t.Fatalf("remove(%q) = false", key)
+			}
+			delete(want, string(key))
+			continue
+		}
+		if isNew := r.insert(key, i); isNew == exists {
+			t.Fatalf("insert(%q) = %v", key, isNew)
+		}
+		want[string(key)] = i
+	}
+
+	if r.size != len(want) {
+		t.Fatalf("size = %d, want %d", r.size, len(want))
+	}
+	var keys []string
+	for key, value := range want {
+		keys = append(keys, key)
+		if got, ok := r.find([]byte(key)); !ok || got != value {
+			t.Errorf("find(%q) = %d, %v, want %d", key, got, ok, value)
+		}
+	}
+	slices.Sort(keys)
+	if got := raxKeys(r, nil, false); !slices.Equal(got, keys) {
+		t.Errorf("walk() = %v, want %v", got, keys)
+	}
+	slices.Reverse(keys)
+	if got := raxKeys(r, nil, true); !slices.Equal(got, keys) {
+		t.Errorf("walk() in reverse = %v, want %v", got, keys)
+	}
+
+	for _, key := range keys {
+		r.remove([]byte(key))
+	}
+	if r.size != 0 || r.nodes != 1 {
+		t.Errorf("size and nodes of an emptied rax = %d, %d, want 0, 1", r.size, r.nodes)
+	}
+}
+
+func Test_raxWalk(t *testing.T) {
+	r := newRax[int]()
+	for _, key := range []string{"ab", "abc", "abd", "b", "ba", "c"} {
+		r.insert([]byte(key), 0)
+	}
+	tests := []struct {
+		name    string
+		from    string
+		reverse bool
+		want    string
+	}{
+		{
+			name: "It should start from a key that is there",
+			from: "abd",
+			want: "abd b ba c",
+		},
+		{
+			name: "It should start from the next key",
+			from: "abca",
+			want: "abd b ba c",
+		},
+		{
+			name: "It should skip keys that are a prefix of from",
+			from: "bb",
+			want: "c",
+		},
+		{
+			name:    "It should start from the previous key in reverse",
+			from:    "bb",
+			reverse: true,
+			want:    "ba b abd abc ab",
+		},
+		{
+			name:    "It should visit keys that are a prefix of from in reverse",
+			from:    "abcz",
+			reverse: true,
+			want:    "abc ab",
+		},
+		{
+			name: "It should find nothing past the last key",
+			from: "d",
+		},
+		{
+			name:    "It should find nothing before the first key in reverse",
+			from:    "a",
+			reverse: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(raxKeys(r, []byte(tt.from), tt.reverse), " "); got != tt.want {
+				t.Errorf("walk() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
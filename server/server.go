@@ -0,0 +1,288 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+// Config holds the settings of a Server
+type Config struct {
+	// Limits bounds the requests clients may send. Zero fields fall back
+	// to resp.DefaultLimits.
+	Limits resp.Limits
+	// Hz is how many times a second background tasks such as removing
+	// expired keys run. Zero means 10, like in Redis.
+	Hz int
+	// Timeout is how long a client may stay idle before the server closes
+	// its connection. Zero means clients are never timed out.
+	Timeout time.Duration
+	// Clock is where the server gets the time from. Nil means the system
+	// clock.
+	Clock Clock
+	// MaxOutputBuffer is how many bytes of replies may wait to be sent to
+	// a client before the server closes its connection, like the hard
+	// client-output-buffer-limit of Redis. Zero means no limit, as for the
+	// normal clients of Redis.
+	MaxOutputBuffer int
+}
+
+// Server serves RESP clients with the settings of its Config. Every client
+// of a Server shares its keyspace.
+type Server struct {
+	cfg   Config
+	clock Clock
+	db    *keyspace
+
+	// clients holds the clients connected to the server
+	clientsMu sync.Mutex
+	clients   map[*client]struct{}
+}
+
+// New returns a Server configured by cfg, with an empty keyspace
+func New(cfg Config) *Server {
+	clock := cfg.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Server{
+		cfg:     cfg,
+		clock:   clock,
+		db:      newKeyspace(clock),
+		clients: map[*client]struct{}{},
+	}
+}
+
+var defaultServer = New(Config{})
+
+// ListenAndServe listens on the TCP network address addr and serves
+// RESP clients with the default Config until the listener fails
+func ListenAndServe(addr string) error {
+	return defaultServer.ListenAndServe(addr)
+}
+
+// Serve serves the connections accepted on ln with the default Config
+func Serve(ln net.Listener) error {
+	return defaultServer.Serve(ln)
+}
+
+// ListenAndServe listens on the TCP network address addr and serves
+// RESP clients until the listener fails
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln and handles each of them on its own
+// goroutine, and runs the background tasks of the server while it does.
+// It always returns a non-nil error and closes ln.
+func (s *Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.cron(stop)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	out := &outputBuffer{conn: conn, limit: s.cfg.MaxOutputBuffer}
+	writer := resp.NewWriter(out)
+	in := &flushingReader{conn: conn, writer: writer, out: out, maxPending: maxPendingInput(s.cfg.Limits)}
+	reader := resp.NewReader(in)
+	reader.SetLimits(s.cfg.Limits)
+	c := newClient(s.db, writer)
+	c.conn = conn
+	c.in = in
+	s.addClient(c)
+	defer s.removeClient(c)
+	for {
+		command, err := readCommand(reader)
+		if err != nil {
+			var protocolErr *resp.ProtocolError
+			if errors.As(err, &protocolErr) {
+				// Let the client know why it is being disconnected
+				writer.WriteError("ERR " + protocolErr.Error())
+				_ = in.flush()
+			}
+			if err != io.EOF {
+				log.Printf("closing connection from %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			// The client may have only closed its write side, so it
+			// still gets the replies to everything it sent
+			if err := in.flush(); err != nil {
+				log.Printf("closing connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		c.touch(s.clock.Now())
+		handleCommand(c, command)
+	}
+}
+
+func (s *Server) addClient(c *client) {
+	c.touch(s.clock.Now())
+	s.clientsMu.Lock()
+	s.clients[c] = struct{}{}
+	s.clientsMu.Unlock()
+}
+
+func (s *Server) removeClient(c *client) {
+	s.clientsMu.Lock()
+	delete(s.clients, c)
+	s.clientsMu.Unlock()
+}
+
+// errQueryBufferLimit is why a blocked client that sends more than
+// flushingReader.maxPending is disconnected
+var errQueryBufferLimit = errors.New("query buffer limit reached while blocked")
+
+// maxPendingInput returns how much a blocked client may send before it is
+// disconnected, which is enough for a bulk string and a line as long as
+// limits allow
+func maxPendingInput(limits resp.Limits) int {
+	bulk, inline := limits.MaxBulkLen, limits.MaxInlineLen
+	if bulk <= 0 {
+		bulk = resp.DefaultLimits.MaxBulkLen
+	}
+	if inline <= 0 {
+		inline = resp.DefaultLimits.MaxInlineLen
+	}
+	return bulk + inline
+}
+
+// errOutputBufferLimit is why a client whose replies outgrow
+// Config.MaxOutputBuffer is disconnected
+var errOutputBufferLimit = errors.New("output buffer limit reached")
+
+// outputBufferKeep is the most memory an emptied outputBuffer keeps for
+// the next replies
+const outputBufferKeep = 64 * 1024
+
+// outputBuffer holds the replies to a client until they are sent. Commands
+// write their replies with the keyspace locked, so they must never wait
+// for a slow client to read them: they go to memory, and the socket is
+// only written once the lock is released.
+type outputBuffer struct {
+	conn net.Conn
+	buf  []byte
+	// limit is the most bytes buf may hold, or zero for no limit
+	limit int
+}
+
+func (o *outputBuffer) Write(p []byte) (int, error) {
+	if o.limit > 0 && len(o.buf)+len(p) > o.limit {
+		return 0, errOutputBufferLimit
+	}
+	o.buf = append(o.buf, p...)
+	return len(p), nil
+}
+
+// flush sends the buffered replies to the connection
+func (o *outputBuffer) flush() error {
+	if len(o.buf) == 0 {
+		return nil
+	}
+	_, err := o.conn.Write(o.buf)
+	if cap(o.buf) > outputBufferKeep {
+		o.buf = nil
+	} else {
+		o.buf = o.buf[:0]
+	}
+	return err
+}
+
+// flushingReader flushes pending replies before every read from the
+// connection. Commands that are already buffered are executed without
+// touching the socket, so replies to a pipeline go out in a single write
+// just before the server waits for more input.
+type flushingReader struct {
+	conn   net.Conn
+	writer *resp.Writer
+	out    *outputBuffer
+	// pending holds what watch read off the connection and err how the
+	// connection failed while it did, for the next calls to Read
+	pending []byte
+	err     error
+	// maxPending is how large pending may grow before the client is
+	// disconnected, as the commands in it are not parsed, and so not
+	// checked against resp.Limits, until the client is unblocked
+	maxPending int
+}
+
+func (r *flushingReader) Read(p []byte) (int, error) {
+	if len(r.pending) > 0 {
+		n := copy(p, r.pending)
+		r.pending = r.pending[n:]
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if err := r.flush(); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
+
+// flush sends the replies written so far to the connection. It must be
+// called without holding the lock of the keyspace.
+func (r *flushingReader) flush() error {
+	if err := r.writer.Flush(); err != nil {
+		return err
+	}
+	return r.out.flush()
+}
+
+// watch reads the connection in the background until stop is called, so
+// the server notices when a blocked client disconnects. What it reads is
+// kept for the next calls to Read. The returned channel is closed if the
+// connection fails.
+func (r *flushingReader) watch() (gone <-chan struct{}, stop func()) {
+	failed := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.conn.Read(buf)
+			r.pending = append(r.pending, buf[:n]...)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return
+			}
+			if err == nil && len(r.pending) > r.maxPending {
+				r.pending, err = nil, errQueryBufferLimit
+			}
+			if err != nil {
+				r.err = err
+				close(failed)
+				return
+			}
+		}
+	}()
+	return failed, func() {
+		// Interrupt the pending read
+		_ = r.conn.SetReadDeadline(time.Now())
+		<-done
+		_ = r.conn.SetReadDeadline(time.Time{})
+	}
+}
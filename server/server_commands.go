@@ -0,0 +1,434 @@
+package server
+
+import (
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name: "command", handler: commandCommand, arity: -1,
+		summary: "Returns detailed information about all commands.", since: "2.8.13", group: "server",
+		complexity: "O(N) where N is the total number of Redis commands",
+		subcommands: map[string]*command{
+			"count": {
+				name: "command|count", handler: commandCountCommand, arity: 2,
+				summary: "Returns a count of commands.", since: "2.8.13", group: "server", complexity: "O(1)",
+			},
+			"docs": {
+				name: "command|docs", handler: commandDocsCommand, arity: -2,
+				summary: "Returns documentary information about one, multiple or all commands.", since: "7.0.0", group: "server",
+				complexity: "O(N) where N is the number of commands to look up",
+			},
+			"getkeys": {
+				name: "command|getkeys", handler: commandGetKeysCommand, arity: -3,
+				summary: "Extracts the key names from an arbitrary command.", since: "2.8.13", group: "server",
+				complexity: "O(N) where N is the number of arguments to the command",
+			},
+			"help": {
+				name: "command|help", handler: commandHelpCommand, arity: 2,
+				summary: "Returns helpful text about the different subcommands.", since: "5.0.0", group: "server", complexity: "O(1)",
+			},
+			"info": {
+				name: "command|info", handler: commandInfoCommand, arity: -2,
+				summary: "Returns information about one, multiple or all commands.", since: "2.8.13", group: "server",
+				complexity: "O(N) where N is the number of commands to look up",
+			},
+			"list": {
+				name: "command|list", handler: commandListCommand, arity: -2,
+				summary: "Returns a list of command names.", since: "7.0.0", group: "server",
+				complexity: "O(N) where N is the total number of Redis commands",
+			},
+		},
+	})
+}
+
+// commandFlagNames lists the flags in the order Redis reports them
+var commandFlagNames = []struct {
+	flag commandFlag
+	name string
+}{
+	{flagWrite, "write"},
+	{flagReadonly, "readonly"},
+	{flagAdmin, "admin"},
+	{flagPubsub, "pubsub"},
+	{flagNoscript, "noscript"},
+	{flagBlocking, "blocking"},
+	{flagFast, "fast"},
+}
+
+// groupCategories maps the group of a command to the ACL category every
+// command of the group belongs to
+var groupCategories = map[string]string{
+	"connection": "@connection",
+	"generic":    "@keyspace",
+	"string":     "@string",
+	"list":       "@list",
+	"set":        "@set",
+	"hash":       "@hash",
+	"sorted-set": "@sortedset",
+	"stream":     "@stream",
+}
+
+// sortedCommands returns the top level commands ordered by name
+func sortedCommands() []*command {
+	sorted := make([]*command, 0, len(commands))
+	for _, cmd := range commands {
+		sorted = append(sorted, cmd)
+	}
+	sortCommands(sorted)
+	return sorted
+}
+
+// sortedSubcommands returns the subcommands of cmd ordered by name
+func sortedSubcommands(cmd *command) []*command {
+	sorted := make([]*command, 0, len(cmd.subcommands))
+	for _, sub := range cmd.subcommands {
+		sorted = append(sorted, sub)
+	}
+	sortCommands(sorted)
+	return sorted
+}
+
+func sortCommands(cmds []*command) {
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].name < cmds[j].name })
+}
+
+// aclCategories returns the ACL categories of cmd, which follow from its
+// flags and its group
+func aclCategories(cmd *command) []string {
+	var categories []string
+	if cmd.flags&flagWrite != 0 {
+		categories = append(categories, "@write")
+	}
+	if cmd.flags&flagReadonly != 0 {
+		categories = append(categories, "@read")
+	}
+	if cmd.flags&flagAdmin != 0 {
+		categories = append(categories, "@admin", "@dangerous")
+	}
+	if cmd.flags&flagPubsub != 0 {
+		categories = append(categories, "@pubsub")
+	}
+	if cmd.flags&flagFast != 0 {
+		categories = append(categories, "@fast")
+	} else {
+		categories = append(categories, "@slow")
+	}
+	if cmd.flags&flagBlocking != 0 {
+		categories = append(categories, "@blocking")
+	}
+	if category, ok := groupCategories[cmd.group]; ok {
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// writeCommandInfo writes the 10 element description of cmd that COMMAND
+// and COMMAND INFO reply with
+func writeCommandInfo(c *client, cmd *command) {
+	w := c.w
+	w.WriteArrayHeader(10)
+	w.WriteBulkString(cmd.name)
+	w.WriteInteger(int64(cmd.arity))
+
+	var flags []string
+	for _, f := range commandFlagNames {
+		if cmd.flags&f.flag != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+	if cmd.keyNumIndex != 0 {
+		// The positions of the keys depend on the arguments
+		flags = append(flags, "movablekeys")
+	}
+	w.WriteSetHeader(len(flags))
+	for _, flag := range flags {
+		w.WriteSimpleString(flag)
+	}
+
+	w.WriteInteger(int64(cmd.firstKey))
+	w.WriteInteger(int64(cmd.lastKey))
+	w.WriteInteger(int64(cmd.step))
+
+	categories := aclCategories(cmd)
+	w.WriteSetHeader(len(categories))
+	for _, category := range categories {
+		w.WriteSimpleString(category)
+	}
+
+	// No command has tips
+	w.WriteSetHeader(0)
+
+	writeKeySpecs(c, cmd)
+
+	subcommands := sortedSubcommands(cmd)
+	w.WriteArrayHeader(len(subcommands))
+	for _, sub := range subcommands {
+		writeCommandInfo(c, sub)
+	}
+}
+
+// writeKeySpecs writes the key specifications of cmd, which describe its
+// key positions the way Redis 7 does
+func writeKeySpecs(c *client, cmd *command) {
+	w := c.w
+	specs := 0
+	if cmd.firstKey != 0 {
+		specs++
+	}
+	if cmd.keyNumIndex != 0 {
+		specs++
+	}
+	w.WriteArrayHeader(specs)
+
+	if cmd.firstKey != 0 {
+		// The last key of a range is given relative to the first one
+		lastKey := cmd.lastKey
+		if lastKey >= 0 {
+			lastKey -= cmd.firstKey
+		}
+		writeKeySpec(c, cmd, cmd.firstKey)
+		w.WriteBulkString("range")
+		w.WriteBulkString("spec")
+		w.WriteMapHeader(3)
+		w.WriteBulkString("lastkey")
+		w.WriteInteger(int64(lastKey))
+		w.WriteBulkString("keystep")
+		w.WriteInteger(int64(cmd.step))
+		w.WriteBulkString("limit")
+		w.WriteInteger(0)
+	}
+	if cmd.keyNumIndex != 0 {
+		writeKeySpec(c, cmd, cmd.keyNumIndex)
+		w.WriteBulkString("keynum")
+		w.WriteBulkString("spec")
+		w.WriteMapHeader(3)
+		w.WriteBulkString("keynumidx")
+		w.WriteInteger(0)
+		w.WriteBulkString("firstkey")
+		w.WriteInteger(1)
+		w.WriteBulkString("keystep")
+		w.WriteInteger(1)
+	}
+}
+
+// writeKeySpec writes the start of a key specification of cmd whose search
+// begins at index, up to the type of its find_keys entry
+func writeKeySpec(c *client, cmd *command, index int) {
+	w := c.w
+	w.WriteMapHeader(3)
+	w.WriteBulkString("flags")
+	w.WriteSetHeader(2)
+	if cmd.flags&flagWrite != 0 {
+		w.WriteSimpleString("RW")
+		w.WriteSimpleString("update")
+	} else {
+		w.WriteSimpleString("RO")
+		w.WriteSimpleString("access")
+	}
+	w.WriteBulkString("begin_search")
+	w.WriteMapHeader(2)
+	w.WriteBulkString("type")
+	w.WriteBulkString("index")
+	w.WriteBulkString("spec")
+	w.WriteMapHeader(1)
+	w.WriteBulkString("index")
+	w.WriteInteger(int64(index))
+	w.WriteBulkString("find_keys")
+	w.WriteMapHeader(2)
+	w.WriteBulkString("type")
+}
+
+// writeCommandDocs writes the documentation map of cmd that COMMAND DOCS
+// replies with
+func writeCommandDocs(c *client, cmd *command) {
+	w := c.w
+	entries := 4
+	if cmd.subcommands != nil {
+		entries++
+	}
+	w.WriteMapHeader(entries)
+	w.WriteBulkString("summary")
+	w.WriteBulkString(cmd.summary)
+	w.WriteBulkString("since")
+	w.WriteBulkString(cmd.since)
+	w.WriteBulkString("group")
+	w.WriteBulkString(cmd.group)
+	w.WriteBulkString("complexity")
+	w.WriteBulkString(cmd.complexity)
+	if cmd.subcommands != nil {
+		w.WriteBulkString("subcommands")
+		subcommands := sortedSubcommands(cmd)
+		w.WriteMapHeader(len(subcommands))
+		for _, sub := range subcommands {
+			w.WriteBulkString(sub.name)
+			writeCommandDocs(c, sub)
+		}
+	}
+}
+
+// lookupCommandOrSubcommand finds a command by the name COMMAND INFO and
+// COMMAND DOCS accept, which is "container|subcommand" for subcommands
+func lookupCommandOrSubcommand(name []byte) *command {
+	container, sub, found := strings.Cut(strings.ToLower(string(name)), "|")
+	cmd := commands[container]
+	if cmd == nil || !found {
+		return cmd
+	}
+	return cmd.subcommands[sub]
+}
+
+// commandCommand implements COMMAND
+func commandCommand(c *client, args [][]byte) {
+	all := sortedCommands()
+	c.w.WriteArrayHeader(len(all))
+	for _, cmd := range all {
+		writeCommandInfo(c, cmd)
+	}
+}
+
+// commandCountCommand implements COMMAND COUNT
+func commandCountCommand(c *client, args [][]byte) {
+	c.w.WriteInteger(int64(len(commands)))
+}
+
+// commandInfoCommand implements COMMAND INFO [command-name ...]
+func commandInfoCommand(c *client, args [][]byte) {
+	if len(args) == 2 {
+		commandCommand(c, args)
+		return
+	}
+	c.w.WriteArrayHeader(len(args) - 2)
+	for _, name := range args[2:] {
+		cmd := lookupCommandOrSubcommand(name)
+		if cmd == nil {
+			c.w.WriteNull()
+			continue
+		}
+		writeCommandInfo(c, cmd)
+	}
+}
+
+// commandDocsCommand implements COMMAND DOCS [command-name ...]
+func commandDocsCommand(c *client, args [][]byte) {
+	var cmds []*command
+	if len(args) == 2 {
+		cmds = sortedCommands()
+	} else {
+		// Unknown names are left out of the reply
+		for _, name := range args[2:] {
+			if cmd := lookupCommandOrSubcommand(name); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	c.w.WriteMapHeader(len(cmds))
+	for _, cmd := range cmds {
+		c.w.WriteBulkString(cmd.name)
+		writeCommandDocs(c, cmd)
+	}
+}
+
+// commandListCommand implements
+// COMMAND LIST [FILTERBY MODULE module-name | ACLCAT category | PATTERN pattern]
+func commandListCommand(c *client, args [][]byte) {
+	filter := func(cmd *command) bool { return true }
+	switch {
+	case len(args) == 2:
+	case len(args) == 5 && strings.EqualFold(string(args[2]), "FILTERBY"):
+		value := args[4]
+		switch strings.ToLower(string(args[3])) {
+		case "module":
+			// There are no modules, so no command belongs to one
+			filter = func(cmd *command) bool { return false }
+		case "aclcat":
+			category := "@" + strings.ToLower(string(value))
+			filter = func(cmd *command) bool {
+				for _, cat := range aclCategories(cmd) {
+					if cat == category {
+						return true
+					}
+				}
+				return false
+			}
+		case "pattern":
+			filter = func(cmd *command) bool { return stringMatch(value, []byte(cmd.name), true) }
+		default:
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+	default:
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+
+	var names []string
+	for _, cmd := range sortedCommands() {
+		if filter(cmd) {
+			names = append(names, cmd.name)
+		}
+		for _, sub := range sortedSubcommands(cmd) {
+			if filter(sub) {
+				names = append(names, sub.name)
+			}
+		}
+	}
+	c.w.WriteArrayHeader(len(names))
+	for _, name := range names {
+		c.w.WriteBulkString(name)
+	}
+}
+
+// commandGetKeysCommand implements COMMAND GETKEYS command [arg ...]
+func commandGetKeysCommand(c *client, args [][]byte) {
+	cmdArgs := args[2:]
+	cmd, _ := resolveCommand(cmdArgs)
+	if cmd == nil {
+		c.w.WriteError("ERR Invalid command specified")
+		return
+	}
+	if !cmd.checkArity(len(cmdArgs)) {
+		c.w.WriteError("ERR Invalid number of arguments specified for command")
+		return
+	}
+	positions := cmd.keyPositions(cmdArgs)
+	if len(positions) == 0 {
+		c.w.WriteError("ERR The command has no key arguments")
+		return
+	}
+	c.w.WriteArrayHeader(len(positions))
+	for _, i := range positions {
+		c.w.WriteBulk(cmdArgs[i])
+	}
+}
+
+var commandHelp = []string{
+	"COMMAND <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"(no subcommand)",
+	"    Return details about all Redis commands.",
+	"COUNT",
+	"    Return the total number of commands in this Redis server.",
+	"INFO [<command-name> ...]",
+	"    Return details about multiple Redis commands.",
+	"    If no command names are given, documentation details for all",
+	"    commands are returned.",
+	"DOCS [<command-name> ...]",
+	"    Return documentation details about multiple Redis commands.",
+	"    If no command names are given, documentation details for all",
+	"    commands are returned.",
+	"GETKEYS <full-command>",
+	"    Return the keys from a full Redis command.",
+	"LIST [FILTERBY (MODULE <module-name>|ACLCAT <category>|PATTERN <pattern>)]",
+	"    Return a list of all commands in this Redis server.",
+	"HELP",
+	"    Print this help.",
+}
+
+// commandHelpCommand implements COMMAND HELP
+func commandHelpCommand(c *client, args [][]byte) {
+	c.w.WriteArrayHeader(len(commandHelp))
+	for _, line := range commandHelp {
+		c.w.WriteSimpleString(line)
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+func Test_CommandSubcommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should count the top level commands",
+			input: "COMMAND COUNT\r\n",
+			want:  ":" + strconv.Itoa(len(commands)) + "\r\n",
+		},
+		{
+			name:  "It should describe a command",
+			input: "COMMAND INFO echo\r\n",
+			want: "*1\r\n*10\r\n$4\r\necho\r\n:2\r\n*1\r\n+fast\r\n:0\r\n:0\r\n:0\r\n" +
+				"*2\r\n+@fast\r\n+@connection\r\n*0\r\n*0\r\n*0\r\n",
+		},
+		{
+			name:  "It should describe a subcommand",
+			input: "COMMAND INFO COMMAND|COUNT\r\n",
+			want:  "*1\r\n*10\r\n$13\r\ncommand|count\r\n:2\r\n*0\r\n:0\r\n:0\r\n:0\r\n*1\r\n+@slow\r\n*0\r\n*0\r\n*0\r\n",
+		},
+		{
+			name:  "It should reply with a null for unknown commands",
+			input: "COMMAND INFO nope\r\n",
+			want:  "*1\r\n$-1\r\n",
+		},
+		{
+			name:  "It should list commands matching a pattern",
+			input: "COMMAND LIST FILTERBY PATTERN command|*\r\n",
+			want: "*6\r\n$13\r\ncommand|count\r\n$12\r\ncommand|docs\r\n$15\r\ncommand|getkeys\r\n" +
+				"$12\r\ncommand|help\r\n$12\r\ncommand|info\r\n$12\r\ncommand|list\r\n",
+		},
+		{
+			name:  "It should list commands in an ACL category",
+			input: "COMMAND LIST FILTERBY ACLCAT connection\r\n",
+			want:  "*3\r\n$4\r\necho\r\n$5\r\nhello\r\n$4\r\nping\r\n",
+		},
+		{
+			name:  "It should list no commands for a module",
+			input: "COMMAND LIST FILTERBY MODULE search\r\n",
+			want:  "*0\r\n",
+		},
+		{
+			name:  "It should reject an unknown filter",
+			input: "COMMAND LIST FILTERBY COLOR red\r\n",
+			want:  "-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should document a command",
+			input: "HELLO 3\r\nCOMMAND DOCS ping nope\r\n",
+			want: "%1\r\n$4\r\nping\r\n%4\r\n$7\r\nsummary\r\n$41\r\nReturns the server's liveliness response.\r\n" +
+				"$5\r\nsince\r\n$5\r\n1.0.0\r\n$5\r\ngroup\r\n$10\r\nconnection\r\n$10\r\ncomplexity\r\n$4\r\nO(1)\r\n",
+		},
+		{
+			name:  "It should reject GETKEYS for commands without keys",
+			input: "COMMAND GETKEYS ECHO hi\r\n",
+			want:  "-ERR The command has no key arguments\r\n",
+		},
+		{
+			name:  "It should reject GETKEYS for unknown commands",
+			input: "COMMAND GETKEYS NOPE hi\r\n",
+			want:  "-ERR Invalid command specified\r\n",
+		},
+		{
+			name:  "It should reject GETKEYS with the wrong number of arguments",
+			input: "COMMAND GETKEYS ECHO\r\n",
+			want:  "-ERR Invalid number of arguments specified for command\r\n",
+		},
+		{
+			name:  "It should reject unknown subcommands",
+			input: "COMMAND NOPE\r\n",
+			want:  "-ERR unknown subcommand 'NOPE'. Try COMMAND HELP.\r\n",
+		},
+		{
+			name:  "It should check the arity of subcommands",
+			input: "COMMAND COUNT 1\r\n",
+			want:  "-ERR wrong number of arguments for 'command|count' command\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HandleRequest(tt.input)
+			// HELLO is only there to switch protocols, so skip its reply
+			if strings.HasPrefix(tt.input, "HELLO 3\r\n") {
+				value, err := resp.Deserialize(got)
+				if err != nil {
+					t.Fatalf("Deserialize() error = %v", err)
+				}
+				got = got[len(resp.Serialize(value)):]
+			}
+			if got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CommandGetKeys(t *testing.T) {
+	commands["mtest"] = &command{name: "mtest", handler: pingCommand, arity: -3, firstKey: 1, lastKey: -1, step: 2}
+	defer delete(commands, "mtest")
+
+	want := "*2\r\n$2\r\nk1\r\n$2\r\nk2\r\n"
+	if got := HandleRequest("COMMAND GETKEYS mtest k1 v1 k2 v2\r\n"); got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+
+	info, err := resp.Deserialize(HandleRequest("COMMAND INFO mtest\r\n"))
+	if err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	keySpecs := info.Elems()[0].Elems()[8]
+	want = "*1\r\n*6\r\n$5\r\nflags\r\n*2\r\n+RO\r\n+access\r\n" +
+		"$12\r\nbegin_search\r\n*4\r\n$4\r\ntype\r\n$5\r\nindex\r\n$4\r\nspec\r\n*2\r\n$5\r\nindex\r\n:1\r\n" +
+		"$9\r\nfind_keys\r\n*4\r\n$4\r\ntype\r\n$5\r\nrange\r\n$4\r\nspec\r\n" +
+		"*6\r\n$7\r\nlastkey\r\n:-1\r\n$7\r\nkeystep\r\n:2\r\n$5\r\nlimit\r\n:0\r\n"
+	if got := resp.Serialize(keySpecs); got != want {
+		t.Errorf("key specs = %q, want %q", got, want)
+	}
+}
+
+func Test_CommandGetKeysWithNumkeys(t *testing.T) {
+	want := "*2\r\n$1\r\na\r\n$1\r\nb\r\n"
+	if got := HandleRequest("COMMAND GETKEYS LMPOP 2 a b LEFT\r\n"); got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+
+	info, err := resp.Deserialize(HandleRequest("COMMAND INFO blmpop\r\n"))
+	if err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	fields := info.Elems()[0].Elems()
+	want = "*3\r\n+write\r\n+blocking\r\n+movablekeys\r\n"
+	if got := resp.Serialize(fields[2]); got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+	want = "*1\r\n*6\r\n$5\r\nflags\r\n*2\r\n+RW\r\n+update\r\n" +
+		"$12\r\nbegin_search\r\n*4\r\n$4\r\ntype\r\n$5\r\nindex\r\n$4\r\nspec\r\n*2\r\n$5\r\nindex\r\n:2\r\n" +
+		"$9\r\nfind_keys\r\n*4\r\n$4\r\ntype\r\n$6\r\nkeynum\r\n$4\r\nspec\r\n" +
+		"*6\r\n$9\r\nkeynumidx\r\n:0\r\n$8\r\nfirstkey\r\n:1\r\n$7\r\nkeystep\r\n:1\r\n"
+	if got := resp.Serialize(fields[8]); got != want {
+		t.Errorf("key specs = %q, want %q", got, want)
+	}
+}
+
+func Test_CommandDescribesEveryCommand(t *testing.T) {
+	for _, proto := range []string{"2", "3"} {
+		reply := HandleRequest("HELLO " + proto + "\r\nCOMMAND\r\n")
+		hello, err := resp.Deserialize(reply)
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		all, err := resp.Deserialize(reply[len(resp.Serialize(hello)):])
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		if len(all.Elems()) != len(commands) {
+			t.Errorf("COMMAND returned %d commands, want %d", len(all.Elems()), len(commands))
+		}
+		for _, info := range all.Elems() {
+			if len(info.Elems()) != 10 {
+				t.Errorf("COMMAND entry for %s has %d elements, want 10", info.Elems()[0].Str(), len(info.Elems()))
+			}
+			name := info.Elems()[0].Str()
+			if cmd := commands[name]; cmd == nil || info.Elems()[1].Int() != int64(cmd.arity) {
+				t.Errorf("COMMAND entry for %s does not match the command table", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	return startConfiguredServer(t, Config{})
+}
+
+func startConfiguredServer(t *testing.T, cfg Config) string {
+	t.Helper()
+	_, addr := startServer(t, cfg)
+	return addr
+}
+
+// startServer serves a new Server configured by cfg on a local port, and
+// returns it with its address
+func startServer(t *testing.T, cfg Config) (*Server, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	srv := New(cfg)
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return srv, ln.Addr().String()
+}
+
+func dialTestServer(t *testing.T, addr string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// expectReply reads exactly len(want) bytes off conn and compares them
+func expectReply(t *testing.T, conn net.Conn, want string) {
+	t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("reading reply error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got reply %q, want %q", got, want)
+	}
+}
+
+func send(t *testing.T, conn net.Conn, input string) {
+	t.Helper()
+	if _, err := io.WriteString(conn, input); err != nil {
+		t.Fatalf("write error = %v", err)
+	}
+}
+
+func Test_Serve(t *testing.T) {
+	addr := startTestServer(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should reply to PING",
+			input: "*1\r\n$4\r\nPING\r\n",
+			want:  "+PONG\r\n",
+		},
+		{
+			name:  "It should reply to ECHO",
+			input: "*2\r\n$4\r\nECHO\r\n$5\r\nhello\r\n",
+			want:  "$5\r\nhello\r\n",
+		},
+		{
+			name:  "It should ECHO binary data unchanged",
+			input: "*2\r\n$4\r\nECHO\r\n$6\r\n\x00\r\n\xff\xfe\x00\r\n",
+			want:  "$6\r\n\x00\r\n\xff\xfe\x00\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialTestServer(t, addr)
+			send(t, conn, tt.input)
+			expectReply(t, conn, tt.want)
+		})
+	}
+}
+
+func Test_ServeConcurrentConnections(t *testing.T) {
+	addr := startTestServer(t)
+	first := dialTestServer(t, addr)
+	second := dialTestServer(t, addr)
+
+	// Send half a command on the first connection; the second one must
+	// still be served while the first is waiting for the rest
+	send(t, first, "*2\r\n$4\r\nECHO\r\n")
+	send(t, second, "*1\r\n$4\r\nPING\r\n")
+	expectReply(t, second, "+PONG\r\n")
+
+	send(t, first, "$3\r\nhey\r\n")
+	expectReply(t, first, "$3\r\nhey\r\n")
+}
+
+// pushLargeList fills the list l of srv with about size bytes
+func pushLargeList(srv *Server, size int) {
+	push := "*1001\r\n$5\r\nRPUSH\r\n$1\r\nl\r\n" + strings.Repeat("$100\r\n"+strings.Repeat("v", 100)+"\r\n", 999)
+	for i := 0; i < size/100/1000; i++ {
+		srv.HandleRequest(push)
+	}
+}
+
+func Test_ServeWhileAClientDoesNotRead(t *testing.T) {
+	srv, addr := startServer(t, Config{})
+	pushLargeList(srv, 32<<20)
+	slow := dialTestServer(t, addr)
+	other := dialTestServer(t, addr)
+
+	// The reply is far larger than the socket buffers, and nobody reads it
+	send(t, slow, "LRANGE l 0 -1\r\n")
+	time.Sleep(100 * time.Millisecond)
+
+	_ = other.SetReadDeadline(time.Now().Add(3 * time.Second))
+	send(t, other, "PING\r\n")
+	expectReply(t, other, "+PONG\r\n")
+}
+
+func Test_ServeDisconnectsClientsOverTheOutputBufferLimit(t *testing.T) {
+	srv, addr := startServer(t, Config{MaxOutputBuffer: 1 << 20})
+	pushLargeList(srv, 2<<20)
+	conn := dialTestServer(t, addr)
+
+	send(t, conn, "PING\r\nLRANGE l 0 -1\r\n")
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil || len(got) != 0 {
+		t.Errorf("reading from the client = %q, %v, want the connection closed", got, err)
+	}
+
+	conn = dialTestServer(t, addr)
+	send(t, conn, "LRANGE l 0 0\r\n")
+	expectReply(t, conn, "*1\r\n$100\r\n"+strings.Repeat("v", 100)+"\r\n")
+}
+
+func Test_ServePipelinedCommands(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dialTestServer(t, addr)
+
+	send(t, conn, "*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$1\r\na\r\n*2\r\n$4\r\nECHO\r\n$1\r\nb\r\n")
+	expectReply(t, conn, "+PONG\r\n$1\r\na\r\n$1\r\nb\r\n")
+}
+
+// recordingConn is a net.Conn that serves a fixed input and records every
+// write made to it
+type recordingConn struct {
+	net.Conn
+	input  *strings.Reader
+	writes []string
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	return c.input.Read(p)
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.writes = append(c.writes, string(p))
+	return len(p), nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func Test_handleConnectionBatchesReplies(t *testing.T) {
+	conn := &recordingConn{
+		input: strings.NewReader("*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\n"),
+	}
+	defaultServer.handleConnection(conn)
+
+	want := []string{"+PONG\r\n+PONG\r\n$2\r\nhi\r\n"}
+	if !reflect.DeepEqual(conn.writes, want) {
+		t.Errorf("handleConnection() wrote %q, want %q", conn.writes, want)
+	}
+}
+
+func Test_HandleRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should handle a single command",
+			input: "*1\r\n$4\r\nPING\r\n",
+			want:  "+PONG\r\n",
+		},
+		{
+			name:  "It should handle several commands in order",
+			input: "*2\r\n$4\r\nECHO\r\n$3\r\none\r\n*2\r\n$4\r\nECHO\r\n$3\r\ntwo\r\n",
+			want:  "$3\r\none\r\n$3\r\ntwo\r\n",
+		},
+		{
+			name:  "It should report unknown commands",
+			input: "*1\r\n$4\r\nNOPE\r\n",
+			want:  "-ERR unknown command 'NOPE', with args beginning with: \r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ServeInlineCommands(t *testing.T) {
+	addr := startTestServer(t)
+	conn := dialTestServer(t, addr)
+
+	send(t, conn, "PING\r\n\r\nECHO \"hello world\"\nECHO *\r\n")
+	expectReply(t, conn, "+PONG\r\n$11\r\nhello world\r\n$1\r\n*\r\n")
+
+	send(t, conn, "ECHO \"oops\r\n")
+	expectReply(t, conn, "-ERR Protocol error: unbalanced quotes in request\r\n")
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("connection still open after a protocol error, read error = %v", err)
+	}
+}
+
+func Test_Hello(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantPrefix string
+	}{
+		{
+			name:       "It should reply with a flat array under RESP2",
+			input:      "HELLO\r\n",
+			wantPrefix: "*14\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.4.0\r\n$5\r\nproto\r\n:2\r\n",
+		},
+		{
+			name:       "It should switch to RESP3 and reply with a map",
+			input:      "HELLO 3\r\n",
+			wantPrefix: "%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.4.0\r\n$5\r\nproto\r\n:3\r\n",
+		},
+		{
+			name:       "It should accept AUTH for the default user and SETNAME",
+			input:      "HELLO 3 AUTH default secret SETNAME worker-1\r\n",
+			wantPrefix: "%7\r\n",
+		},
+		{
+			name:       "It should reject unknown protocol versions",
+			input:      "HELLO 4\r\n",
+			wantPrefix: "-NOPROTO unsupported protocol version\r\n",
+		},
+		{
+			name:       "It should reject a protocol version that is not a number",
+			input:      "HELLO three\r\n",
+			wantPrefix: "-ERR Protocol version is not an integer or out of range\r\n",
+		},
+		{
+			name:       "It should reject unknown users",
+			input:      "HELLO 3 AUTH admin secret\r\n",
+			wantPrefix: "-WRONGPASS invalid username-password pair or user is disabled.\r\n",
+		},
+		{
+			name:       "It should reject unknown options",
+			input:      "HELLO 3 NOPE\r\n",
+			wantPrefix: "-ERR Syntax error in HELLO option 'NOPE'\r\n",
+		},
+		{
+			name:       "It should not let an option forge a reply",
+			input:      "*3\r\n$5\r\nHELLO\r\n$1\r\n2\r\n$8\r\nfo\r\n+bar\r\n",
+			wantPrefix: "-ERR Syntax error in HELLO option 'fo  +bar'\r\n",
+		},
+		{
+			name:       "It should keep the protocol after a failed HELLO",
+			input:      "HELLO 3\r\nHELLO 4\r\nHELLO\r\n",
+			wantPrefix: "%7\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HandleRequest(tt.input)
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("HandleRequest() = %q, want prefix %q", got, tt.wantPrefix)
+			}
+		})
+	}
+
+	// The last HELLO must still see protocol 3
+	got := HandleRequest("HELLO 3\r\nHELLO 4\r\nHELLO\r\n")
+	if strings.Count(got, ":3\r\n") != 2 {
+		t.Errorf("HandleRequest() = %q, want both successful HELLO replies to use RESP3", got)
+	}
+}
+
+func Test_ServeEnforcesLimits(t *testing.T) {
+	addr := startConfiguredServer(t, Config{Limits: resp.Limits{
+		MaxBulkLen:      16,
+		MaxMultibulkLen: 4,
+		MaxDepth:        1,
+		MaxInlineLen:    32,
+	}})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should reject a huge multibulk length without allocating it",
+			input: "*2147483647\r\n",
+			want:  "-ERR Protocol error: invalid multibulk length\r\n",
+		},
+		{
+			name:  "It should reject a multibulk length over the limit",
+			input: "*5\r\n",
+			want:  "-ERR Protocol error: invalid multibulk length\r\n",
+		},
+		{
+			name:  "It should reject a bulk length over the limit",
+			input: "*2\r\n$4\r\nECHO\r\n$17\r\n",
+			want:  "-ERR Protocol error: invalid bulk length\r\n",
+		},
+		{
+			name:  "It should reject nested arrays deeper than the limit",
+			input: "*1\r\n*1\r\n$4\r\nPING\r\n",
+			want:  "-ERR Protocol error: too deeply nested\r\n",
+		},
+		{
+			name:  "It should reject an inline command over the limit",
+			input: "ECHO " + strings.Repeat("a", 40) + "\r\n",
+			want:  "-ERR Protocol error: too big inline request\r\n",
+		},
+		{
+			name:  "It should reject a header line over the limit",
+			input: "*" + strings.Repeat("1", 40) + "\r\n",
+			want:  "-ERR Protocol error: too big mbulk count string\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialTestServer(t, addr)
+			send(t, conn, tt.input)
+			expectReply(t, conn, tt.want)
+			// The connection is closed after a protocol error
+			if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+				t.Errorf("read after protocol error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func Test_ServeAcceptsRequestsWithinLimits(t *testing.T) {
+	addr := startConfiguredServer(t, Config{Limits: resp.Limits{MaxBulkLen: 16, MaxInlineLen: 32}})
+	conn := dialTestServer(t, addr)
+
+	send(t, conn, "*2\r\n$4\r\nECHO\r\n$16\r\n"+strings.Repeat("b", 16)+"\r\n")
+	expectReply(t, conn, "$16\r\n"+strings.Repeat("b", 16)+"\r\n")
+	send(t, conn, "ECHO "+strings.Repeat("c", 27)+"\r\n")
+	expectReply(t, conn, "$27\r\n"+strings.Repeat("c", 27)+"\r\n")
+}
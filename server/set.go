@@ -0,0 +1,142 @@
+package server
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+)
+
+// setMaxIntsetEntries is the most members a set keeps in an intset, like
+// the set-max-intset-entries setting of Redis
+const setMaxIntsetEntries = 512
+
+// set is the set type. Like in Redis, a small set whose members are all
+// integers is stored as an intset, a sorted slice of integers that takes a
+// fraction of the memory of a map, and it is converted to a hash table once
+// it grows past setMaxIntsetEntries or gets a member that is not an
+// integer.
+type set struct {
+	// ints holds the members while dict is nil
+	ints []int64
+	dict map[string]struct{}
+}
+
+func newSet() *set {
+	return &set{}
+}
+
+// isIntset reports whether s is stored as an intset
+func (s *set) isIntset() bool {
+	return s.dict == nil
+}
+
+func (s *set) len() int {
+	if s.isIntset() {
+		return len(s.ints)
+	}
+	return len(s.dict)
+}
+
+// add adds member to s, and reports whether it was not a member already
+func (s *set) add(member string) bool {
+	if s.isIntset() {
+		if n, ok := parseInt([]byte(member)); ok {
+			i, found := slices.BinarySearch(s.ints, n)
+			if found {
+				return false
+			}
+			if len(s.ints) < setMaxIntsetEntries {
+				s.ints = slices.Insert(s.ints, i, n)
+				return true
+			}
+		}
+		s.convertToDict()
+	}
+	if _, ok := s.dict[member]; ok {
+		return false
+	}
+	s.dict[member] = struct{}{}
+	return true
+}
+
+// remove removes member from s, and reports whether it was a member
+func (s *set) remove(member string) bool {
+	if s.isIntset() {
+		n, ok := parseInt([]byte(member))
+		if !ok {
+			return false
+		}
+		i, found := slices.BinarySearch(s.ints, n)
+		if found {
+			s.ints = slices.Delete(s.ints, i, i+1)
+		}
+		return found
+	}
+	if _, ok := s.dict[member]; !ok {
+		return false
+	}
+	delete(s.dict, member)
+	return true
+}
+
+// contains reports whether member is a member of s
+func (s *set) contains(member string) bool {
+	if s.isIntset() {
+		n, ok := parseInt([]byte(member))
+		if !ok {
+			return false
+		}
+		_, found := slices.BinarySearch(s.ints, n)
+		return found
+	}
+	_, ok := s.dict[member]
+	return ok
+}
+
+// forEach calls fn with every member of s until it returns false. Members
+// of an intset come in ascending order.
+func (s *set) forEach(fn func(member string) bool) {
+	if s.isIntset() {
+		for _, n := range s.ints {
+			if !fn(strconv.FormatInt(n, 10)) {
+				return
+			}
+		}
+		return
+	}
+	for member := range s.dict {
+		if !fn(member) {
+			return
+		}
+	}
+}
+
+// members returns the members of s
+func (s *set) members() []string {
+	members := make([]string, 0, s.len())
+	s.forEach(func(member string) bool {
+		members = append(members, member)
+		return true
+	})
+	return members
+}
+
+// randomMember returns a random member of s, which must not be empty
+func (s *set) randomMember() string {
+	if s.isIntset() {
+		return strconv.FormatInt(s.ints[rand.IntN(len(s.ints))], 10)
+	}
+	// Map iteration starts at a random place
+	for member := range s.dict {
+		return member
+	}
+	return ""
+}
+
+func (s *set) convertToDict() {
+	s.dict = make(map[string]struct{}, len(s.ints)+1)
+	for _, n := range s.ints {
+		s.dict[strconv.FormatInt(n, 10)] = struct{}{}
+	}
+	s.ints = nil
+}
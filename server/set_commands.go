@@ -0,0 +1,578 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+var (
+	errTooManyKeys   = errors.New("ERR Number of keys can't be greater than number of args")
+	errLimitNegative = errors.New("ERR LIMIT can't be negative")
+	// errRandomCountRange is the error for a negative count too large to
+	// reply with
+	errRandomCountRange = errors.New("ERR value is out of range")
+)
+
+func init() {
+	registerCommand(&command{
+		name: "sadd", handler: saddCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Adds one or more members to a set. Creates the key if it doesn't exist.", since: "1.0.0", group: "set",
+		complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+	})
+	registerCommand(&command{
+		name: "srem", handler: sremCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes one or more members from a set. Deletes the set if the last member was removed.", since: "1.0.0",
+		group: "set", complexity: "O(N) where N is the number of members to be removed.",
+	})
+	registerCommand(&command{
+		name: "smembers", handler: smembersCommand, arity: 2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns all members of a set.", since: "1.0.0", group: "set", complexity: "O(N) where N is the set cardinality.",
+	})
+	registerCommand(&command{
+		name: "sismember", handler: sismemberCommand, arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Determines whether a member belongs to a set.", since: "1.0.0", group: "set", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "smismember", handler: smismemberCommand, arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Determines whether multiple members belong to a set.", since: "6.2.0", group: "set",
+		complexity: "O(N) where N is the number of elements being checked for membership",
+	})
+	registerCommand(&command{
+		name: "scard", handler: scardCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the number of members in a set.", since: "1.0.0", group: "set", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "spop", handler: spopCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.",
+		since:   "1.0.0", group: "set", complexity: "Without the count argument O(1), otherwise O(N) where N is the value of the passed count.",
+	})
+	registerCommand(&command{
+		name: "srandmember", handler: srandmemberCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Get one or multiple random members from a set", since: "1.0.0", group: "set",
+		complexity: "Without the count argument O(1), otherwise O(N) where N is the absolute value of the passed count.",
+	})
+	registerCommand(&command{
+		name: "smove", handler: smoveCommand, arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Moves a member from one set to another.", since: "1.0.0", group: "set", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "sinter", handler: sinterCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Returns the intersect of multiple sets.", since: "1.0.0", group: "set",
+		complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	})
+	registerCommand(&command{
+		name: "sintercard", handler: sintercardCommand, arity: -3, flags: flagReadonly, keyNumIndex: 1,
+		summary: "Returns the number of members of the intersect of multiple sets.", since: "7.0.0", group: "set",
+		complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	})
+	registerCommand(&command{
+		name: "sinterstore", handler: sinterstoreCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Stores the intersect of multiple sets in a key.", since: "1.0.0", group: "set",
+		complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+	})
+	registerCommand(&command{
+		name: "sunion", handler: sunionCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Returns the union of multiple sets.", since: "1.0.0", group: "set",
+		complexity: "O(N) where N is the total number of elements in all given sets.",
+	})
+	registerCommand(&command{
+		name: "sunionstore", handler: sunionstoreCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Stores the union of multiple sets in a key.", since: "1.0.0", group: "set",
+		complexity: "O(N) where N is the total number of elements in all given sets.",
+	})
+	registerCommand(&command{
+		name: "sdiff", handler: sdiffCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Returns the difference of multiple sets.", since: "1.0.0", group: "set",
+		complexity: "O(N) where N is the total number of elements in all given sets.",
+	})
+	registerCommand(&command{
+		name: "sdiffstore", handler: sdiffstoreCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Stores the difference of multiple sets in a key.", since: "1.0.0", group: "set",
+		complexity: "O(N) where N is the total number of elements in all given sets.",
+	})
+}
+
+// getSet returns the set stored at key, or nil if there is none. It
+// returns errWrongType if key holds a value of another type.
+func (ks *keyspace) getSet(key string) (*set, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	s, ok := value.(*set)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// writeMembers replies with members as a set
+func writeMembers(c *client, members []string) {
+	c.w.WriteSetHeader(len(members))
+	for _, member := range members {
+		c.w.WriteBulkString(member)
+	}
+}
+
+// saddCommand implements SADD key member [member ...]
+func saddCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	s, err := c.db.getSet(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		s = newSet()
+		c.db.set(key, s, false)
+	}
+	var added int64
+	for _, member := range args[2:] {
+		if s.add(string(member)) {
+			added++
+		}
+	}
+	c.w.WriteInteger(added)
+}
+
+// sremCommand implements SREM key member [member ...]
+func sremCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	s, err := c.db.getSet(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	var removed int64
+	for _, member := range args[2:] {
+		if s.remove(string(member)) {
+			removed++
+		}
+	}
+	if s.len() == 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteInteger(removed)
+}
+
+// smembersCommand implements SMEMBERS key
+func smembersCommand(c *client, args [][]byte) {
+	s, err := c.db.getSet(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		s = newSet()
+	}
+	writeMembers(c, s.members())
+}
+
+// sismemberCommand implements SISMEMBER key member
+func sismemberCommand(c *client, args [][]byte) {
+	s, err := c.db.getSet(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s != nil && s.contains(string(args[2])) {
+		c.w.WriteInteger(1)
+		return
+	}
+	c.w.WriteInteger(0)
+}
+
+// smismemberCommand implements SMISMEMBER key member [member ...]
+func smismemberCommand(c *client, args [][]byte) {
+	s, err := c.db.getSet(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteArrayHeader(len(args) - 2)
+	for _, member := range args[2:] {
+		if s != nil && s.contains(string(member)) {
+			c.w.WriteInteger(1)
+		} else {
+			c.w.WriteInteger(0)
+		}
+	}
+}
+
+// scardCommand implements SCARD key
+func scardCommand(c *client, args [][]byte) {
+	s, err := c.db.getSet(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(s.len()))
+}
+
+// spopCommand implements SPOP key [count]
+func spopCommand(c *client, args [][]byte) {
+	if len(args) > 3 {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	hasCount := len(args) == 3
+	var count int64
+	if hasCount {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok || count < 0 {
+			c.w.WriteError(errNotPositive.Error())
+			return
+		}
+	}
+
+	key := string(args[1])
+	s, err := c.db.getSet(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		if hasCount {
+			c.w.WriteSetHeader(0)
+		} else {
+			c.w.WriteNull()
+		}
+		return
+	}
+
+	if !hasCount {
+		member := s.randomMember()
+		s.remove(member)
+		if s.len() == 0 {
+			c.db.remove(key)
+		}
+		c.w.WriteBulkString(member)
+		return
+	}
+	if count >= int64(s.len()) {
+		// Popping everything is deleting the set
+		members := s.members()
+		c.db.remove(key)
+		writeMembers(c, members)
+		return
+	}
+	popped := randomMembers(s, int(count))
+	for _, member := range popped {
+		s.remove(member)
+	}
+	writeMembers(c, popped)
+}
+
+// randomMembers returns count distinct random members of s, which must
+// have at least count members
+func randomMembers(s *set, count int) []string {
+	members := s.members()
+	// A partial Fisher-Yates shuffle picks the first count members
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:count]
+}
+
+// parseRandomCount parses the count of SRANDMEMBER, HRANDFIELD and
+// ZRANDMEMBER. Like Redis, it refuses negative counts below -LONG_MAX/2,
+// whose replies could never be written.
+func parseRandomCount(b []byte) (int64, error) {
+	count, ok := parseInt(b)
+	switch {
+	case !ok:
+		return 0, errNotInteger
+	case count == math.MinInt64:
+		return 0, errors.New("ERR value is out of range, must be between -9223372036854775807 and 9223372036854775807")
+	case count < -math.MaxInt64/2:
+		return 0, errRandomCountRange
+	}
+	return count, nil
+}
+
+// srandmemberCommand implements SRANDMEMBER key [count]. A negative count
+// may return the same member several times.
+func srandmemberCommand(c *client, args [][]byte) {
+	if len(args) > 3 {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	hasCount := len(args) == 3
+	var count int64
+	if hasCount {
+		var err error
+		if count, err = parseRandomCount(args[2]); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	s, err := c.db.getSet(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !hasCount {
+		if s == nil {
+			c.w.WriteNull()
+			return
+		}
+		c.w.WriteBulkString(s.randomMember())
+		return
+	}
+	if s == nil || count == 0 {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+
+	if count < 0 {
+		members := s.members()
+		c.w.WriteArrayHeader(int(-count))
+		for i := int64(0); i < -count; i++ {
+			c.w.WriteBulkString(members[rand.IntN(len(members))])
+		}
+		return
+	}
+	if count >= int64(s.len()) {
+		members := s.members()
+		c.w.WriteArrayHeader(len(members))
+		for _, member := range members {
+			c.w.WriteBulkString(member)
+		}
+		return
+	}
+	members := randomMembers(s, int(count))
+	c.w.WriteArrayHeader(len(members))
+	for _, member := range members {
+		c.w.WriteBulkString(member)
+	}
+}
+
+// smoveCommand implements SMOVE source destination member
+func smoveCommand(c *client, args [][]byte) {
+	source, destination, member := string(args[1]), string(args[2]), string(args[3])
+	src, err := c.db.getSet(source)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if src == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	dst, err := c.db.getSet(destination)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !src.contains(member) {
+		c.w.WriteInteger(0)
+		return
+	}
+	if source == destination {
+		c.w.WriteInteger(1)
+		return
+	}
+
+	src.remove(member)
+	if src.len() == 0 {
+		c.db.remove(source)
+	}
+	if dst == nil {
+		dst = newSet()
+		c.db.set(destination, dst, false)
+	}
+	dst.add(member)
+	c.w.WriteInteger(1)
+}
+
+// getSets returns the sets stored at keys for op, with nil for the missing
+// ones. It returns errWrongType if one of the keys holds another type. Like
+// in Redis, an intersection looks no further than the first missing key,
+// since the result is empty anyway.
+func (ks *keyspace) getSets(keys [][]byte, op setOperation) ([]*set, error) {
+	sets := make([]*set, len(keys))
+	for i, key := range keys {
+		s, err := ks.getSet(string(key))
+		if err != nil {
+			return nil, err
+		}
+		if s == nil && op == setInter {
+			return sets, nil
+		}
+		sets[i] = s
+	}
+	return sets, nil
+}
+
+// setOperation is an operation of set algebra
+type setOperation int
+
+const (
+	setUnion setOperation = iota
+	setInter
+	setDiff
+)
+
+// combineSets applies op to sets, where nil sets are empty, and returns the
+// result. A limit other than zero stops an intersection once it has limit
+// members.
+func combineSets(op setOperation, sets []*set, limit int) *set {
+	result := newSet()
+	switch op {
+	case setUnion:
+		for _, s := range sets {
+			if s == nil {
+				continue
+			}
+			s.forEach(func(member string) bool {
+				result.add(member)
+				return true
+			})
+		}
+	case setInter:
+		smallest := -1
+		for i, s := range sets {
+			if s == nil {
+				// The intersection with an empty set is empty
+				return result
+			}
+			if smallest < 0 || s.len() < sets[smallest].len() {
+				smallest = i
+			}
+		}
+		sets[smallest].forEach(func(member string) bool {
+			for i, s := range sets {
+				if i != smallest && !s.contains(member) {
+					return true
+				}
+			}
+			result.add(member)
+			return limit == 0 || result.len() < limit
+		})
+	case setDiff:
+		if sets[0] == nil {
+			return result
+		}
+		sets[0].forEach(func(member string) bool {
+			for _, s := range sets[1:] {
+				if s != nil && s.contains(member) {
+					return true
+				}
+			}
+			result.add(member)
+			return true
+		})
+	}
+	return result
+}
+
+// setOperationGeneric implements SUNION, SINTER and SDIFF
+func setOperationGeneric(c *client, args [][]byte, op setOperation) {
+	sets, err := c.db.getSets(args[1:], op)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	writeMembers(c, combineSets(op, sets, 0).members())
+}
+
+// setOperationStoreGeneric implements SUNIONSTORE, SINTERSTORE and
+// SDIFFSTORE, which store the result at destination whatever it held
+func setOperationStoreGeneric(c *client, args [][]byte, op setOperation) {
+	sets, err := c.db.getSets(args[2:], op)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	destination := string(args[1])
+	result := combineSets(op, sets, 0)
+	if result.len() == 0 {
+		c.db.remove(destination)
+	} else {
+		c.db.set(destination, result, false)
+	}
+	c.w.WriteInteger(int64(result.len()))
+}
+
+// sinterCommand implements SINTER key [key ...]
+func sinterCommand(c *client, args [][]byte) {
+	setOperationGeneric(c, args, setInter)
+}
+
+// sunionCommand implements SUNION key [key ...]
+func sunionCommand(c *client, args [][]byte) {
+	setOperationGeneric(c, args, setUnion)
+}
+
+// sdiffCommand implements SDIFF key [key ...]
+func sdiffCommand(c *client, args [][]byte) {
+	setOperationGeneric(c, args, setDiff)
+}
+
+// sinterstoreCommand implements SINTERSTORE destination key [key ...]
+func sinterstoreCommand(c *client, args [][]byte) {
+	setOperationStoreGeneric(c, args, setInter)
+}
+
+// sunionstoreCommand implements SUNIONSTORE destination key [key ...]
+func sunionstoreCommand(c *client, args [][]byte) {
+	setOperationStoreGeneric(c, args, setUnion)
+}
+
+// sdiffstoreCommand implements SDIFFSTORE destination key [key ...]
+func sdiffstoreCommand(c *client, args [][]byte) {
+	setOperationStoreGeneric(c, args, setDiff)
+}
+
+// parseIntercardArgs parses the arguments of SINTERCARD and ZINTERCARD
+// from numkeys on, as in numkeys key [key ...] [LIMIT limit]. It returns
+// the keys and the limit, which is zero for none.
+func parseIntercardArgs(args [][]byte) ([][]byte, int64, error) {
+	numKeys, ok := parseInt(args[0])
+	if !ok || numKeys <= 0 {
+		return nil, 0, errNumKeys
+	}
+	if numKeys > int64(len(args)-1) {
+		return nil, 0, errTooManyKeys
+	}
+	keys := args[1 : 1+numKeys]
+
+	var limit int64
+	options := args[1+numKeys:]
+	for i := 0; i < len(options); i += 2 {
+		if !strings.EqualFold(string(options[i]), "LIMIT") || i+1 == len(options) {
+			return nil, 0, errSyntax
+		}
+		if limit, ok = parseInt(options[i+1]); !ok || limit < 0 {
+			return nil, 0, errLimitNegative
+		}
+	}
+	return keys, limit, nil
+}
+
+// sintercardCommand implements SINTERCARD numkeys key [key ...] [LIMIT limit]
+func sintercardCommand(c *client, args [][]byte) {
+	keys, limit, err := parseIntercardArgs(args[1:])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	sets, err := c.db.getSets(keys, setInter)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteInteger(int64(combineSets(setInter, sets, int(limit)).len()))
+}
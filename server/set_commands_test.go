@@ -0,0 +1,150 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+func Test_SetCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should add members and count the new ones",
+			input: "SADD s 3 1 2\r\nSADD s 2 4\r\nSCARD s\r\nSMEMBERS s\r\n",
+			want:  ":3\r\n:1\r\n:4\r\n*4\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n$1\r\n4\r\n",
+		},
+		{
+			name:  "It should remove members and delete the emptied set",
+			input: "SADD s a b\r\nSREM s a c\r\nSREM s b\r\nEXISTS s\r\nSREM s b\r\n",
+			want:  ":2\r\n:1\r\n:1\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should tell members apart",
+			input: "SADD s a 1\r\nSISMEMBER s a\r\nSISMEMBER s b\r\nSMISMEMBER s 1 b a\r\nSMISMEMBER missing a\r\nSISMEMBER missing a\r\n",
+			want:  ":2\r\n:1\r\n:0\r\n*3\r\n:1\r\n:0\r\n:1\r\n*1\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should describe a missing key as an empty set",
+			input: "SMEMBERS s\r\nSCARD s\r\nSPOP s\r\nSPOP s 2\r\nSRANDMEMBER s\r\nSRANDMEMBER s 2\r\n",
+			want:  "*0\r\n:0\r\n$-1\r\n*0\r\n$-1\r\n*0\r\n",
+		},
+		{
+			name:  "It should pop the only member and delete the set",
+			input: "SADD s a\r\nSPOP s\r\nEXISTS s\r\n",
+			want:  ":1\r\n$1\r\na\r\n:0\r\n",
+		},
+		{
+			name:  "It should pop every member when the count is large enough",
+			input: "SADD s 1 2\r\nSPOP s 5\r\nEXISTS s\r\n",
+			want:  ":2\r\n*2\r\n$1\r\n1\r\n$1\r\n2\r\n:0\r\n",
+		},
+		{
+			name: "It should reject invalid SPOP and SRANDMEMBER counts",
+			input: "SPOP s -1\r\nSPOP s x\r\nSPOP s 1 2\r\nSRANDMEMBER s x\r\nSRANDMEMBER s -9223372036854775808\r\n" +
+				"SADD s a\r\nSRANDMEMBER s -9223372036854775807\r\n",
+			want: "-ERR value is out of range, must be positive\r\n-ERR value is out of range, must be positive\r\n-ERR syntax error\r\n" +
+				"-ERR value is not an integer or out of range\r\n-ERR value is out of range, must be between -9223372036854775807 and 9223372036854775807\r\n" +
+				":1\r\n-ERR value is out of range\r\n",
+		},
+		{
+			name:  "It should repeat members for a negative SRANDMEMBER count",
+			input: "SADD s a\r\nSRANDMEMBER s -3\r\nSRANDMEMBER s 3\r\nSRANDMEMBER s 0\r\n",
+			want:  ":1\r\n*3\r\n$1\r\na\r\n$1\r\na\r\n$1\r\na\r\n*1\r\n$1\r\na\r\n*0\r\n",
+		},
+		{
+			name:  "It should move members between sets",
+			input: "SADD src a b\r\nSMOVE src dst a\r\nSMOVE src dst c\r\nSMOVE src src b\r\nSMOVE src dst b\r\nEXISTS src\r\nSCARD dst\r\nSMOVE missing dst a\r\n",
+			want:  ":2\r\n:1\r\n:0\r\n:1\r\n:1\r\n:0\r\n:2\r\n:0\r\n",
+		},
+		{
+			name:  "It should intersect, unite and subtract sets",
+			input: "SADD a 1 2 3 4\r\nSADD b 2 3 5\r\nSADD c 3 4\r\nSINTER a b c\r\nSUNION b c\r\nSDIFF a b\r\nSDIFF a b c\r\nSINTER a missing\r\nSUNION missing\r\nSDIFF missing a\r\n",
+			want: ":4\r\n:3\r\n:2\r\n*1\r\n$1\r\n3\r\n*4\r\n$1\r\n2\r\n$1\r\n3\r\n$1\r\n4\r\n$1\r\n5\r\n" +
+				"*2\r\n$1\r\n1\r\n$1\r\n4\r\n*1\r\n$1\r\n1\r\n*0\r\n*0\r\n*0\r\n",
+		},
+		{
+			name:  "It should store the result of set algebra whatever the destination held",
+			input: "SADD a 1 2 3\r\nSADD b 2 3 4\r\nSET dst v\r\nSINTERSTORE dst a b\r\nSMEMBERS dst\r\nSUNIONSTORE dst a b\r\nSDIFFSTORE dst a b\r\nSMEMBERS dst\r\nSDIFFSTORE dst a a\r\nEXISTS dst\r\n",
+			want:  ":3\r\n:3\r\n+OK\r\n:2\r\n*2\r\n$1\r\n2\r\n$1\r\n3\r\n:4\r\n:1\r\n*1\r\n$1\r\n1\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should count the intersection up to a limit",
+			input: "SADD a 1 2 3 4\r\nSADD b 2 3 4 5\r\nSINTERCARD 2 a b\r\nSINTERCARD 2 a b LIMIT 2\r\nSINTERCARD 2 a b LIMIT 0\r\nSINTERCARD 1 missing\r\n",
+			want:  ":4\r\n:4\r\n:3\r\n:2\r\n:3\r\n:0\r\n",
+		},
+		{
+			name:  "It should reject invalid SINTERCARD arguments",
+			input: "SINTERCARD 0 a\r\nSINTERCARD 3 a b\r\nSINTERCARD 1 a LIMIT -1\r\nSINTERCARD 1 a LIMIT\r\nSINTERCARD 1 a FOO 1\r\n",
+			want: "-ERR numkeys should be greater than 0\r\n-ERR Number of keys can't be greater than number of args\r\n" +
+				"-ERR LIMIT can't be negative\r\n-ERR syntax error\r\n-ERR syntax error\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SetCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET str v\r\nSADD s a\r\n")
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"SADD str a", "SREM str a", "SMEMBERS str", "SISMEMBER str a", "SMISMEMBER str a", "SCARD str",
+		"SPOP str", "SRANDMEMBER str", "SMOVE str s a", "SMOVE s str a", "SINTER s str", "SUNION str",
+		"SDIFF s str", "SINTERSTORE dst s str", "SINTERCARD 2 s str", "LPUSH s a", "GET s",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+	// Like Redis, an intersection with a missing key stops looking there
+	if got := srv.HandleRequest("SINTER missing str\r\n"); got != "*0\r\n" {
+		t.Errorf("SINTER missing str = %q, want %q", got, "*0\r\n")
+	}
+}
+
+func Test_SetCommandsReplyWithSetsInRESP3(t *testing.T) {
+	got := New(Config{}).HandleRequest("HELLO 3\r\nSADD s 1 2\r\nSMEMBERS s\r\n")
+	if want := ":2\r\n~2\r\n$1\r\n1\r\n$1\r\n2\r\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("HandleRequest() = %q, want it to end with %q", got, want)
+	}
+}
+
+func Test_SetCommandsPopDistinctMembers(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SADD s a b c d e f\r\n")
+
+	for _, input := range []string{"SRANDMEMBER s 4", "SPOP s 4"} {
+		reply, err := resp.Deserialize(srv.HandleRequest(input + "\r\n"))
+		if err != nil {
+			t.Fatalf("Deserialize() error = %v", err)
+		}
+		var members []string
+		for _, member := range reply.Elems() {
+			members = append(members, member.Str())
+		}
+		sort.Strings(members)
+		if len(members) != 4 {
+			t.Fatalf("%s = %v, want 4 members", input, members)
+		}
+		for i := 1; i < len(members); i++ {
+			if members[i] == members[i-1] {
+				t.Errorf("%s = %v, want distinct members", input, members)
+			}
+		}
+	}
+	if got := srv.HandleRequest("SCARD s\r\n"); got != ":2\r\n" {
+		t.Errorf("SCARD after SPOP = %q, want %q", got, ":2\r\n")
+	}
+}
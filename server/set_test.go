@@ -0,0 +1,84 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func Test_setEncoding(t *testing.T) {
+	tests := []struct {
+		name       string
+		members    []string
+		wantIntset bool
+	}{
+		{
+			name:       "It should keep small sets of integers in an intset",
+			members:    []string{"3", "-1", "20", "3"},
+			wantIntset: true,
+		},
+		{
+			name:       "It should convert to a hash table for a member that is not an integer",
+			members:    []string{"1", "2", "x"},
+			wantIntset: false,
+		},
+		{
+			name:       "It should not take integers with leading zeros as integers",
+			members:    []string{"1", "007"},
+			wantIntset: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSet()
+			for _, member := range tt.members {
+				s.add(member)
+			}
+			if s.isIntset() != tt.wantIntset {
+				t.Errorf("isIntset() = %v, want %v", s.isIntset(), tt.wantIntset)
+			}
+			for _, member := range tt.members {
+				if !s.contains(member) {
+					t.Errorf("contains(%q) = false", member)
+				}
+			}
+		})
+	}
+}
+
+func Test_setIntsetKeepsMembersSorted(t *testing.T) {
+	s := newSet()
+	for _, member := range []string{"5", "-3", "10", "0"} {
+		s.add(member)
+	}
+	s.remove("10")
+	if got, want := s.members(), []string{"-3", "0", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("members() = %v, want %v", got, want)
+	}
+}
+
+func Test_setConvertsLargeIntsets(t *testing.T) {
+	s := newSet()
+	for i := 0; i < setMaxIntsetEntries; i++ {
+		s.add(strconv.Itoa(i))
+	}
+	if !s.isIntset() {
+		t.Fatalf("a set of %d integers is not an intset", setMaxIntsetEntries)
+	}
+	s.add(strconv.Itoa(setMaxIntsetEntries))
+	if s.isIntset() {
+		t.Fatalf("a set of %d integers is still an intset", setMaxIntsetEntries+1)
+	}
+	members := s.members()
+	sort.Slice(members, func(i, j int) bool {
+		a, _ := strconv.Atoi(members[i])
+		b, _ := strconv.Atoi(members[j])
+		return a < b
+	})
+	for i, member := range members {
+		if member != strconv.Itoa(i) {
+			t.Fatalf("members()[%d] = %q after the conversion", i, member)
+		}
+	}
+}
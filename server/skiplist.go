@@ -0,0 +1,361 @@
+package server
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	// skiplistMaxLevel is the most levels a node can have, plenty for 2^64
+	// elements with skiplistP
+	skiplistMaxLevel = 32
+	// skiplistP is the probability that a node with a level also has the
+	// one above it
+	skiplistP = 0.25
+)
+
+// skiplist orders the elements of a sorted set by score, and by member
+// among equal scores, like the zskiplist of Redis. Each level of a node
+// records how many nodes its forward pointer skips, so finding the element
+// at a rank, or the rank of an element, takes O(log N) like a lookup.
+type skiplist struct {
+	// header is a sentinel with every level, before the first element
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	// level is the number of levels in use
+	level int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	// span is how many nodes forward moves ahead by
+	span int
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+// randomLevel returns the number of levels of a new node, with each level
+// a quarter as likely as the one below
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// before reports whether n comes before the element with score and member
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds an element, which must not be in zsl already
+func (zsl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	// rank holds the rank of update[i]
+	var rank [skiplistMaxLevel]int
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i < zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		// update[i] now skips to x, and x skips what is left
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// The levels above the new node skip over it
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+	return x
+}
+
+// deleteNode unlinks x, where update holds the last node before x on every
+// level
+func (zsl *skiplist) deleteNode(x *skiplistNode, update *[skiplistMaxLevel]*skiplistNode) {
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+}
+
+// delete removes an element, and reports whether it was there
+func (zsl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	zsl.deleteNode(x, &update)
+	return true
+}
+
+// updateScore changes the score of an element, which must be in zsl
+func (zsl *skiplist) updateScore(score float64, member string, newScore float64) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+
+	// When the element keeps its place, like it mostly does for small
+	// changes, there is nothing to relink
+	if (x.backward == nil || x.backward.score < newScore) &&
+		(x.level[0].forward == nil || x.level[0].forward.score > newScore) {
+		x.score = newScore
+		return x
+	}
+	zsl.deleteNode(x, &update)
+	return zsl.insert(newScore, member)
+}
+
+// rank returns the rank of an element, counting from 1, or 0 if it is not
+// in zsl
+func (zsl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.before(score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the element with the given rank, counting from 1, or nil
+// if there is none
+func (zsl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank && x != zsl.header {
+			return x
+		}
+	}
+	return nil
+}
+
+// scoreRange is a range of scores, like the min and max arguments of
+// ZRANGEBYSCORE
+type scoreRange struct {
+	min, max float64
+	// minex and maxex are set if the range excludes min and max
+	minex, maxex bool
+}
+
+func (r scoreRange) gteMin(score float64) bool {
+	if r.minex {
+		return score > r.min
+	}
+	return score >= r.min
+}
+
+func (r scoreRange) lteMax(score float64) bool {
+	if r.maxex {
+		return score < r.max
+	}
+	return score <= r.max
+}
+
+// empty reports whether no score can be in r
+func (r scoreRange) empty() bool {
+	return r.min > r.max || (r.min == r.max && (r.minex || r.maxex))
+}
+
+// firstInRange returns the first element with a score in r, or nil if
+// there is none
+func (zsl *skiplist) firstInRange(r scoreRange) *skiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.score) {
+		return nil
+	}
+	return x
+}
+
+// lastInRange returns the last element with a score in r, or nil if there
+// is none
+func (zsl *skiplist) lastInRange(r scoreRange) *skiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.gteMin(x.score) {
+		return nil
+	}
+	return x
+}
+
+// lexBound is one end of a lexRange
+type lexBound struct {
+	value     string
+	exclusive bool
+	// inf is -1 for -, the bound before every member, and 1 for +, the
+	// bound after every member
+	inf int
+}
+
+// compare compares b with member the way strings.Compare does
+func (b lexBound) compare(member string) int {
+	if b.inf != 0 {
+		return b.inf
+	}
+	return strings.Compare(b.value, member)
+}
+
+// lexRange is a range of members, like the min and max arguments of
+// ZRANGEBYLEX. It only makes sense when all the members have the same
+// score.
+type lexRange struct {
+	min, max lexBound
+}
+
+func (r lexRange) gteMin(member string) bool {
+	if r.min.exclusive {
+		return r.min.compare(member) < 0
+	}
+	return r.min.compare(member) <= 0
+}
+
+func (r lexRange) lteMax(member string) bool {
+	if r.max.exclusive {
+		return r.max.compare(member) > 0
+	}
+	return r.max.compare(member) >= 0
+}
+
+// empty reports whether no member can be in r
+func (r lexRange) empty() bool {
+	c := strings.Compare(r.min.value, r.max.value)
+	if r.min.inf != 0 || r.max.inf != 0 {
+		c = cmp.Compare(r.min.inf, r.max.inf)
+	}
+	return c > 0 || (c == 0 && (r.min.exclusive || r.max.exclusive))
+}
+
+// firstInLexRange returns the first element with a member in r, or nil if
+// there is none
+func (zsl *skiplist) firstInLexRange(r lexRange) *skiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if x == nil || !r.lteMax(x.member) {
+		return nil
+	}
+	return x
+}
+
+// lastInLexRange returns the last element with a member in r, or nil if
+// there is none
+func (zsl *skiplist) lastInLexRange(r lexRange) *skiplistNode {
+	if r.empty() {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+	if x == zsl.header || !r.gteMin(x.member) {
+		return nil
+	}
+	return x
+}
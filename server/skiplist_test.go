@@ -0,0 +1,124 @@
+package server
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkSkiplist checks that zsl holds want, which is sorted, in order both
+// ways and with the ranks its spans add up to
+func checkSkiplist(t *testing.T, zsl *skiplist, want []zsetElement) {
+	t.Helper()
+	if zsl.length != len(want) {
+		t.Fatalf("length = %d, want %d", zsl.length, len(want))
+	}
+	i := 0
+	for x := zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		if x.member != want[i].member || x.score != want[i].score {
+			t.Fatalf("element %d = %q %v, want %q %v", i, x.member, x.score, want[i].member, want[i].score)
+		}
+		if rank := zsl.rank(x.score, x.member); rank != i+1 {
+			t.Fatalf("rank(%q) = %d, want %d", x.member, rank, i+1)
+		}
+		if zsl.byRank(i+1) != x {
+			t.Fatalf("byRank(%d) is not %q", i+1, x.member)
+		}
+		i++
+	}
+	for x := zsl.tail; x != nil; x = x.backward {
+		i--
+		if x.member != want[i].member {
+			t.Fatalf("element %d going backwards = %q, want %q", i, x.member, want[i].member)
+		}
+	}
+}
+
+func Test_skiplistKeepsElementsInOrder(t *testing.T) {
+	zsl := newSkiplist()
+	scores := map[string]float64{}
+	for i := 0; i < 2000; i++ {
+		member := strconv.Itoa(rand.IntN(500))
+		score := float64(rand.IntN(50))
+		current, ok := scores[member]
+		switch {
+		case !ok:
+			zsl.insert(score, member)
+			scores[member] = score
+		case i%3 == 0:
+			if !zsl.delete(current, member) {
+				t.Fatalf("delete(%v, %q) = false", current, member)
+			}
+			delete(scores, member)
+		default:
+			zsl.updateScore(current, member, score)
+			scores[member] = score
+		}
+	}
+
+	var want []zsetElement
+	for member, score := range scores {
+		want = append(want, zsetElement{member, score})
+	}
+	slices.SortFunc(want, func(a, b zsetElement) int {
+		if a.score != b.score {
+			return cmp.Compare(a.score, b.score)
+		}
+		return strings.Compare(a.member, b.member)
+	})
+	checkSkiplist(t, zsl, want)
+	if zsl.delete(-1, "missing") {
+		t.Errorf("delete() of a missing element = true")
+	}
+}
+
+func Test_skiplistRanges(t *testing.T) {
+	zsl := newSkiplist()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		zsl.insert(float64(i+1), member)
+	}
+	tests := []struct {
+		name      string
+		r         scoreRange
+		wantFirst string
+		wantLast  string
+	}{
+		{
+			name:      "It should find the ends of an inclusive range",
+			r:         scoreRange{min: 2, max: 4},
+			wantFirst: "b",
+			wantLast:  "d",
+		},
+		{
+			name:      "It should leave out excluded ends",
+			r:         scoreRange{min: 2, max: 4, minex: true, maxex: true},
+			wantFirst: "c",
+			wantLast:  "c",
+		},
+		{
+			name: "It should find nothing in a range between the scores",
+			r:    scoreRange{min: 2.1, max: 2.9},
+		},
+		{
+			name: "It should find nothing in an empty range",
+			r:    scoreRange{min: 3, max: 3, minex: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last := zsl.firstInRange(tt.r), zsl.lastInRange(tt.r)
+			if tt.wantFirst == "" {
+				if first != nil || last != nil {
+					t.Errorf("firstInRange() and lastInRange() found elements, want none")
+				}
+				return
+			}
+			if first == nil || first.member != tt.wantFirst || last == nil || last.member != tt.wantLast {
+				t.Errorf("firstInRange() and lastInRange() = %v, %v, want %q, %q", first, last, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// streamNodeMaxEntries is the most entries a node of a stream holds,
+	// like the stream-node-max-entries setting of Redis
+	streamNodeMaxEntries = 100
+	// streamNodeMaxBytes is roughly the most bytes of fields and values a
+	// node holds, like the stream-node-max-bytes setting of Redis
+	streamNodeMaxBytes = 4096
+)
+
+// streamID is the ID of a stream entry: a unix time in milliseconds and a
+// sequence number telling apart the entries added in the same millisecond
+type streamID struct {
+	ms, seq uint64
+}
+
+func (id streamID) String() string {
+	return strconv.FormatUint(id.ms, 10) + "-" + strconv.FormatUint(id.seq, 10)
+}
+
+func (id streamID) compare(other streamID) int {
+	if id.ms != other.ms {
+		if id.ms < other.ms {
+			return -1
+		}
+		return 1
+	}
+	if id.seq != other.seq {
+		if id.seq < other.seq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// key encodes id big endian, so the keys of a rax sort like the IDs
+func (id streamID) key() []byte {
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(make([]byte, 0, 16), id.ms), id.seq)
+}
+
+// next returns the ID right after id, or false if id is the last one
+func (id streamID) next() (streamID, bool) {
+	switch {
+	case id.seq < math.MaxUint64:
+		return streamID{id.ms, id.seq + 1}, true
+	case id.ms < math.MaxUint64:
+		return streamID{id.ms + 1, 0}, true
+	}
+	return id, false
+}
+
+// prev returns the ID right before id, or false if id is 0-0
+func (id streamID) prev() (streamID, bool) {
+	switch {
+	case id.seq > 0:
+		return streamID{id.ms, id.seq - 1}, true
+	case id.ms > 0:
+		return streamID{id.ms - 1, math.MaxUint64}, true
+	}
+	return id, false
+}
+
+// parseStreamID parses an ID written as ms-seq, or as ms alone in which
+// case the sequence number is missingSeq
+func parseStreamID(b []byte, missingSeq uint64) (streamID, bool) {
+	msPart, seqPart, hasSeq := strings.Cut(string(b), "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	if !hasSeq {
+		return streamID{ms, missingSeq}, true
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	return streamID{ms, seq}, true
+}
+
+// streamEntry is an entry of a stream
+type streamEntry struct {
+	id streamID
+	// fields holds the field names and values, one after the other
+	fields []string
+}
+
+// streamNode is a node of the rax of a stream. Like the listpacks Redis
+// keeps the entries in, it holds a run of consecutive entries in a flat
+// slice, so a stream costs one rax key per node rather than per entry.
+type streamNode struct {
+	// master is the ID of the first entry added to the node, which is its
+	// key in the rax. It stays the key when that entry is deleted.
+	master  streamID
+	entries []streamEntry
+	// bytes is how many bytes of fields and values the entries hold
+	bytes int
+}
+
+// stream is the stream type. Its entries are kept in nodes of up to
+// streamNodeMaxEntries entries, which a rax orders by their master IDs, so
+// finding an ID takes a walk down the rax and a search of a single node.
+type stream struct {
+	rax    *rax[*streamNode]
+	length int
+	// lastID is the ID of the last entry ever added, even if it was
+	// deleted since
+	lastID streamID
+	// maxDeletedID is the greatest ID XDEL removed
+	maxDeletedID streamID
+	// entriesAdded counts every entry ever added
+	entriesAdded uint64
+}
+
+func newStream() *stream {
+	return &stream{rax: newRax[*streamNode]()}
+}
+
+// firstNode returns the node holding the oldest entries, or nil if s is
+// empty
+func (s *stream) firstNode() *streamNode {
+	var first *streamNode
+	s.rax.walk(nil, false, func(_ []byte, node *streamNode) bool {
+		first = node
+		return false
+	})
+	return first
+}
+
+// lastNode returns the node holding the newest entries, or nil if s is
+// empty
+func (s *stream) lastNode() *streamNode {
+	var last *streamNode
+	s.rax.walk(nil, true, func(_ []byte, node *streamNode) bool {
+		last = node
+		return false
+	})
+	return last
+}
+
+// firstEntry returns the oldest entry
+func (s *stream) firstEntry() (streamEntry, bool) {
+	if node := s.firstNode(); node != nil {
+		return node.entries[0], true
+	}
+	return streamEntry{}, false
+}
+
+// lastEntry returns the newest entry
+func (s *stream) lastEntry() (streamEntry, bool) {
+	if node := s.lastNode(); node != nil {
+		return node.entries[len(node.entries)-1], true
+	}
+	return streamEntry{}, false
+}
+
+// add appends an entry, whose id must be greater than lastID
+func (s *stream) add(id streamID, fields []string) {
+	size := 0
+	for _, f := range fields {
+		size += len(f)
+	}
+	node := s.lastNode()
+	if node == nil || len(node.entries) >= streamNodeMaxEntries || node.bytes+size > streamNodeMaxBytes {
+		node = &streamNode{master: id}
+		s.rax.insert(id.key(), node)
+	}
+	node.entries = append(node.entries, streamEntry{id, fields})
+	node.bytes += size
+	s.length++
+	s.lastID = id
+	s.entriesAdded++
+}
+
+// nodeOf returns the node that would hold id, which is the last one with a
+// master no greater than id
+func (s *stream) nodeOf(id streamID) *streamNode {
+	var found *streamNode
+	s.rax.walk(id.key(), true, func(_ []byte, node *streamNode) bool {
+		found = node
+		return false
+	})
+	return found
+}
+
+// removeNode removes node and all its entries
+func (s *stream) removeNode(node *streamNode) {
+	s.rax.remove(node.master.key())
+	s.length -= len(node.entries)
+}
+
+// delete removes the entry with id, and reports whether it was there
+func (s *stream) delete(id streamID) bool {
+	node := s.nodeOf(id)
+	if node == nil {
+		return false
+	}
+	i, ok := slices.BinarySearchFunc(node.entries, id, func(e streamEntry, id streamID) int {
+		return e.id.compare(id)
+	})
+	if !ok {
+		return false
+	}
+	for _, f := range node.entries[i].fields {
+		node.bytes -= len(f)
+	}
+	node.entries = slices.Delete(node.entries, i, i+1)
+	s.length--
+	if len(node.entries) == 0 {
+		s.rax.remove(node.master.key())
+	}
+	if id.compare(s.maxDeletedID) > 0 {
+		s.maxDeletedID = id
+	}
+	return true
+}
+
+// rangeEntries returns the entries with an ID from start to end, from the
+// newest down if reverse is set, and at most count of them unless it is
+// zero
+func (s *stream) rangeEntries(start, end streamID, reverse bool, count int) []streamEntry {
+	var entries []streamEntry
+	if start.compare(end) > 0 {
+		return entries
+	}
+	full := func() bool {
+		return count > 0 && len(entries) == count
+	}
+	if reverse {
+		s.rax.walk(end.key(), true, func(_ []byte, node *streamNode) bool {
+			for i := len(node.entries) - 1; i >= 0; i-- {
+				e := node.entries[i]
+				if e.id.compare(end) > 0 {
+					continue
+				}
+				if e.id.compare(start) < 0 || full() {
+					return false
+				}
+				entries = append(entries, e)
+			}
+			return true
+		})
+		return entries
+	}
+
+	// The first entries may be in the node before the first master in the
+	// range
+	from := start
+	if node := s.nodeOf(start); node != nil {
+		from = node.master
+	}
+	s.rax.walk(from.key(), false, func(_ []byte, node *streamNode) bool {
+		for _, e := range node.entries {
+			if e.id.compare(start) < 0 {
+				continue
+			}
+			if e.id.compare(end) > 0 || full() {
+				return false
+			}
+			entries = append(entries, e)
+		}
+		return true
+	})
+	return entries
+}
+
+// streamTrimStrategy is what XADD and XTRIM trim a stream by
+type streamTrimStrategy int
+
+const (
+	streamTrimNone streamTrimStrategy = iota
+	// streamTrimMaxLen keeps the newest maxLen entries
+	streamTrimMaxLen
+	// streamTrimMinID keeps the entries with an ID no lower than minID
+	streamTrimMinID
+)
+
+// streamTrim describes how to trim a stream
+type streamTrim struct {
+	strategy streamTrimStrategy
+	maxLen   int64
+	minID    streamID
+	// approx only removes whole nodes, which is cheaper but may leave a
+	// few more entries than asked
+	approx bool
+	// limit is the most entries to remove, or zero for no limit
+	limit int64
+}
+
+// trim removes the oldest entries the way t says, and returns how many it
+// removed. Like in Redis, whole nodes are removed while they can be, and
+// only exact trimming goes into the node after them.
+func (s *stream) trim(t streamTrim) int64 {
+	var removed int64
+	for {
+		node := s.firstNode()
+		if node == nil {
+			break
+		}
+		if t.strategy == streamTrimMaxLen && int64(s.length) <= t.maxLen {
+			break
+		}
+		n := int64(len(node.entries))
+		if t.limit > 0 && removed+n > t.limit {
+			break
+		}
+		removeNode := int64(s.length)-n >= t.maxLen
+		if t.strategy == streamTrimMinID {
+			removeNode = node.entries[n-1].id.compare(t.minID) < 0
+		}
+		if removeNode {
+			s.removeNode(node)
+			removed += n
+			continue
+		}
+		if t.approx {
+			break
+		}
+
+		i := 0
+		for ; i < len(node.entries); i++ {
+			if t.strategy == streamTrimMaxLen && int64(s.length-i) <= t.maxLen {
+				break
+			}
+			if t.strategy == streamTrimMinID && node.entries[i].id.compare(t.minID) >= 0 {
+				break
+			}
+			for _, f := range node.entries[i].fields {
+				node.bytes -= len(f)
+			}
+		}
+		node.entries = slices.Delete(node.entries, 0, i)
+		s.length -= i
+		removed += int64(i)
+		break
+	}
+	return removed
+}
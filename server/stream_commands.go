@@ -0,0 +1,522 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var (
+	errInvalidStreamID  = errors.New("ERR Invalid stream ID specified as stream command argument")
+	errStreamIDTooSmall = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	errStreamIDZero     = errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	errStreamExhausted  = errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
+)
+
+// streamDefaultTrimLimit is the most entries approximate trimming removes
+// at once when no LIMIT is given, like in Redis
+const streamDefaultTrimLimit = 100 * streamNodeMaxEntries
+
+func init() {
+	registerCommand(&command{
+		name: "xadd", handler: xaddCommand, arity: -5, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Appends a new message to a stream. Creates the key if it doesn't exist.", since: "5.0.0", group: "stream",
+		complexity: "O(1) when adding a new entry, O(N) when trimming where N being the number of entries evicted.",
+	})
+	registerCommand(&command{
+		name: "xrange", handler: xrangeCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the messages from a stream within a range of IDs.", since: "5.0.0", group: "stream",
+		complexity: "O(N) with N being the number of elements being returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+	})
+	registerCommand(&command{
+		name: "xrevrange", handler: xrevrangeCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the messages from a stream within a range of IDs in reverse order.", since: "5.0.0", group: "stream",
+		complexity: "O(N) with N being the number of elements returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+	})
+	registerCommand(&command{
+		name: "xlen", handler: xlenCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Return the number of messages in a stream.", since: "5.0.0", group: "stream", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "xdel", handler: xdelCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the number of messages after removing them from a stream.", since: "5.0.0", group: "stream",
+		complexity: "O(1) for each single item to delete in the stream, regardless of the stream size.",
+	})
+	registerCommand(&command{
+		name: "xtrim", handler: xtrimCommand, arity: -4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Deletes messages from the beginning of a stream.", since: "5.0.0", group: "stream",
+		complexity: "O(N), with N being the number of evicted entries. Constant times are very small however, since entries are organized in macro nodes containing multiple entries that can be released with a single deallocation.",
+	})
+	registerCommand(&command{
+		name: "xinfo", arity: -2,
+		summary: "A container for stream introspection commands.", since: "5.0.0", group: "stream",
+		complexity: "Depends on subcommand.",
+		subcommands: map[string]*command{
+			"help": {
+				name: "xinfo|help", handler: xinfoHelpCommand, arity: 2,
+				summary: "Returns helpful text about the different subcommands.", since: "5.0.0", group: "stream", complexity: "O(1)",
+			},
+			"stream": {
+				name: "xinfo|stream", handler: xinfoStreamCommand, arity: -3, flags: flagReadonly, firstKey: 2, lastKey: 2, step: 1,
+				summary: "Returns information about a stream.", since: "5.0.0", group: "stream", complexity: "O(1)",
+			},
+		},
+	})
+}
+
+// getStream returns the stream stored at key, or nil if there is none. It
+// returns errWrongType if key holds a value of another type.
+func (ks *keyspace) getStream(key string) (*stream, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	s, ok := value.(*stream)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// writeStreamEntries replies with entries, each as its ID and its fields
+// and values
+func writeStreamEntries(c *client, entries []streamEntry) {
+	c.w.WriteArrayHeader(len(entries))
+	for _, e := range entries {
+		writeStreamEntry(c, e)
+	}
+}
+
+func writeStreamEntry(c *client, e streamEntry) {
+	c.w.WriteArrayHeader(2)
+	c.w.WriteBulkString(e.id.String())
+	c.w.WriteArrayHeader(len(e.fields))
+	for _, f := range e.fields {
+		c.w.WriteBulkString(f)
+	}
+}
+
+// parseStreamTrim parses the options of XADD and XTRIM, as in
+// [NOMKSTREAM] [MAXLEN | MINID [= | ~] threshold [LIMIT count]], from
+// args[2] on. For XADD it stops at the first argument that is not an
+// option, and returns its position and whether NOMKSTREAM was given.
+func parseStreamTrim(args [][]byte, xadd bool) (streamTrim, int, bool, error) {
+	var t streamTrim
+	noMkStream, hasLimit := false, false
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		moreArgs := len(args) - i - 1
+		switch option := strings.ToUpper(string(args[i])); {
+		case option == "NOMKSTREAM" && xadd:
+			noMkStream = true
+		case (option == "MAXLEN" || option == "MINID") && moreArgs >= 1:
+			if t.strategy != streamTrimNone {
+				return t, 0, false, errors.New("ERR syntax error, MAXLEN and MINID options at the same time are not compatible")
+			}
+			if next := string(args[i+1]); (next == "~" || next == "=") && moreArgs >= 2 {
+				t.approx = next == "~"
+				i++
+			}
+			i++
+			if option == "MAXLEN" {
+				t.strategy = streamTrimMaxLen
+				maxLen, ok := parseInt(args[i])
+				if !ok {
+					return t, 0, false, errNotInteger
+				}
+				if maxLen < 0 {
+					return t, 0, false, errors.New("ERR The MAXLEN argument must be >= 0.")
+				}
+				t.maxLen = maxLen
+			} else {
+				t.strategy = streamTrimMinID
+				minID, ok := parseStreamID(args[i], 0)
+				if !ok {
+					return t, 0, false, errInvalidStreamID
+				}
+				t.minID = minID
+			}
+		case option == "LIMIT" && moreArgs >= 1:
+			i++
+			limit, ok := parseInt(args[i])
+			if !ok {
+				return t, 0, false, errNotInteger
+			}
+			if limit < 0 {
+				return t, 0, false, errors.New("ERR The LIMIT argument must be >= 0.")
+			}
+			t.limit, hasLimit = limit, true
+		case xadd:
+			break options
+		default:
+			return t, 0, false, errSyntax
+		}
+	}
+
+	if hasLimit && t.strategy == streamTrimNone {
+		return t, 0, false, errors.New("ERR syntax error, LIMIT cannot be used without specifying a trimming strategy")
+	}
+	if !xadd && t.strategy == streamTrimNone {
+		return t, 0, false, errors.New("ERR syntax error, XTRIM must be called with a trimming strategy")
+	}
+	if hasLimit && !t.approx {
+		return t, 0, false, errors.New("ERR syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	if t.approx && !hasLimit {
+		t.limit = streamDefaultTrimLimit
+	}
+	return t, i, noMkStream, nil
+}
+
+// xaddCommand implements
+// XADD key [NOMKSTREAM] [MAXLEN | MINID [= | ~] threshold [LIMIT count]] * | id field value [field value ...]
+func xaddCommand(c *client, args [][]byte) {
+	t, idAt, noMkStream, err := parseStreamTrim(args, true)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if n := len(args) - idAt - 1; n < 2 || n%2 != 0 {
+		c.w.WriteError(wrongArityError("xadd"))
+		return
+	}
+	fields := args[idAt+1:]
+	// The ID is *, ms-* to only generate the sequence number, or explicit
+	idArg := string(args[idAt])
+	autoMs, autoSeq := idArg == "*", false
+	var id streamID
+	if !autoMs {
+		if ms, ok := strings.CutSuffix(idArg, "-*"); ok {
+			autoSeq = true
+			idArg = ms
+		}
+		var ok bool
+		if id, ok = parseStreamID([]byte(idArg), 0); !ok || (autoSeq && strings.Contains(idArg, "-")) {
+			c.w.WriteError(errInvalidStreamID.Error())
+			return
+		}
+		if !autoSeq && id == (streamID{}) {
+			c.w.WriteError(errStreamIDZero.Error())
+			return
+		}
+	}
+
+	key := string(args[1])
+	s, err := c.db.getStream(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil && noMkStream {
+		c.w.WriteNull()
+		return
+	}
+	last := streamID{}
+	if s != nil {
+		last = s.lastID
+	}
+
+	switch {
+	case autoMs:
+		// Like Redis, the time never goes back below the last ID, so IDs
+		// keep growing even if the clock does not
+		var ok bool
+		if now := uint64(c.db.now()); now > last.ms {
+			id = streamID{now, 0}
+		} else if id, ok = last.next(); !ok {
+			c.w.WriteError(errStreamExhausted.Error())
+			return
+		}
+	case autoSeq:
+		// As 0-0 is not a valid ID, 0-* starts at 0-1
+		switch {
+		case id.ms > last.ms:
+			id.seq = 0
+		case id.ms == last.ms && last.seq < math.MaxUint64:
+			id.seq = last.seq + 1
+		default:
+			c.w.WriteError(errStreamIDTooSmall.Error())
+			return
+		}
+	default:
+		if s != nil && id.compare(last) <= 0 {
+			c.w.WriteError(errStreamIDTooSmall.Error())
+			return
+		}
+	}
+
+	if s == nil {
+		s = newStream()
+		c.db.set(key, s, false)
+	}
+	entry := make([]string, len(fields))
+	for i, f := range fields {
+		entry[i] = string(f)
+	}
+	s.add(id, entry)
+	if t.strategy != streamTrimNone {
+		s.trim(t)
+	}
+	c.w.WriteBulkString(id.String())
+}
+
+// parseStreamRangeID parses the start or end of XRANGE, which is - or +
+// for the ends of the stream, or an ID that ( makes exclusive. An ID
+// without a sequence number gets missingSeq.
+func parseStreamRangeID(b []byte, missingSeq uint64) (streamID, bool, error) {
+	exclusive := len(b) > 1 && b[0] == '('
+	if exclusive {
+		b = b[1:]
+	}
+	switch string(b) {
+	case "-":
+		if exclusive {
+			return streamID{}, false, errInvalidStreamID
+		}
+		return streamID{}, false, nil
+	case "+":
+		if exclusive {
+			return streamID{}, false, errInvalidStreamID
+		}
+		return streamID{math.MaxUint64, math.MaxUint64}, false, nil
+	}
+	id, ok := parseStreamID(b, missingSeq)
+	if !ok {
+		return streamID{}, false, errInvalidStreamID
+	}
+	return id, exclusive, nil
+}
+
+// xrangeGeneric implements XRANGE and XREVRANGE, whose range is given as
+// args[2] to args[3], or args[3] to args[2] in reverse
+func xrangeGeneric(c *client, args [][]byte, reverse bool) {
+	startArg, endArg := args[2], args[3]
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	start, startEx, err := parseStreamRangeID(startArg, 0)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if startEx {
+		var ok bool
+		if start, ok = start.next(); !ok {
+			c.w.WriteError("ERR invalid start ID for the interval")
+			return
+		}
+	}
+	end, endEx, err := parseStreamRangeID(endArg, math.MaxUint64)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if endEx {
+		var ok bool
+		if end, ok = end.prev(); !ok {
+			c.w.WriteError("ERR invalid end ID for the interval")
+			return
+		}
+	}
+
+	var count int64
+	hasCount := false
+	for j := 4; j < len(args); j++ {
+		if !strings.EqualFold(string(args[j]), "COUNT") || j+1 == len(args) {
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+		var ok bool
+		if count, ok = parseInt(args[j+1]); !ok {
+			c.w.WriteError(errNotInteger.Error())
+			return
+		}
+		count, hasCount = max(count, 0), true
+		j++
+	}
+
+	s, err := c.db.getStream(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil || (hasCount && count == 0) {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+	writeStreamEntries(c, s.rangeEntries(start, end, reverse, int(count)))
+}
+
+// xrangeCommand implements XRANGE key start end [COUNT count]
+func xrangeCommand(c *client, args [][]byte) {
+	xrangeGeneric(c, args, false)
+}
+
+// xrevrangeCommand implements XREVRANGE key end start [COUNT count]
+func xrevrangeCommand(c *client, args [][]byte) {
+	xrangeGeneric(c, args, true)
+}
+
+// xlenCommand implements XLEN key
+func xlenCommand(c *client, args [][]byte) {
+	s, err := c.db.getStream(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(s.length))
+}
+
+// xdelCommand implements XDEL key id [id ...]. Like in Redis, a stream left
+// empty is kept.
+func xdelCommand(c *client, args [][]byte) {
+	ids := make([]streamID, len(args)-2)
+	for i, arg := range args[2:] {
+		id, ok := parseStreamID(arg, 0)
+		if !ok {
+			c.w.WriteError(errInvalidStreamID.Error())
+			return
+		}
+		ids[i] = id
+	}
+	s, err := c.db.getStream(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	var deleted int64
+	for _, id := range ids {
+		if s.delete(id) {
+			deleted++
+		}
+	}
+	c.w.WriteInteger(deleted)
+}
+
+// xtrimCommand implements
+// XTRIM key MAXLEN | MINID [= | ~] threshold [LIMIT count]
+func xtrimCommand(c *client, args [][]byte) {
+	t, _, _, err := parseStreamTrim(args, false)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	s, err := c.db.getStream(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(s.trim(t))
+}
+
+// xinfoStreamCommand implements XINFO STREAM key [FULL [COUNT count]]. The
+// server has no consumer groups, so there are never any to report.
+func xinfoStreamCommand(c *client, args [][]byte) {
+	full := false
+	count := int64(10)
+	if len(args) > 3 {
+		if !strings.EqualFold(string(args[3]), "FULL") {
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+		full = true
+		switch {
+		case len(args) == 6 && strings.EqualFold(string(args[4]), "COUNT"):
+			var ok bool
+			if count, ok = parseInt(args[5]); !ok {
+				c.w.WriteError(errNotInteger.Error())
+				return
+			}
+			count = max(count, 0)
+		case len(args) != 4:
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+	}
+
+	s, err := c.db.getStream(string(args[2]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if s == nil {
+		c.w.WriteError("ERR no such key")
+		return
+	}
+	first, hasFirst := s.firstEntry()
+	recordedFirstID := streamID{}
+	if hasFirst {
+		recordedFirstID = first.id
+	}
+
+	if full {
+		c.w.WriteMapHeader(9)
+	} else {
+		c.w.WriteMapHeader(10)
+	}
+	c.w.WriteBulkString("length")
+	c.w.WriteInteger(int64(s.length))
+	c.w.WriteBulkString("radix-tree-keys")
+	c.w.WriteInteger(int64(s.rax.size))
+	c.w.WriteBulkString("radix-tree-nodes")
+	c.w.WriteInteger(int64(s.rax.nodes))
+	c.w.WriteBulkString("last-generated-id")
+	c.w.WriteBulkString(s.lastID.String())
+	c.w.WriteBulkString("max-deleted-entry-id")
+	c.w.WriteBulkString(s.maxDeletedID.String())
+	c.w.WriteBulkString("entries-added")
+	c.w.WriteInteger(int64(s.entriesAdded))
+	c.w.WriteBulkString("recorded-first-entry-id")
+	c.w.WriteBulkString(recordedFirstID.String())
+	if full {
+		// A count of 0 asks for every entry
+		c.w.WriteBulkString("entries")
+		writeStreamEntries(c, s.rangeEntries(streamID{}, streamID{math.MaxUint64, math.MaxUint64}, false, int(count)))
+		c.w.WriteBulkString("groups")
+		c.w.WriteArrayHeader(0)
+		return
+	}
+	c.w.WriteBulkString("groups")
+	c.w.WriteInteger(0)
+	c.w.WriteBulkString("first-entry")
+	if hasFirst {
+		writeStreamEntry(c, first)
+	} else {
+		c.w.WriteNull()
+	}
+	c.w.WriteBulkString("last-entry")
+	if last, ok := s.lastEntry(); ok {
+		writeStreamEntry(c, last)
+	} else {
+		c.w.WriteNull()
+	}
+}
+
+var xinfoHelp = []string{
+	"XINFO <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"STREAM <key> [FULL [COUNT <count>]",
+	"    Show information about the stream.",
+	"HELP",
+	"    Print this help.",
+}
+
+// xinfoHelpCommand implements XINFO HELP
+func xinfoHelpCommand(c *client, args [][]byte) {
+	c.w.WriteArrayHeader(len(xinfoHelp))
+	for _, line := range xinfoHelp {
+		c.w.WriteSimpleString(line)
+	}
+}
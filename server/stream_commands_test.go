@@ -0,0 +1,170 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// entryReply returns the reply for a stream entry with id and fields
+func entryReply(id string, fields ...string) string {
+	reply := "*2\r\n$" + strconv.Itoa(len(id)) + "\r\n" + id + "\r\n*" + strconv.Itoa(len(fields)) + "\r\n"
+	for _, f := range fields {
+		reply += "$" + strconv.Itoa(len(f)) + "\r\n" + f + "\r\n"
+	}
+	return reply
+}
+
+func Test_StreamCommands(t *testing.T) {
+	const setup = "XADD s 1-1 a 1\r\nXADD s 1-2 b 2\r\nXADD s 2-1 c 3\r\nXADD s 3-0 d 4\r\n"
+	const setupReply = "$3\r\n1-1\r\n$3\r\n1-2\r\n$3\r\n2-1\r\n$3\r\n3-0\r\n"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should add entries with explicit IDs",
+			input: "XADD s 1-1 a 1\r\nXADD s 1-* b 2\r\nXADD s 2 c 3 d 4\r\nXADD s 5-* e 5\r\nXLEN s\r\nXRANGE s - +\r\n",
+			want: "$3\r\n1-1\r\n$3\r\n1-2\r\n$3\r\n2-0\r\n$3\r\n5-0\r\n:4\r\n*4\r\n" +
+				entryReply("1-1", "a", "1") + entryReply("1-2", "b", "2") + entryReply("2-0", "c", "3", "d", "4") + entryReply("5-0", "e", "5"),
+		},
+		{
+			name: "It should only accept IDs greater than the last one",
+			input: "XADD s 5-5 a 1\r\nXADD s 5-5 a 1\r\nXADD s 4 a 1\r\nXADD s 4-* a 1\r\nXADD t 0-0 a 1\r\nXADD t 0 a 1\r\nXADD t 0-* a 1\r\n" +
+				"XADD t 18446744073709551615-18446744073709551615 a 1\r\nXADD t * a 1\r\nXADD t 18446744073709551615-* a 1\r\n",
+			want: "$3\r\n5-5\r\n-" + errStreamIDTooSmall.Error() + "\r\n-" + errStreamIDTooSmall.Error() + "\r\n-" + errStreamIDTooSmall.Error() + "\r\n" +
+				"-" + errStreamIDZero.Error() + "\r\n-" + errStreamIDZero.Error() + "\r\n$3\r\n0-1\r\n" +
+				"$41\r\n18446744073709551615-18446744073709551615\r\n-" + errStreamExhausted.Error() + "\r\n-" + errStreamIDTooSmall.Error() + "\r\n",
+		},
+		{
+			name: "It should reject invalid XADD arguments",
+			input: "XADD s x a 1\r\nXADD s 1-x a 1\r\nXADD s 1-2-* a 1\r\nXADD s * a 1 b\r\nXADD s MAXLEN 1 NOMKSTREAM\r\nXADD s MAXLEN x * a 1\r\n" +
+				"XADD s MAXLEN -1 * a 1\r\nXADD s MINID x * a 1\r\nXADD s MAXLEN 1 MINID 1 * a 1\r\nXADD s MAXLEN 1 LIMIT 1 * a 1\r\n" +
+				"XADD s MAXLEN 1 LIMIT 0 * a 1\r\nXADD s MAXLEN ~ 1 LIMIT -1 * a 1\r\nXADD s LIMIT 1 * a 1\r\nEXISTS s\r\n",
+			want: "-" + errInvalidStreamID.Error() + "\r\n-" + errInvalidStreamID.Error() + "\r\n-" + errInvalidStreamID.Error() + "\r\n" +
+				"-ERR wrong number of arguments for 'xadd' command\r\n-ERR wrong number of arguments for 'xadd' command\r\n" +
+				"-ERR value is not an integer or out of range\r\n-ERR The MAXLEN argument must be >= 0.\r\n-" + errInvalidStreamID.Error() + "\r\n" +
+				"-ERR syntax error, MAXLEN and MINID options at the same time are not compatible\r\n" +
+				"-ERR syntax error, LIMIT cannot be used without the special ~ option\r\n" +
+				"-ERR syntax error, LIMIT cannot be used without the special ~ option\r\n-ERR The LIMIT argument must be >= 0.\r\n" +
+				"-ERR syntax error, LIMIT cannot be used without specifying a trimming strategy\r\n:0\r\n",
+		},
+		{
+			name:  "It should not create a stream with NOMKSTREAM",
+			input: "XADD s NOMKSTREAM * a 1\r\nEXISTS s\r\nXADD s 1 a 1\r\nXADD s NOMKSTREAM 2 a 2\r\n",
+			want:  "$-1\r\n:0\r\n$3\r\n1-0\r\n$3\r\n2-0\r\n",
+		},
+		{
+			name: "It should trim the stream it adds to",
+			input: "XADD s 1 a 1\r\nXADD s 2 a 2\r\nXADD s MAXLEN 2 3 a 3\r\nXADD s MINID = 3 4 a 4\r\nXRANGE s - +\r\n" +
+				"XADD s MAXLEN ~ 1 5 a 5\r\nXLEN s\r\n",
+			want: "$3\r\n1-0\r\n$3\r\n2-0\r\n$3\r\n3-0\r\n$3\r\n4-0\r\n*2\r\n" + entryReply("3-0", "a", "3") + entryReply("4-0", "a", "4") +
+				"$3\r\n5-0\r\n:3\r\n",
+		},
+		{
+			name:  "It should return ranges of IDs",
+			input: setup + "XRANGE s 1 1\r\nXRANGE s (1-1 2\r\nXRANGE s - (3-0\r\nXRANGE s 3 1\r\nXRANGE missing - +\r\n",
+			want: setupReply + "*2\r\n" + entryReply("1-1", "a", "1") + entryReply("1-2", "b", "2") +
+				"*2\r\n" + entryReply("1-2", "b", "2") + entryReply("2-1", "c", "3") +
+				"*3\r\n" + entryReply("1-1", "a", "1") + entryReply("1-2", "b", "2") + entryReply("2-1", "c", "3") + "*0\r\n*0\r\n",
+		},
+		{
+			name:  "It should return ranges in reverse and limit their length",
+			input: setup + "XRANGE s - + COUNT 2\r\nXREVRANGE s + - COUNT 1\r\nXREVRANGE s (3-0 (1-1\r\nXRANGE s - + COUNT 0\r\nXREVRANGE s 1 2\r\n",
+			want: setupReply + "*2\r\n" + entryReply("1-1", "a", "1") + entryReply("1-2", "b", "2") + "*1\r\n" + entryReply("3-0", "d", "4") +
+				"*2\r\n" + entryReply("2-1", "c", "3") + entryReply("1-2", "b", "2") + "*0\r\n*0\r\n",
+		},
+		{
+			name: "It should reject invalid ranges",
+			input: "XRANGE s (- +\r\nXRANGE s x +\r\nXRANGE s (18446744073709551615-18446744073709551615 +\r\nXRANGE s - (0-0\r\n" +
+				"XRANGE s - + COUNT\r\nXRANGE s - + COUNT x\r\nXREVRANGE s + - FOO 1\r\n",
+			want: "-" + errInvalidStreamID.Error() + "\r\n-" + errInvalidStreamID.Error() + "\r\n-ERR invalid start ID for the interval\r\n" +
+				"-ERR invalid end ID for the interval\r\n-ERR syntax error\r\n-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should delete entries and keep the emptied stream",
+			input: setup + "XDEL s 1-2 9-9 1-2\r\nXLEN s\r\nXDEL s x\r\nXDEL missing 1-1\r\nXDEL s 1-1 2-1 3-0\r\nXLEN s\r\nEXISTS s\r\nXADD s 3-0 a 1\r\n",
+			want:  setupReply + ":1\r\n:3\r\n-" + errInvalidStreamID.Error() + "\r\n:0\r\n:3\r\n:0\r\n:1\r\n-" + errStreamIDTooSmall.Error() + "\r\n",
+		},
+		{
+			name: "It should trim streams",
+			input: "XADD s 1 a 1\r\nXADD s 2 a 2\r\nXADD s 3 a 3\r\nXTRIM s MAXLEN 2\r\nXTRIM s MINID 3\r\nXTRIM s MAXLEN ~ 0\r\nXLEN s\r\n" +
+				"XTRIM missing MAXLEN 0\r\nXTRIM s FOO 1\r\nXTRIM s NOMKSTREAM MAXLEN 1\r\nXTRIM s LIMIT 1\r\nXTRIM s MAXLEN 0 LIMIT 0\r\n",
+			want: "$3\r\n1-0\r\n$3\r\n2-0\r\n$3\r\n3-0\r\n:1\r\n:1\r\n:1\r\n:0\r\n:0\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"-ERR syntax error, LIMIT cannot be used without specifying a trimming strategy\r\n" +
+				"-ERR syntax error, LIMIT cannot be used without the special ~ option\r\n",
+		},
+		{
+			name:  "It should describe a stream",
+			input: "XADD s 1-1 a 1\r\nXADD s 2-1 b 2\r\nXDEL s 2-1\r\nXINFO STREAM s\r\n",
+			want: "$3\r\n1-1\r\n$3\r\n2-1\r\n:1\r\n*20\r\n$6\r\nlength\r\n:1\r\n$15\r\nradix-tree-keys\r\n:1\r\n$16\r\nradix-tree-nodes\r\n:2\r\n" +
+				"$17\r\nlast-generated-id\r\n$3\r\n2-1\r\n$20\r\nmax-deleted-entry-id\r\n$3\r\n2-1\r\n$13\r\nentries-added\r\n:2\r\n" +
+				"$23\r\nrecorded-first-entry-id\r\n$3\r\n1-1\r\n$6\r\ngroups\r\n:0\r\n" +
+				"$11\r\nfirst-entry\r\n" + entryReply("1-1", "a", "1") + "$10\r\nlast-entry\r\n" + entryReply("1-1", "a", "1"),
+		},
+		{
+			name:  "It should describe a stream with its entries",
+			input: "XADD s 1-1 a 1\r\nXADD s 2-1 b 2\r\nXINFO STREAM s FULL COUNT 1\r\n",
+			want: "$3\r\n1-1\r\n$3\r\n2-1\r\n*18\r\n$6\r\nlength\r\n:2\r\n$15\r\nradix-tree-keys\r\n:1\r\n$16\r\nradix-tree-nodes\r\n:2\r\n" +
+				"$17\r\nlast-generated-id\r\n$3\r\n2-1\r\n$20\r\nmax-deleted-entry-id\r\n$3\r\n0-0\r\n$13\r\nentries-added\r\n:2\r\n" +
+				"$23\r\nrecorded-first-entry-id\r\n$3\r\n1-1\r\n$7\r\nentries\r\n*1\r\n" + entryReply("1-1", "a", "1") + "$6\r\ngroups\r\n*0\r\n",
+		},
+		{
+			name:  "It should reject invalid XINFO arguments",
+			input: "XINFO STREAM s\r\nXADD s 1 a 1\r\nXINFO STREAM s FOO\r\nXINFO STREAM s FULL COUNT x\r\nXINFO FOO s\r\nXINFO\r\n",
+			want: "-ERR no such key\r\n$3\r\n1-0\r\n-ERR syntax error\r\n-ERR value is not an integer or out of range\r\n" +
+				"-ERR unknown subcommand 'FOO'. Try XINFO HELP.\r\n-ERR wrong number of arguments for 'xinfo' command\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_StreamCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET str v\r\nXADD s 1 a 1\r\n")
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"XADD str * a 1", "XRANGE str - +", "XREVRANGE str + -", "XLEN str", "XDEL str 1", "XTRIM str MAXLEN 1",
+		"XINFO STREAM str", "GET s", "LPUSH s a",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+}
+
+func Test_StreamAddGeneratesIDsFromTheClock(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+
+	got := srv.HandleRequest("XADD s * a 1\r\nXADD s * a 2\r\nXADD s 1800000000000-5 a 3\r\nXADD s * a 4\r\n")
+	want := "$15\r\n1700000000000-0\r\n$15\r\n1700000000000-1\r\n$15\r\n1800000000000-5\r\n$15\r\n1800000000000-6\r\n"
+	if got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+}
+
+func Test_StreamCommandsSpanManyNodes(t *testing.T) {
+	srv := New(Config{})
+	var input strings.Builder
+	for i := 1; i <= 3*streamNodeMaxEntries; i++ {
+		input.WriteString("XADD s " + strconv.Itoa(i) + " f v\r\n")
+	}
+	srv.HandleRequest(input.String())
+
+	got := srv.HandleRequest("XRANGE s 99 102\r\nXREVRANGE s 201 199\r\nXTRIM s MAXLEN ~ 150\r\nXLEN s\r\n")
+	want := "*4\r\n" + entryReply("99-0", "f", "v") + entryReply("100-0", "f", "v") + entryReply("101-0", "f", "v") + entryReply("102-0", "f", "v") +
+		"*3\r\n" + entryReply("201-0", "f", "v") + entryReply("200-0", "f", "v") + entryReply("199-0", "f", "v") +
+		":100\r\n:200\r\n"
+	if got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+)
+
+// streamIDs returns the IDs of entries as a string
+func streamIDs(entries []streamEntry) string {
+	ids := ""
+	for _, e := range entries {
+		ids += e.id.String() + " "
+	}
+	return ids
+}
+
+func Test_streamSpansNodes(t *testing.T) {
+	s := newStream()
+	for i := 1; i <= 250; i++ {
+		s.add(streamID{uint64(i), 0}, []string{"f", strconv.Itoa(i)})
+	}
+	if s.length != 250 || s.rax.size != 3 {
+		t.Fatalf("length and nodes = %d, %d, want 250, 3", s.length, s.rax.size)
+	}
+	if got, want := streamIDs(s.rangeEntries(streamID{99, 1}, streamID{201, 0}, false, 3)), "100-0 101-0 102-0 "; got != want {
+		t.Errorf("rangeEntries() = %q, want %q", got, want)
+	}
+	if got, want := streamIDs(s.rangeEntries(streamID{99, 1}, streamID{201, 0}, true, 3)), "201-0 200-0 199-0 "; got != want {
+		t.Errorf("rangeEntries() in reverse = %q, want %q", got, want)
+	}
+
+	// Deleting every entry of a node removes it
+	for i := 101; i <= 200; i++ {
+		s.delete(streamID{uint64(i), 0})
+	}
+	if s.length != 150 || s.rax.size != 2 || s.maxDeletedID != (streamID{200, 0}) {
+		t.Fatalf("length, nodes and max deleted ID = %d, %d, %v, want 150, 2, 200-0", s.length, s.rax.size, s.maxDeletedID)
+	}
+	if got, want := streamIDs(s.rangeEntries(streamID{100, 0}, streamID{202, 0}, false, 0)), "100-0 201-0 202-0 "; got != want {
+		t.Errorf("rangeEntries() = %q, want %q", got, want)
+	}
+}
+
+func Test_streamTrim(t *testing.T) {
+	tests := []struct {
+		name    string
+		trim    streamTrim
+		removed int64
+		first   string
+	}{
+		{
+			name:    "It should trim to an exact length",
+			trim:    streamTrim{strategy: streamTrimMaxLen, maxLen: 120},
+			removed: 130,
+			first:   "131-0",
+		},
+		{
+			name:    "It should only remove whole nodes when trimming approximately",
+			trim:    streamTrim{strategy: streamTrimMaxLen, maxLen: 120, approx: true},
+			removed: 100,
+			first:   "101-0",
+		},
+		{
+			name:    "It should trim below an ID",
+			trim:    streamTrim{strategy: streamTrimMinID, minID: streamID{230, 0}},
+			removed: 229,
+			first:   "230-0",
+		},
+		{
+			name:    "It should stop at the limit",
+			trim:    streamTrim{strategy: streamTrimMaxLen, maxLen: 0, approx: true, limit: 150},
+			removed: 100,
+			first:   "101-0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStream()
+			for i := 1; i <= 250; i++ {
+				s.add(streamID{uint64(i), 0}, []string{"f", "v"})
+			}
+			if got := s.trim(tt.trim); got != tt.removed {
+				t.Errorf("trim() = %d, want %d", got, tt.removed)
+			}
+			if first, _ := s.firstEntry(); first.id.String() != tt.first || s.length != 250-int(tt.removed) {
+				t.Errorf("first entry and length = %v, %d, want %s, %d", first.id, s.length, tt.first, 250-tt.removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,594 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxStringLen is the largest string SETRANGE and APPEND may build, which
+// is the default proto-max-bulk-len of Redis
+const maxStringLen = 512 * 1024 * 1024
+
+var (
+	errStringTooLong = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	errOverflow      = errors.New("ERR increment or decrement would overflow")
+	errNotFloat      = errors.New("ERR value is not a valid float")
+)
+
+func init() {
+	registerCommand(&command{
+		name: "set", handler: setCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.", since: "1.0.0",
+		group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "get", handler: getCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the string value of a key.", since: "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "getset", handler: getSetCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the previous string value of a key after setting it to a new value.", since: "1.0.0",
+		group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "getdel", handler: getDelCommand, arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the string value of a key after deleting the key.", since: "6.2.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "getex", handler: getExCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the string value of a key after setting its expiration time.", since: "6.2.0",
+		group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "mget", handler: mgetCommand, arity: -2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: -1, step: 1,
+		summary: "Atomically returns the string values of one or more keys.", since: "1.0.0", group: "string",
+		complexity: "O(N) where N is the number of keys to retrieve",
+	})
+	registerCommand(&command{
+		name: "mset", handler: msetCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: -1, step: 2,
+		summary: "Atomically creates or modifies the string values of one or more keys.", since: "1.0.1", group: "string",
+		complexity: "O(N) where N is the number of keys to set",
+	})
+	registerCommand(&command{
+		name: "msetnx", handler: msetnxCommand, arity: -3, flags: flagWrite, firstKey: 1, lastKey: -1, step: 2,
+		summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.", since: "1.0.1",
+		group: "string", complexity: "O(N) where N is the number of keys to set",
+	})
+	registerCommand(&command{
+		name: "setnx", handler: setnxCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Set the string value of a key only when the key doesn't exist.", since: "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "setrange", handler: setRangeCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+		since:   "2.2.0", group: "string", complexity: "O(1), not counting the time taken to copy the new string in place",
+	})
+	registerCommand(&command{
+		name: "getrange", handler: getRangeCommand, arity: 4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns a substring of the string stored at a key.", since: "2.4.0", group: "string",
+		complexity: "O(N) where N is the length of the returned string",
+	})
+	registerCommand(&command{
+		name: "strlen", handler: strlenCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the length of a string value.", since: "2.2.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "incr", handler: incrCommand, arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		since:   "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "decr", handler: decrCommand, arity: 2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		since:   "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "incrby", handler: incrByCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		since:   "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "decrby", handler: decrByCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+		since:   "1.0.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "incrbyfloat", handler: incrByFloatCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		since:   "2.6.0", group: "string", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "append", handler: appendCommand, arity: 3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.", since: "2.0.0",
+		group: "string", complexity: "O(1)",
+	})
+}
+
+// getString returns the string stored at key. It returns errWrongType if
+// key holds a value of another type.
+func (ks *keyspace) getString(key string) ([]byte, bool, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, false, nil
+	}
+	switch s := value.(type) {
+	case []byte:
+		return s, true, nil
+	case int64:
+		return strconv.AppendInt(nil, s, 10), true, nil
+	}
+	return nil, false, errWrongType
+}
+
+// getInt returns the integer stored at key, or 0 if there is none. It
+// returns errNotInteger if the string does not hold an integer.
+func (ks *keyspace) getInt(key string) (int64, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return 0, nil
+	}
+	switch s := value.(type) {
+	case int64:
+		return s, nil
+	case []byte:
+		n, ok := parseInt(s)
+		if !ok {
+			return 0, errNotInteger
+		}
+		return n, nil
+	}
+	return 0, errWrongType
+}
+
+// setString stores value under key. Strings that hold an integer are
+// stored as one; anything else is stored as a copy, since arguments point
+// into buffers that belong to the connection.
+func (ks *keyspace) setString(key string, value []byte, keepTTL bool) {
+	if n, ok := parseInt(value); ok {
+		ks.set(key, n, keepTTL)
+		return
+	}
+	ks.set(key, bytes.Clone(value), keepTTL)
+}
+
+// writeString replies with s, or with a null if the key did not exist
+func writeString(c *client, s []byte, exists bool) {
+	if !exists {
+		c.w.WriteNull()
+		return
+	}
+	c.w.WriteBulk(s)
+}
+
+// stringOptions holds the options of SET and GETEX
+type stringOptions struct {
+	nx, xx, get, keepTTL, persist bool
+	// expireUnit is the option that set the expiry, one of EX, PX, EXAT
+	// and PXAT, and expireArg its argument
+	expireUnit string
+	expireArg  []byte
+}
+
+// parseStringOptions parses the options of SET, when forSet is set, or of
+// GETEX otherwise. Options that conflict or belong to the other command are
+// syntax errors.
+func parseStringOptions(args [][]byte, forSet bool) (stringOptions, error) {
+	var opts stringOptions
+	for i := 0; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		hasExpire := opts.expireUnit != ""
+		switch {
+		case option == "NX" && forSet && !opts.xx:
+			opts.nx = true
+		case option == "XX" && forSet && !opts.nx:
+			opts.xx = true
+		case option == "GET" && forSet:
+			opts.get = true
+		case option == "KEEPTTL" && forSet && !hasExpire:
+			opts.keepTTL = true
+		case option == "PERSIST" && !forSet && !hasExpire:
+			opts.persist = true
+		case (option == "EX" || option == "PX" || option == "EXAT" || option == "PXAT") &&
+			!hasExpire && !opts.keepTTL && !opts.persist && i+1 < len(args):
+			opts.expireUnit = option
+			opts.expireArg = args[i+1]
+			i++
+		default:
+			return stringOptions{}, errSyntax
+		}
+	}
+	return opts, nil
+}
+
+// expireTime converts the argument of an EX, PX, EXAT or PXAT option to the
+// unix time in milliseconds at which the key expires, if it is now the unix
+// time now in milliseconds
+func expireTime(unit string, arg []byte, commandName string, now int64) (int64, error) {
+	invalid := errors.New("ERR invalid expire time in '" + commandName + "' command")
+	when, ok := parseInt(arg)
+	if !ok {
+		return 0, errNotInteger
+	}
+	if when <= 0 {
+		return 0, invalid
+	}
+	if unit == "EX" || unit == "EXAT" {
+		if when > math.MaxInt64/1000 {
+			return 0, invalid
+		}
+		when *= 1000
+	}
+	if unit == "EX" || unit == "PX" {
+		if when, ok = checkedAdd(when, now); !ok {
+			return 0, invalid
+		}
+	}
+	return when, nil
+}
+
+// setCommand implements
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+func setCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	opts, err := parseStringOptions(args[3:], true)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	var when int64
+	if opts.expireUnit != "" {
+		if when, err = expireTime(opts.expireUnit, opts.expireArg, "set", c.db.now()); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	var old []byte
+	var oldExists bool
+	if opts.get {
+		if old, oldExists, err = c.db.getString(key); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	_, exists := c.db.lookup(key)
+	if (opts.nx && exists) || (opts.xx && !exists) {
+		if opts.get {
+			writeString(c, old, oldExists)
+		} else {
+			c.w.WriteNull()
+		}
+		return
+	}
+
+	c.db.setString(key, args[2], opts.keepTTL)
+	if opts.expireUnit != "" {
+		c.db.setExpire(key, when)
+	}
+	if opts.get {
+		writeString(c, old, oldExists)
+		return
+	}
+	c.w.WriteSimpleString("OK")
+}
+
+// getCommand implements GET key
+func getCommand(c *client, args [][]byte) {
+	s, exists, err := c.db.getString(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	writeString(c, s, exists)
+}
+
+// getSetCommand implements GETSET key value
+func getSetCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	old, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.db.setString(key, args[2], false)
+	writeString(c, old, exists)
+}
+
+// getDelCommand implements GETDEL key
+func getDelCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	s, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if exists {
+		c.db.remove(key)
+	}
+	writeString(c, s, exists)
+}
+
+// getExCommand implements
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+func getExCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	opts, err := parseStringOptions(args[2:], false)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	var when int64
+	if opts.expireUnit != "" {
+		if when, err = expireTime(opts.expireUnit, opts.expireArg, "getex", c.db.now()); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	s, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if exists {
+		switch {
+		case opts.expireUnit != "":
+			c.db.setExpire(key, when)
+		case opts.persist:
+			c.db.persist(key)
+		}
+	}
+	writeString(c, s, exists)
+}
+
+// mgetCommand implements MGET key [key ...]. Keys that do not hold a
+// string are reported as nulls rather than errors.
+func mgetCommand(c *client, args [][]byte) {
+	c.w.WriteArrayHeader(len(args) - 1)
+	for _, key := range args[1:] {
+		s, exists, err := c.db.getString(string(key))
+		writeString(c, s, exists && err == nil)
+	}
+}
+
+// msetCommand implements MSET key value [key value ...]
+func msetCommand(c *client, args [][]byte) {
+	if len(args)%2 == 0 {
+		c.w.WriteError(wrongArityError("mset"))
+		return
+	}
+	for i := 1; i < len(args); i += 2 {
+		c.db.setString(string(args[i]), args[i+1], false)
+	}
+	c.w.WriteSimpleString("OK")
+}
+
+// msetnxCommand implements MSETNX key value [key value ...]. Nothing is set
+// if any of the keys exists.
+func msetnxCommand(c *client, args [][]byte) {
+	if len(args)%2 == 0 {
+		c.w.WriteError(wrongArityError("msetnx"))
+		return
+	}
+	for i := 1; i < len(args); i += 2 {
+		if _, exists := c.db.lookup(string(args[i])); exists {
+			c.w.WriteInteger(0)
+			return
+		}
+	}
+	for i := 1; i < len(args); i += 2 {
+		c.db.setString(string(args[i]), args[i+1], false)
+	}
+	c.w.WriteInteger(1)
+}
+
+// setnxCommand implements SETNX key value
+func setnxCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	if _, exists := c.db.lookup(key); exists {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.db.setString(key, args[2], false)
+	c.w.WriteInteger(1)
+}
+
+// setRangeCommand implements SETRANGE key offset value
+func setRangeCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	value := args[3]
+	offset, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	if offset < 0 {
+		c.w.WriteError("ERR offset is out of range")
+		return
+	}
+
+	s, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	// Nothing to write leaves the key as it is, and does not create it
+	if len(value) == 0 {
+		c.w.WriteInteger(int64(len(s)))
+		return
+	}
+	if offset+int64(len(value)) > maxStringLen {
+		c.w.WriteError(errStringTooLong.Error())
+		return
+	}
+
+	end := int(offset) + len(value)
+	updated := make([]byte, max(len(s), end))
+	copy(updated, s)
+	copy(updated[offset:], value)
+	c.db.set(key, updated, exists)
+	c.w.WriteInteger(int64(len(updated)))
+}
+
+// getRangeCommand implements GETRANGE key start end. Negative offsets count
+// from the end of the string, and the range is clamped to the string.
+func getRangeCommand(c *client, args [][]byte) {
+	start, startOK := parseInt(args[2])
+	end, endOK := parseInt(args[3])
+	if !startOK || !endOK {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	s, _, err := c.db.getString(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	length := int64(len(s))
+	if start < 0 && end < 0 && start > end {
+		c.w.WriteBulk(nil)
+		return
+	}
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if end < 0 {
+		end = max(length+end, 0)
+	}
+	end = min(end, length-1)
+	if start > end || length == 0 {
+		c.w.WriteBulk(nil)
+		return
+	}
+	c.w.WriteBulk(s[start : end+1])
+}
+
+// strlenCommand implements STRLEN key
+func strlenCommand(c *client, args [][]byte) {
+	s, _, err := c.db.getString(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteInteger(int64(len(s)))
+}
+
+// appendCommand implements APPEND key value
+func appendCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	s, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if len(s)+len(args[2]) > maxStringLen {
+		c.w.WriteError(errStringTooLong.Error())
+		return
+	}
+	updated := make([]byte, 0, len(s)+len(args[2]))
+	updated = append(append(updated, s...), args[2]...)
+	c.db.set(key, updated, exists)
+	c.w.WriteInteger(int64(len(updated)))
+}
+
+// incrBy adds increment to the integer stored at key and replies with the
+// result. The key keeps its time to live.
+func incrBy(c *client, key string, increment int64) {
+	n, err := c.db.getInt(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	n, ok := checkedAdd(n, increment)
+	if !ok {
+		c.w.WriteError(errOverflow.Error())
+		return
+	}
+	c.db.set(key, n, true)
+	c.w.WriteInteger(n)
+}
+
+// incrCommand implements INCR key
+func incrCommand(c *client, args [][]byte) {
+	incrBy(c, string(args[1]), 1)
+}
+
+// decrCommand implements DECR key
+func decrCommand(c *client, args [][]byte) {
+	incrBy(c, string(args[1]), -1)
+}
+
+// incrByCommand implements INCRBY key increment
+func incrByCommand(c *client, args [][]byte) {
+	increment, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	incrBy(c, string(args[1]), increment)
+}
+
+// decrByCommand implements DECRBY key decrement
+func decrByCommand(c *client, args [][]byte) {
+	decrement, ok := parseInt(args[2])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	// The smallest integer has no positive counterpart to add instead
+	if decrement == math.MinInt64 {
+		c.w.WriteError("ERR decrement would overflow")
+		return
+	}
+	incrBy(c, string(args[1]), -decrement)
+}
+
+// parseFloat parses b as a float the way Redis does, rejecting spaces, NaN
+// and values out of range. The underscores Go allows between digits are
+// rejected too.
+func parseFloat(b []byte) (float64, bool) {
+	if len(b) == 0 || isSpace(b[0]) || isSpace(b[len(b)-1]) || bytes.IndexByte(b, '_') >= 0 {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// incrByFloatCommand implements INCRBYFLOAT key increment
+func incrByFloatCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	increment, ok := parseFloat(args[2])
+	if !ok {
+		c.w.WriteError(errNotFloat.Error())
+		return
+	}
+	s, exists, err := c.db.getString(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	var current float64
+	if exists {
+		if current, ok = parseFloat(s); !ok {
+			c.w.WriteError(errNotFloat.Error())
+			return
+		}
+	}
+
+	result := current + increment
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		c.w.WriteError("ERR increment would produce NaN or Infinity")
+		return
+	}
+	formatted := []byte(strconv.FormatFloat(result, 'f', -1, 64))
+	c.db.setString(key, formatted, true)
+	c.w.WriteBulk(formatted)
+}
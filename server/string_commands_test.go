@@ -0,0 +1,296 @@
+package server
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_StringCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should set and get a value",
+			input: "SET k v\r\nGET k\r\n",
+			want:  "+OK\r\n$1\r\nv\r\n",
+		},
+		{
+			name:  "It should reply with a null for a missing key",
+			input: "GET k\r\n",
+			want:  "$-1\r\n",
+		},
+		{
+			name:  "It should store binary values unchanged",
+			input: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\n\x00\r\n\xff\r\nGET k\r\n",
+			want:  "+OK\r\n$4\r\n\x00\r\n\xff\r\n",
+		},
+		{
+			name:  "It should only set with NX when the key is missing",
+			input: "SET k a NX\r\nSET k b NX\r\nGET k\r\n",
+			want:  "+OK\r\n$-1\r\n$1\r\na\r\n",
+		},
+		{
+			name:  "It should only set with XX when the key exists",
+			input: "SET k a XX\r\nSET k a\r\nSET k b XX\r\nGET k\r\n",
+			want:  "$-1\r\n+OK\r\n+OK\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "It should return the old value with GET",
+			input: "SET k a GET\r\nSET k b GET\r\nSET k c NX GET\r\nGET k\r\n",
+			want:  "$-1\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "It should reject conflicting options",
+			input: "SET k v NX XX\r\nSET k v EX 10 PX 100\r\nSET k v KEEPTTL EX 10\r\nSET k v EX\r\nSET k v PERSIST\r\n",
+			want:  strings.Repeat("-ERR syntax error\r\n", 5),
+		},
+		{
+			name:  "It should reject invalid expire times",
+			input: "SET k v EX 0\r\nSET k v PX -1\r\nSET k v EX 9223372036854775807\r\nSET k v EX ten\r\n",
+			want: "-ERR invalid expire time in 'set' command\r\n-ERR invalid expire time in 'set' command\r\n" +
+				"-ERR invalid expire time in 'set' command\r\n-ERR value is not an integer or out of range\r\n",
+		},
+		{
+			name:  "It should delete a key set to expire in the past",
+			input: "SET k v PXAT 1\r\nEXISTS k\r\n",
+			want:  "+OK\r\n:0\r\n",
+		},
+		{
+			name:  "It should get and set with GETSET",
+			input: "GETSET k a\r\nGETSET k b\r\nGET k\r\n",
+			want:  "$-1\r\n$1\r\na\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "It should get and delete with GETDEL",
+			input: "SET k v\r\nGETDEL k\r\nGETDEL k\r\nEXISTS k\r\n",
+			want:  "+OK\r\n$1\r\nv\r\n$-1\r\n:0\r\n",
+		},
+		{
+			name:  "It should get and expire with GETEX",
+			input: "SET k v\r\nGETEX k PXAT 1\r\nGETEX k\r\nGETEX k KEEPTTL\r\n",
+			want:  "+OK\r\n$1\r\nv\r\n$-1\r\n-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should get several values with MGET",
+			input: "MSET a 1 b 2\r\nMGET a missing b\r\n",
+			want:  "+OK\r\n*3\r\n$1\r\n1\r\n$-1\r\n$1\r\n2\r\n",
+		},
+		{
+			name:  "It should check MSET arguments come in pairs",
+			input: "MSET a 1 b\r\n",
+			want:  "-ERR wrong number of arguments for 'mset' command\r\n",
+		},
+		{
+			name:  "It should set nothing with MSETNX if any key exists",
+			input: "MSETNX a 1 b 2\r\nMSETNX b 3 c 4\r\nMGET a b c\r\n",
+			want:  ":1\r\n:0\r\n*3\r\n$1\r\n1\r\n$1\r\n2\r\n$-1\r\n",
+		},
+		{
+			name:  "It should only set missing keys with SETNX",
+			input: "SETNX k a\r\nSETNX k b\r\nGET k\r\n",
+			want:  ":1\r\n:0\r\n$1\r\na\r\n",
+		},
+		{
+			name:  "It should overwrite part of a string with SETRANGE",
+			input: "SET k \"Hello World\"\r\nSETRANGE k 6 Redis\r\nGET k\r\n",
+			want:  "+OK\r\n:11\r\n$11\r\nHello Redis\r\n",
+		},
+		{
+			name:  "It should pad with zero bytes in SETRANGE",
+			input: "SETRANGE k 3 ab\r\nGET k\r\nSETRANGE missing 5 \"\"\r\nEXISTS missing\r\n",
+			want:  ":5\r\n$5\r\n\x00\x00\x00ab\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should reject SETRANGE offsets out of range",
+			input: "SETRANGE k -1 a\r\nSETRANGE k 536870911 ab\r\n",
+			want:  "-ERR offset is out of range\r\n-ERR string exceeds maximum allowed size (proto-max-bulk-len)\r\n",
+		},
+		{
+			name:  "It should return substrings with GETRANGE",
+			input: "SET k \"This is a string\"\r\nGETRANGE k 0 3\r\nGETRANGE k -3 -1\r\nGETRANGE k 0 -1\r\nGETRANGE k 10 100\r\nGETRANGE k 5 3\r\nGETRANGE k -1 -5\r\nGETRANGE missing 0 -1\r\n",
+			want:  "+OK\r\n$4\r\nThis\r\n$3\r\ning\r\n$16\r\nThis is a string\r\n$6\r\nstring\r\n$0\r\n\r\n$0\r\n\r\n$0\r\n\r\n",
+		},
+		{
+			name:  "It should return the length with STRLEN",
+			input: "SET k hello\r\nSTRLEN k\r\nSTRLEN missing\r\n",
+			want:  "+OK\r\n:5\r\n:0\r\n",
+		},
+		{
+			name:  "It should append to a string",
+			input: "APPEND k Hello\r\nAPPEND k \" World\"\r\nGET k\r\n",
+			want:  ":5\r\n:11\r\n$11\r\nHello World\r\n",
+		},
+		{
+			name:  "It should reply with a null in RESP3",
+			input: "HELLO 3\r\nGET k\r\n",
+			want:  "_\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(Config{}).HandleRequest(tt.input)
+			if strings.HasPrefix(tt.input, "HELLO") {
+				got = got[strings.LastIndex(got, "*0\r\n")+4:]
+			}
+			if got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_StringCommandsKeepTTL(t *testing.T) {
+	clock := newFakeClock()
+	srv := New(Config{Clock: clock})
+	srv.HandleRequest("SET k v EX 100\r\nSET kept v EX 100\r\n")
+	clock.Advance(10 * time.Second)
+	srv.HandleRequest("SET kept w KEEPTTL\r\nAPPEND kept x\r\nSET k w\r\n")
+
+	if _, ok := srv.db.expires["k"]; ok {
+		t.Errorf("SET without KEEPTTL kept the time to live")
+	}
+	when, ok := srv.db.expires["kept"]
+	if !ok {
+		t.Fatalf("SET KEEPTTL and APPEND lost the time to live")
+	}
+	if remaining := time.Duration(when-srv.db.now()) * time.Millisecond; remaining != 90*time.Second {
+		t.Errorf("time to live = %v, want 90s", remaining)
+	}
+
+	srv.HandleRequest("GETEX kept PERSIST\r\n")
+	if _, ok := srv.db.expires["kept"]; ok {
+		t.Errorf("GETEX PERSIST kept the time to live")
+	}
+}
+
+func Test_StringCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.db.set("other", struct{}{}, false)
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"GET other", "GETSET other v", "GETDEL other", "GETEX other", "SET other v GET",
+		"SETRANGE other 0 v", "GETRANGE other 0 -1", "STRLEN other", "APPEND other v",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+
+	want := "*1\r\n$-1\r\n+OK\r\n$1\r\nv\r\n"
+	if got := srv.HandleRequest("MGET other\r\nSET other v\r\nGET other\r\n"); got != want {
+		t.Errorf("HandleRequest() = %q, want %q", got, want)
+	}
+}
+
+func Test_expireTime(t *testing.T) {
+	const now = 1700000000000
+	tests := []struct {
+		unit string
+		arg  int64
+		want int64
+	}{
+		{unit: "EX", arg: 10, want: now + 10000},
+		{unit: "PX", arg: 10, want: now + 10},
+		{unit: "EXAT", arg: 10, want: 10000},
+		{unit: "PXAT", arg: 10, want: 10},
+	}
+	for _, tt := range tests {
+		got, err := expireTime(tt.unit, []byte(strconv.FormatInt(tt.arg, 10)), "set", now)
+		if err != nil {
+			t.Fatalf("expireTime(%s) error = %v", tt.unit, err)
+		}
+		if got != tt.want {
+			t.Errorf("expireTime(%s, %d) = %d, want %d", tt.unit, tt.arg, got, tt.want)
+		}
+	}
+}
+
+func Test_CounterCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should start counters at zero",
+			input: "INCR a\r\nDECR b\r\nINCRBY c 5\r\nDECRBY d 5\r\n",
+			want:  ":1\r\n:-1\r\n:5\r\n:-5\r\n",
+		},
+		{
+			name:  "It should increment an integer string",
+			input: "SET k 10\r\nINCR k\r\nINCRBY k -20\r\nDECRBY k -1\r\nGET k\r\n",
+			want:  "+OK\r\n:11\r\n:-9\r\n:-8\r\n$2\r\n-8\r\n",
+		},
+		{
+			name:  "It should reject strings that are not integers",
+			input: "SET a abc\r\nINCR a\r\nSET b \" 1\"\r\nINCR b\r\nSET c 01\r\nINCR c\r\nSET d 1.5\r\nINCR d\r\n",
+			want:  strings.Repeat("+OK\r\n-ERR value is not an integer or out of range\r\n", 4),
+		},
+		{
+			name:  "It should reject increments that are not integers",
+			input: "INCRBY k 1.5\r\nDECRBY k x\r\n",
+			want:  "-ERR value is not an integer or out of range\r\n-ERR value is not an integer or out of range\r\n",
+		},
+		{
+			name:  "It should detect overflow",
+			input: "SET k 9223372036854775807\r\nINCR k\r\nSET k -9223372036854775808\r\nDECR k\r\nINCRBY k -1\r\nGET k\r\n",
+			want: "+OK\r\n-ERR increment or decrement would overflow\r\n+OK\r\n-ERR increment or decrement would overflow\r\n" +
+				"-ERR increment or decrement would overflow\r\n$20\r\n-9223372036854775808\r\n",
+		},
+		{
+			name:  "It should refuse to negate the smallest decrement",
+			input: "DECRBY k -9223372036854775808\r\n",
+			want:  "-ERR decrement would overflow\r\n",
+		},
+		{
+			name:  "It should increment by a float",
+			input: "SET k 10.50\r\nINCRBYFLOAT k 0.1\r\nINCRBYFLOAT k -5\r\nSET e 5.0e3\r\nINCRBYFLOAT e 2.0e2\r\nINCRBYFLOAT n 3\r\n",
+			want:  "+OK\r\n$4\r\n10.6\r\n$3\r\n5.6\r\n+OK\r\n$4\r\n5200\r\n$1\r\n3\r\n",
+		},
+		{
+			name:  "It should reject invalid floats",
+			input: "INCRBYFLOAT k abc\r\nINCRBYFLOAT k nan\r\nINCRBYFLOAT k 1_5\r\nSET k abc\r\nINCRBYFLOAT k 1\r\n",
+			want: "-ERR value is not a valid float\r\n-ERR value is not a valid float\r\n-ERR value is not a valid float\r\n" +
+				"+OK\r\n-ERR value is not a valid float\r\n",
+		},
+		{
+			name:  "It should refuse to produce infinity",
+			input: "INCRBYFLOAT k inf\r\nSET k 1.7e308\r\nINCRBYFLOAT k 1.7e308\r\n",
+			want:  "-ERR increment would produce NaN or Infinity\r\n+OK\r\n-ERR increment would produce NaN or Infinity\r\n",
+		},
+		{
+			name:  "It should work with the other string commands",
+			input: "INCRBY k 100\r\nAPPEND k 5\r\nSTRLEN k\r\nINCR k\r\nGETRANGE k 0 1\r\n",
+			want:  ":100\r\n:4\r\n:4\r\n:1006\r\n$2\r\n10\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CountersAreStoredAsIntegers(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET a 42\r\nINCR b\r\nSET c 042\r\nSET d 1e3\r\n")
+
+	for key, want := range map[string]any{"a": int64(42), "b": int64(1), "c": []byte("042"), "d": []byte("1e3")} {
+		if got := srv.db.data[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s is stored as %#v, want %#v", key, got, want)
+		}
+	}
+
+	srv.HandleRequest("SET e 1 EX 100\r\nINCR e\r\n")
+	if _, ok := srv.db.expires["e"]; !ok {
+		t.Errorf("INCR removed the time to live")
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+// zset is the sorted set type. Like in Redis, a map finds the score of a
+// member in O(1) and a skiplist keeps the members in order for ranges and
+// ranks.
+type zset struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+// zsetElement is a member of a sorted set with its score
+type zsetElement struct {
+	member string
+	score  float64
+}
+
+func newZset() *zset {
+	return &zset{dict: map[string]float64{}, zsl: newSkiplist()}
+}
+
+func (z *zset) len() int {
+	return len(z.dict)
+}
+
+// score returns the score of member
+func (z *zset) score(member string) (float64, bool) {
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// add sets the score of member, and reports whether member is new
+func (z *zset) add(member string, score float64) bool {
+	current, ok := z.dict[member]
+	if !ok {
+		z.zsl.insert(score, member)
+		z.dict[member] = score
+		return true
+	}
+	if current != score {
+		z.zsl.updateScore(current, member, score)
+		z.dict[member] = score
+	}
+	return false
+}
+
+// remove removes member, and reports whether it was there
+func (z *zset) remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// rank returns the rank of member counting from 0, from the highest score
+// down if reverse is set
+func (z *zset) rank(member string, reverse bool) (int, bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false
+	}
+	rank := z.zsl.rank(score, member)
+	if reverse {
+		return z.len() - rank, true
+	}
+	return rank - 1, true
+}
+
+// count returns how many members have a score in r
+func (z *zset) count(r scoreRange) int {
+	first := z.zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// lexCount returns how many members are in r
+func (z *zset) lexCount(r lexRange) int {
+	first := z.zsl.firstInLexRange(r)
+	if first == nil {
+		return 0
+	}
+	last := z.zsl.lastInLexRange(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// pop removes and returns up to count elements with the lowest scores, or
+// the highest if max is set
+func (z *zset) pop(count int, max bool) []zsetElement {
+	elements := make([]zsetElement, 0, min(count, z.len()))
+	for len(elements) < count && z.len() > 0 {
+		x := z.zsl.header.level[0].forward
+		if max {
+			x = z.zsl.tail
+		}
+		elements = append(elements, zsetElement{x.member, x.score})
+		z.remove(x.member)
+	}
+	return elements
+}
+
+// elements returns the elements of z in order
+func (z *zset) elements() []zsetElement {
+	elements := make([]zsetElement, 0, z.len())
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		elements = append(elements, zsetElement{x.member, x.score})
+	}
+	return elements
+}
+
+// rangeByRank returns the elements from rank start to rank stop, counting
+// from the highest score down if reverse is set. Negative ranks count from
+// the other end.
+func (z *zset) rangeByRank(start, stop int64, reverse bool) []zsetElement {
+	length := int64(z.len())
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+	if start > stop || start >= length {
+		return nil
+	}
+	stop = min(stop, length-1)
+	x := z.zsl.byRank(int(start + 1))
+	if reverse {
+		x = z.zsl.byRank(int(length - start))
+	}
+	return collectElements(x, reverse, 0, stop-start+1, func(*skiplistNode) bool { return true })
+}
+
+// rangeByScore returns the elements with a score in r, from the highest
+// score down if reverse is set, skipping offset elements and returning at
+// most limit unless it is negative
+func (z *zset) rangeByScore(r scoreRange, reverse bool, offset, limit int64) []zsetElement {
+	if reverse {
+		return collectElements(z.zsl.lastInRange(r), true, offset, limit, func(x *skiplistNode) bool { return r.gteMin(x.score) })
+	}
+	return collectElements(z.zsl.firstInRange(r), false, offset, limit, func(x *skiplistNode) bool { return r.lteMax(x.score) })
+}
+
+// rangeByLex is rangeByScore for a range of members
+func (z *zset) rangeByLex(r lexRange, reverse bool, offset, limit int64) []zsetElement {
+	if reverse {
+		return collectElements(z.zsl.lastInLexRange(r), true, offset, limit, func(x *skiplistNode) bool { return r.gteMin(x.member) })
+	}
+	return collectElements(z.zsl.firstInLexRange(r), false, offset, limit, func(x *skiplistNode) bool { return r.lteMax(x.member) })
+}
+
+// collectElements walks the skiplist from x, backwards if reverse is set,
+// skips offset elements and then collects elements while in reports that
+// they are in range, up to limit of them unless it is negative. Like in
+// Redis, a negative offset skips everything.
+func collectElements(x *skiplistNode, reverse bool, offset, limit int64, in func(*skiplistNode) bool) []zsetElement {
+	next := func(x *skiplistNode) *skiplistNode {
+		if reverse {
+			return x.backward
+		}
+		return x.level[0].forward
+	}
+	if offset < 0 {
+		return nil
+	}
+	for ; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	var elements []zsetElement
+	for ; x != nil && limit != 0 && in(x); limit-- {
+		elements = append(elements, zsetElement{x.member, x.score})
+		x = next(x)
+	}
+	return elements
+}
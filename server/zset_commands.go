@@ -0,0 +1,1403 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+var (
+	errMinMaxNotFloat  = errors.New("ERR min or max is not a float")
+	errMinMaxNotString = errors.New("ERR min or max not valid string range item")
+	errScoreNaN        = errors.New("ERR resulting score is not a number (NaN)")
+)
+
+func init() {
+	registerCommand(&command{
+		name: "zadd", handler: zaddCommand, arity: -4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+		since:   "1.2.0", group: "sorted-set",
+		complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "zincrby", handler: zincrbyCommand, arity: 4, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Increments the score of a member in a sorted set.", since: "1.2.0", group: "sorted-set",
+		complexity: "O(log(N)) where N is the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "zrem", handler: zremCommand, arity: -3, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+		since:   "1.2.0", group: "sorted-set",
+		complexity: "O(M*log(N)) with N being the number of elements in the sorted set and M the number of elements to be removed.",
+	})
+	registerCommand(&command{
+		name: "zscore", handler: zscoreCommand, arity: 3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the score of a member in a sorted set.", since: "1.2.0", group: "sorted-set", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "zmscore", handler: zmscoreCommand, arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the score of one or more members in a sorted set.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(N) where N is the number of members being requested.",
+	})
+	registerCommand(&command{
+		name: "zcard", handler: zcardCommand, arity: 2, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the number of members in a sorted set.", since: "1.2.0", group: "sorted-set", complexity: "O(1)",
+	})
+	registerCommand(&command{
+		name: "zcount", handler: zcountCommand, arity: 4, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the count of members in a sorted set that have scores within a range.", since: "2.0.0",
+		group: "sorted-set", complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "zrank", handler: zrankCommand, arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the index of a member in a sorted set ordered by ascending scores.", since: "2.0.0",
+		group: "sorted-set", complexity: "O(log(N))",
+	})
+	registerCommand(&command{
+		name: "zrevrank", handler: zrevrankCommand, arity: -3, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the index of a member in a sorted set ordered by descending scores.", since: "2.0.0",
+		group: "sorted-set", complexity: "O(log(N))",
+	})
+	registerCommand(&command{
+		name: "zrange", handler: zrangeCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a range of indexes.", since: "1.2.0", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+	})
+	registerCommand(&command{
+		name: "zrangestore", handler: zrangestoreCommand, arity: -5, flags: flagWrite, firstKey: 1, lastKey: 2, step: 1,
+		summary: "Stores a range of members from sorted set in a key.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements stored into the destination key.",
+	})
+	registerCommand(&command{
+		name: "zrevrange", handler: zrevrangeCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a range of indexes in reverse order.", since: "1.2.0",
+		group: "sorted-set", complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+	})
+	registerCommand(&command{
+		name: "zrangebyscore", handler: zrangebyscoreCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a range of scores.", since: "1.0.5", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+	})
+	registerCommand(&command{
+		name: "zrevrangebyscore", handler: zrevrangebyscoreCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a range of scores in reverse order.", since: "2.2.0",
+		group: "sorted-set", complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+	})
+	registerCommand(&command{
+		name: "zrangebylex", handler: zrangebylexCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a lexicographical range.", since: "2.8.9", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+	})
+	registerCommand(&command{
+		name: "zrevrangebylex", handler: zrevrangebylexCommand, arity: -4, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns members in a sorted set within a lexicographical range in reverse order.", since: "2.8.9",
+		group: "sorted-set", complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+	})
+	registerCommand(&command{
+		name: "zpopmin", handler: zpopminCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		since:   "5.0.0", group: "sorted-set",
+		complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+	})
+	registerCommand(&command{
+		name: "zpopmax", handler: zpopmaxCommand, arity: -2, flags: flagWrite | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+		since:   "5.0.0", group: "sorted-set",
+		complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+	})
+	registerCommand(&command{
+		name: "zrandmember", handler: zrandmemberCommand, arity: -2, flags: flagReadonly, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns one or more random members from a sorted set.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(N) where N is the number of members returned",
+	})
+	registerCommand(&command{
+		name: "zunion", handler: zunionCommand, arity: -3, flags: flagReadonly, keyNumIndex: 1,
+		summary: "Returns the union of multiple sorted sets.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(N)+O(M*log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set.",
+	})
+	registerCommand(&command{
+		name: "zunionstore", handler: zunionstoreCommand, arity: -4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1, keyNumIndex: 2,
+		summary: "Stores the union of multiple sorted sets in a key.", since: "2.0.0", group: "sorted-set",
+		complexity: "O(N)+O(M log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set.",
+	})
+	registerCommand(&command{
+		name: "zinter", handler: zinterCommand, arity: -3, flags: flagReadonly, keyNumIndex: 1,
+		summary: "Returns the intersect of multiple sorted sets.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set.",
+	})
+	registerCommand(&command{
+		name: "zinterstore", handler: zinterstoreCommand, arity: -4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1, keyNumIndex: 2,
+		summary: "Stores the intersect of multiple sorted sets in a key.", since: "2.0.0", group: "sorted-set",
+		complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set.",
+	})
+	registerCommand(&command{
+		name: "zintercard", handler: zintercardCommand, arity: -3, flags: flagReadonly, keyNumIndex: 1,
+		summary: "Returns the number of members of the intersect of multiple sorted sets.", since: "7.0.0", group: "sorted-set",
+		complexity: "O(N*K) worst case with N being the smallest input sorted set, K being the number of input sorted sets.",
+	})
+	registerCommand(&command{
+		name: "zdiff", handler: zdiffCommand, arity: -3, flags: flagReadonly, keyNumIndex: 1,
+		summary: "Returns the difference between multiple sorted sets.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(L + (N-K)log(N)) worst case where L is the total number of elements in all the sets, N is the size of the first set, and K is the size of the result set.",
+	})
+	registerCommand(&command{
+		name: "zdiffstore", handler: zdiffstoreCommand, arity: -4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1, keyNumIndex: 2,
+		summary: "Stores the difference of multiple sorted sets in a key.", since: "6.2.0", group: "sorted-set",
+		complexity: "O(L + (N-K)log(N)) worst case where L is the total number of elements in all the sets, N is the size of the first set, and K is the size of the result set.",
+	})
+	registerCommand(&command{
+		name: "zlexcount", handler: zlexcountCommand, arity: 4, flags: flagReadonly | flagFast, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Returns the number of members in a sorted set within a lexicographical range.", since: "2.8.9",
+		group: "sorted-set", complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "zremrangebyrank", handler: zremrangebyrankCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes members in a sorted set within a range of indexes. Deletes the sorted set if all members were removed.",
+		since:   "2.0.0", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation.",
+	})
+	registerCommand(&command{
+		name: "zremrangebyscore", handler: zremrangebyscoreCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes members in a sorted set within a range of scores. Deletes the sorted set if all members were removed.",
+		since:   "1.2.0", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation.",
+	})
+	registerCommand(&command{
+		name: "zremrangebylex", handler: zremrangebylexCommand, arity: 4, flags: flagWrite, firstKey: 1, lastKey: 1, step: 1,
+		summary: "Removes members in a sorted set within a lexicographical range. Deletes the sorted set if all members were removed.",
+		since:   "2.8.9", group: "sorted-set",
+		complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements removed by the operation.",
+	})
+	registerCommand(&command{
+		name: "zmpop", handler: zmpopCommand, arity: -4, flags: flagWrite, keyNumIndex: 1,
+		summary: "Returns the highest- or lowest-scoring members from one or more sorted sets after removing them. Deletes the sorted set if the last member was popped.",
+		since:   "7.0.0", group: "sorted-set",
+		complexity: "O(K) + O(M*log(N)) where K is the number of provided keys, N being the number of elements in the sorted set, and M being the number of elements popped.",
+	})
+	registerCommand(&command{
+		name: "bzpopmin", handler: bzpopminCommand, arity: -3, flags: flagWrite | flagFast | flagBlocking, firstKey: 1, lastKey: -2, step: 1,
+		summary: "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		since:   "5.0.0", group: "sorted-set", complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "bzpopmax", handler: bzpopmaxCommand, arity: -3, flags: flagWrite | flagFast | flagBlocking, firstKey: 1, lastKey: -2, step: 1,
+		summary: "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		since:   "5.0.0", group: "sorted-set", complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+	})
+	registerCommand(&command{
+		name: "bzmpop", handler: bzmpopCommand, arity: -5, flags: flagWrite | flagBlocking, keyNumIndex: 2,
+		summary: "Removes and returns a member by score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+		since:   "7.0.0", group: "sorted-set",
+		complexity: "O(K) + O(M*log(N)) where K is the number of provided keys, N being the number of elements in the sorted set, and M being the number of elements popped.",
+	})
+}
+
+// getZset returns the sorted set stored at key, or nil if there is none.
+// It returns errWrongType if key holds a value of another type.
+func (ks *keyspace) getZset(key string) (*zset, error) {
+	value, ok := ks.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	z, ok := value.(*zset)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, nil
+}
+
+// writeZsetElements replies with the members of elements, and their scores
+// if withScores is set. RESP3 clients get each member and score as a pair
+// of their own, like Redis does.
+func writeZsetElements(c *client, elements []zsetElement, withScores bool) {
+	writeZsetElementsHeader(c, len(elements), withScores)
+	for _, e := range elements {
+		writeZsetElement(c, e, withScores)
+	}
+}
+
+// writeZsetElementsHeader starts the reply of writeZsetElements for n
+// elements
+func writeZsetElementsHeader(c *client, n int, withScores bool) {
+	if withScores && c.w.Protocol() < 3 {
+		n *= 2
+	}
+	c.w.WriteArrayHeader(n)
+}
+
+// writeZsetElement writes an element of the reply of writeZsetElements
+func writeZsetElement(c *client, e zsetElement, withScores bool) {
+	if withScores && c.w.Protocol() >= 3 {
+		c.w.WriteArrayHeader(2)
+	}
+	c.w.WriteBulkString(e.member)
+	if withScores {
+		c.w.WriteDouble(e.score)
+	}
+}
+
+// zaddOptions are the flags of ZADD
+type zaddOptions struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+// zaddResult is what zaddMember did to a member
+type zaddResult int
+
+const (
+	// zaddSkipped means the options of ZADD ruled the member out
+	zaddSkipped zaddResult = iota
+	zaddAdded
+	zaddUpdated
+	zaddUnchanged
+)
+
+// zaddMember sets the score of member the way the options of ZADD say,
+// adding score to the current one with INCR, and returns the new score
+func zaddMember(z *zset, member string, score float64, opts zaddOptions) (float64, zaddResult, error) {
+	current, exists := z.score(member)
+	if !exists {
+		if opts.xx {
+			return 0, zaddSkipped, nil
+		}
+		z.add(member, score)
+		return score, zaddAdded, nil
+	}
+	if opts.nx {
+		return 0, zaddSkipped, nil
+	}
+	if opts.incr {
+		score += current
+		if math.IsNaN(score) {
+			return 0, zaddSkipped, errScoreNaN
+		}
+	}
+	// A member keeps its score unless the new one is greater for GT, or
+	// lower for LT
+	if (opts.gt && score <= current) || (opts.lt && score >= current) {
+		return 0, zaddSkipped, nil
+	}
+	if score == current {
+		return score, zaddUnchanged, nil
+	}
+	z.add(member, score)
+	return score, zaddUpdated, nil
+}
+
+// zaddCommand implements
+// ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...]
+func zaddCommand(c *client, args [][]byte) {
+	var opts zaddOptions
+	i := 2
+flags:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX":
+			opts.nx = true
+		case "XX":
+			opts.xx = true
+		case "GT":
+			opts.gt = true
+		case "LT":
+			opts.lt = true
+		case "CH":
+			opts.ch = true
+		case "INCR":
+			opts.incr = true
+		default:
+			break flags
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	if opts.nx && opts.xx {
+		c.w.WriteError("ERR XX and NX options at the same time are not compatible")
+		return
+	}
+	if (opts.gt && opts.nx) || (opts.lt && opts.nx) || (opts.gt && opts.lt) {
+		c.w.WriteError("ERR GT, LT, and/or NX options at the same time are not compatible")
+		return
+	}
+	if opts.incr && len(pairs) > 2 {
+		c.w.WriteError("ERR INCR option supports a single increment-element pair")
+		return
+	}
+	scores := make([]float64, len(pairs)/2)
+	for j := range scores {
+		score, ok := parseFloat(pairs[2*j])
+		if !ok {
+			c.w.WriteError(errNotFloat.Error())
+			return
+		}
+		scores[j] = score
+	}
+
+	key := string(args[1])
+	z, err := c.db.getZset(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		if opts.xx {
+			// Nothing could be updated, so do not create an empty key
+			if opts.incr {
+				c.w.WriteNull()
+			} else {
+				c.w.WriteInteger(0)
+			}
+			return
+		}
+		z = newZset()
+		c.db.set(key, z, false)
+	}
+
+	var added, updated int64
+	var score float64
+	var result zaddResult
+	for j := range scores {
+		score, result, err = zaddMember(z, string(pairs[2*j+1]), scores[j], opts)
+		if err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+		switch result {
+		case zaddAdded:
+			added++
+		case zaddUpdated:
+			updated++
+		}
+	}
+	c.db.signalKeyAsReady(key)
+	switch {
+	case opts.incr && result != zaddSkipped:
+		c.w.WriteDouble(score)
+	case opts.incr:
+		c.w.WriteNull()
+	case opts.ch:
+		c.w.WriteInteger(added + updated)
+	default:
+		c.w.WriteInteger(added)
+	}
+}
+
+// zincrbyCommand implements ZINCRBY key increment member
+func zincrbyCommand(c *client, args [][]byte) {
+	increment, ok := parseFloat(args[2])
+	if !ok {
+		c.w.WriteError(errNotFloat.Error())
+		return
+	}
+	key := string(args[1])
+	z, err := c.db.getZset(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		z = newZset()
+		c.db.set(key, z, false)
+	}
+	score, _, err := zaddMember(z, string(args[3]), increment, zaddOptions{incr: true})
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.db.signalKeyAsReady(key)
+	c.w.WriteDouble(score)
+}
+
+// zremCommand implements ZREM key member [member ...]
+func zremCommand(c *client, args [][]byte) {
+	key := string(args[1])
+	z, err := c.db.getZset(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	var removed int64
+	for _, member := range args[2:] {
+		if z.remove(string(member)) {
+			removed++
+		}
+	}
+	if z.len() == 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteInteger(removed)
+}
+
+// zscoreCommand implements ZSCORE key member
+func zscoreCommand(c *client, args [][]byte) {
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteNull()
+		return
+	}
+	score, ok := z.score(string(args[2]))
+	if !ok {
+		c.w.WriteNull()
+		return
+	}
+	c.w.WriteDouble(score)
+}
+
+// zmscoreCommand implements ZMSCORE key member [member ...]
+func zmscoreCommand(c *client, args [][]byte) {
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteArrayHeader(len(args) - 2)
+	for _, member := range args[2:] {
+		if z == nil {
+			c.w.WriteNull()
+			continue
+		}
+		if score, ok := z.score(string(member)); ok {
+			c.w.WriteDouble(score)
+		} else {
+			c.w.WriteNull()
+		}
+	}
+}
+
+// zcardCommand implements ZCARD key
+func zcardCommand(c *client, args [][]byte) {
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(z.len()))
+}
+
+// parseScoreRange parses the min and max arguments of ZRANGEBYSCORE and
+// friends. A score preceded by ( is excluded from the range.
+func parseScoreRange(minArg, maxArg []byte) (scoreRange, error) {
+	var r scoreRange
+	var minOk, maxOk bool
+	r.min, r.minex, minOk = parseScoreBound(minArg)
+	r.max, r.maxex, maxOk = parseScoreBound(maxArg)
+	if !minOk || !maxOk {
+		return scoreRange{}, errMinMaxNotFloat
+	}
+	return r, nil
+}
+
+func parseScoreBound(b []byte) (float64, bool, bool) {
+	exclusive := len(b) > 0 && b[0] == '('
+	if exclusive {
+		b = b[1:]
+	}
+	score, ok := parseFloat(b)
+	return score, exclusive, ok
+}
+
+// parseLexRange parses the min and max arguments of ZRANGEBYLEX and
+// friends, which are a member preceded by [ to include it or ( to exclude
+// it, or - and + for the ends of the set
+func parseLexRange(minArg, maxArg []byte) (lexRange, error) {
+	var r lexRange
+	var minOk, maxOk bool
+	r.min, minOk = parseLexBound(minArg)
+	r.max, maxOk = parseLexBound(maxArg)
+	if !minOk || !maxOk {
+		return lexRange{}, errMinMaxNotString
+	}
+	return r, nil
+}
+
+func parseLexBound(b []byte) (lexBound, bool) {
+	switch {
+	case string(b) == "-":
+		return lexBound{inf: -1, exclusive: true}, true
+	case string(b) == "+":
+		return lexBound{inf: 1, exclusive: true}, true
+	case len(b) > 0 && b[0] == '(':
+		return lexBound{value: string(b[1:]), exclusive: true}, true
+	case len(b) > 0 && b[0] == '[':
+		return lexBound{value: string(b[1:])}, true
+	}
+	return lexBound{}, false
+}
+
+// zcountCommand implements ZCOUNT key min max
+func zcountCommand(c *client, args [][]byte) {
+	r, err := parseScoreRange(args[2], args[3])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(z.count(r)))
+}
+
+// zrankGeneric implements ZRANK and ZREVRANK
+func zrankGeneric(c *client, args [][]byte, reverse bool) {
+	if len(args) > 4 || (len(args) == 4 && !strings.EqualFold(string(args[3]), "WITHSCORE")) {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	withScore := len(args) == 4
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	var rank int
+	ok := false
+	if z != nil {
+		rank, ok = z.rank(string(args[2]), reverse)
+	}
+	switch {
+	case !ok && withScore:
+		c.w.WriteNullArray()
+	case !ok:
+		c.w.WriteNull()
+	case withScore:
+		score, _ := z.score(string(args[2]))
+		c.w.WriteArrayHeader(2)
+		c.w.WriteInteger(int64(rank))
+		c.w.WriteDouble(score)
+	default:
+		c.w.WriteInteger(int64(rank))
+	}
+}
+
+// zrankCommand implements ZRANK key member [WITHSCORE]
+func zrankCommand(c *client, args [][]byte) {
+	zrankGeneric(c, args, false)
+}
+
+// zrevrankCommand implements ZREVRANK key member [WITHSCORE]
+func zrevrankCommand(c *client, args [][]byte) {
+	zrankGeneric(c, args, true)
+}
+
+// zrangeType is what the range of a ZRANGE is made of
+type zrangeType int
+
+const (
+	// zrangeAuto lets the BYSCORE and BYLEX options decide
+	zrangeAuto zrangeType = iota
+	zrangeRank
+	zrangeScore
+	zrangeLex
+)
+
+// zrangeDirection is the order of the reply of a ZRANGE
+type zrangeDirection int
+
+const (
+	// zrangeDirectionAuto lets the REV option decide
+	zrangeDirectionAuto zrangeDirection = iota
+	zrangeForward
+	zrangeReverse
+)
+
+// zrangeGeneric implements ZRANGE, ZRANGESTORE and their older variants,
+// like zrangeGenericCommand does in Redis. The source key is args[keyAt],
+// followed by the range and the options. With store, the result goes to
+// the key args[1] instead of the reply. rangeType and direction are preset
+// by the older variants, which do not take the options that set them.
+func zrangeGeneric(c *client, args [][]byte, keyAt int, store bool, rangeType zrangeType, direction zrangeDirection) {
+	minArg, maxArg := args[keyAt+1], args[keyAt+2]
+	var withScores, hasLimit bool
+	var offset, limit int64 = 0, -1
+	for j := keyAt + 3; j < len(args); j++ {
+		option := strings.ToUpper(string(args[j]))
+		switch {
+		case option == "WITHSCORES" && !store:
+			withScores = true
+		case option == "LIMIT" && j+2 < len(args):
+			var offsetOk, limitOk bool
+			offset, offsetOk = parseInt(args[j+1])
+			limit, limitOk = parseInt(args[j+2])
+			if !offsetOk || !limitOk {
+				c.w.WriteError(errNotInteger.Error())
+				return
+			}
+			hasLimit = true
+			j += 2
+		case option == "REV" && direction == zrangeDirectionAuto:
+			direction = zrangeReverse
+		case option == "BYSCORE" && rangeType == zrangeAuto:
+			rangeType = zrangeScore
+		case option == "BYLEX" && rangeType == zrangeAuto:
+			rangeType = zrangeLex
+		default:
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+	}
+	if rangeType == zrangeAuto {
+		rangeType = zrangeRank
+	}
+	if hasLimit && rangeType == zrangeRank {
+		c.w.WriteError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+		return
+	}
+	if withScores && rangeType == zrangeLex {
+		c.w.WriteError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+		return
+	}
+	reverse := direction == zrangeReverse
+	// Reversed score and lex ranges are given as max then min
+	if reverse && rangeType != zrangeRank {
+		minArg, maxArg = maxArg, minArg
+	}
+
+	var start, stop int64
+	var sr scoreRange
+	var lr lexRange
+	var err error
+	switch rangeType {
+	case zrangeRank:
+		var startOk, stopOk bool
+		start, startOk = parseInt(minArg)
+		stop, stopOk = parseInt(maxArg)
+		if !startOk || !stopOk {
+			err = errNotInteger
+		}
+	case zrangeScore:
+		sr, err = parseScoreRange(minArg, maxArg)
+	case zrangeLex:
+		lr, err = parseLexRange(minArg, maxArg)
+	}
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	z, err := c.db.getZset(string(args[keyAt]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	var elements []zsetElement
+	if z != nil {
+		switch rangeType {
+		case zrangeRank:
+			elements = z.rangeByRank(start, stop, reverse)
+		case zrangeScore:
+			elements = z.rangeByScore(sr, reverse, offset, limit)
+		case zrangeLex:
+			elements = z.rangeByLex(lr, reverse, offset, limit)
+		}
+	}
+
+	if !store {
+		writeZsetElements(c, elements, withScores)
+		return
+	}
+	dst := string(args[1])
+	if len(elements) == 0 {
+		c.db.remove(dst)
+		c.w.WriteInteger(0)
+		return
+	}
+	result := newZset()
+	for _, e := range elements {
+		result.add(e.member, e.score)
+	}
+	c.db.set(dst, result, false)
+	c.db.signalKeyAsReady(dst)
+	c.w.WriteInteger(int64(result.len()))
+}
+
+// zrangeCommand implements
+// ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func zrangeCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeAuto, zrangeDirectionAuto)
+}
+
+// zrangestoreCommand implements
+// ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+func zrangestoreCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 2, true, zrangeAuto, zrangeDirectionAuto)
+}
+
+// zrevrangeCommand implements ZREVRANGE key start stop [WITHSCORES]
+func zrevrangeCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeRank, zrangeReverse)
+}
+
+// zrangebyscoreCommand implements
+// ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+func zrangebyscoreCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeScore, zrangeForward)
+}
+
+// zrevrangebyscoreCommand implements
+// ZREVRANGEBYSCORE key max min [WITHSCORES] [LIMIT offset count]
+func zrevrangebyscoreCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeScore, zrangeReverse)
+}
+
+// zrangebylexCommand implements ZRANGEBYLEX key min max [LIMIT offset count]
+func zrangebylexCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeLex, zrangeForward)
+}
+
+// zrevrangebylexCommand implements ZREVRANGEBYLEX key max min [LIMIT offset count]
+func zrevrangebylexCommand(c *client, args [][]byte) {
+	zrangeGeneric(c, args, 1, false, zrangeLex, zrangeReverse)
+}
+
+// popZset pops up to count elements with the lowest scores from z, or the
+// highest if max is set, and deletes key once z is empty
+func popZset(ks *keyspace, key string, z *zset, count int64, max bool) []zsetElement {
+	elements := z.pop(int(min(count, int64(z.len()))), max)
+	if z.len() == 0 {
+		ks.remove(key)
+	}
+	return elements
+}
+
+// zpopGeneric implements ZPOPMIN and ZPOPMAX
+func zpopGeneric(c *client, args [][]byte, max bool) {
+	if len(args) > 3 {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	hasCount := len(args) == 3
+	count := int64(1)
+	if hasCount {
+		var ok bool
+		if count, ok = parseInt(args[2]); !ok {
+			c.w.WriteError(errNotInteger.Error())
+			return
+		}
+		if count < 0 {
+			c.w.WriteError(errNotPositive.Error())
+			return
+		}
+		if count == 0 {
+			c.w.WriteArrayHeader(0)
+			return
+		}
+	}
+
+	key := string(args[1])
+	z, err := c.db.getZset(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+	elements := popZset(c.db, key, z, count, max)
+	// Like Redis, a single element comes as a flat member and score, even
+	// in RESP3
+	if !hasCount {
+		c.w.WriteArrayHeader(2 * len(elements))
+		for _, e := range elements {
+			c.w.WriteBulkString(e.member)
+			c.w.WriteDouble(e.score)
+		}
+		return
+	}
+	writeZsetElements(c, elements, true)
+}
+
+// zpopminCommand implements ZPOPMIN key [count]
+func zpopminCommand(c *client, args [][]byte) {
+	zpopGeneric(c, args, false)
+}
+
+// zpopmaxCommand implements ZPOPMAX key [count]
+func zpopmaxCommand(c *client, args [][]byte) {
+	zpopGeneric(c, args, true)
+}
+
+// zrandmemberCommand implements ZRANDMEMBER key [count [WITHSCORES]]. A
+// negative count may return the same member several times.
+func zrandmemberCommand(c *client, args [][]byte) {
+	if len(args) > 4 || (len(args) == 4 && !strings.EqualFold(string(args[3]), "WITHSCORES")) {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	hasCount, withScores := len(args) >= 3, len(args) == 4
+	var count int64
+	if hasCount {
+		var err error
+		if count, err = parseRandomCount(args[2]); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+	}
+
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if !hasCount {
+		if z == nil {
+			c.w.WriteNull()
+			return
+		}
+		c.w.WriteBulkString(z.zsl.byRank(rand.IntN(z.len()) + 1).member)
+		return
+	}
+	if z == nil || count == 0 {
+		c.w.WriteArrayHeader(0)
+		return
+	}
+
+	switch {
+	case count < 0:
+		// The elements are written as they are picked, as there may be
+		// more of them than fit in memory
+		writeZsetElementsHeader(c, int(-count), withScores)
+		for i := int64(0); i < -count; i++ {
+			x := z.zsl.byRank(rand.IntN(z.len()) + 1)
+			writeZsetElement(c, zsetElement{x.member, x.score}, withScores)
+		}
+	case count >= int64(z.len()):
+		writeZsetElements(c, z.elements(), withScores)
+	default:
+		// A partial Fisher-Yates shuffle picks the first count elements
+		chosen := z.elements()
+		for i := 0; i < int(count); i++ {
+			j := i + rand.IntN(len(chosen)-i)
+			chosen[i], chosen[j] = chosen[j], chosen[i]
+		}
+		writeZsetElements(c, chosen[:count], withScores)
+	}
+}
+
+// zsetAggregate is how ZUNION and ZINTER combine the scores a member has in
+// several inputs
+type zsetAggregate int
+
+const (
+	zsetAggregateSum zsetAggregate = iota
+	zsetAggregateMin
+	zsetAggregateMax
+)
+
+// aggregate combines score into total the way agg says. Like in Redis, a
+// sum of inf and -inf is 0 rather than NaN.
+func (agg zsetAggregate) aggregate(total, score float64) float64 {
+	switch agg {
+	case zsetAggregateMin:
+		return min(total, score)
+	case zsetAggregateMax:
+		return max(total, score)
+	}
+	total += score
+	if math.IsNaN(total) {
+		return 0
+	}
+	return total
+}
+
+// zsetSource is an input of ZUNION, ZINTER and ZDIFF. Like in Redis, it
+// may be a sorted set or a set, whose members all have a score of 1.
+type zsetSource struct {
+	z *zset
+	s *set
+}
+
+func (src zsetSource) len() int {
+	if src.z != nil {
+		return src.z.len()
+	}
+	return src.s.len()
+}
+
+func (src zsetSource) score(member string) (float64, bool) {
+	if src.z != nil {
+		return src.z.score(member)
+	}
+	return 1, src.s.contains(member)
+}
+
+// forEach calls fn with every member of src and its score until it returns
+// false
+func (src zsetSource) forEach(fn func(member string, score float64) bool) {
+	if src.z != nil {
+		for member, score := range src.z.dict {
+			if !fn(member, score) {
+				return
+			}
+		}
+		return
+	}
+	src.s.forEach(func(member string) bool {
+		return fn(member, 1)
+	})
+}
+
+// getZsetSources returns the sorted sets or sets stored at keys, with nil
+// for the missing ones. It returns errWrongType if one of the keys holds
+// another type.
+func (ks *keyspace) getZsetSources(keys [][]byte) ([]*zsetSource, error) {
+	sources := make([]*zsetSource, len(keys))
+	for i, key := range keys {
+		value, ok := ks.lookup(string(key))
+		if !ok {
+			continue
+		}
+		switch value := value.(type) {
+		case *zset:
+			sources[i] = &zsetSource{z: value}
+		case *set:
+			sources[i] = &zsetSource{s: value}
+		default:
+			return nil, errWrongType
+		}
+	}
+	return sources, nil
+}
+
+// combineZsets applies op to sources, where nil sources are empty, and
+// returns the result. The scores of a union or intersection are weighted
+// by weights, unless it is nil, and combined by agg, while a difference
+// keeps the scores of the first source. A limit other than zero stops an
+// intersection once it has limit members.
+func combineZsets(op setOperation, sources []*zsetSource, weights []float64, agg zsetAggregate, limit int) *zset {
+	weighted := func(i int, score float64) float64 {
+		if weights == nil {
+			return score
+		}
+		score *= weights[i]
+		if math.IsNaN(score) {
+			return 0
+		}
+		return score
+	}
+	scores := map[string]float64{}
+	switch op {
+	case setUnion:
+		for i, src := range sources {
+			if src == nil {
+				continue
+			}
+			src.forEach(func(member string, score float64) bool {
+				score = weighted(i, score)
+				if total, ok := scores[member]; ok {
+					score = agg.aggregate(total, score)
+				}
+				scores[member] = score
+				return true
+			})
+		}
+	case setInter:
+		smallest := -1
+		for i, src := range sources {
+			if src == nil {
+				// The intersection with an empty set is empty
+				return newZset()
+			}
+			if smallest < 0 || src.len() < sources[smallest].len() {
+				smallest = i
+			}
+		}
+		sources[smallest].forEach(func(member string, _ float64) bool {
+			var total float64
+			for i, src := range sources {
+				score, ok := src.score(member)
+				if !ok {
+					return true
+				}
+				if i == 0 {
+					total = weighted(i, score)
+				} else {
+					total = agg.aggregate(total, weighted(i, score))
+				}
+			}
+			scores[member] = total
+			return limit == 0 || len(scores) < limit
+		})
+	case setDiff:
+		if sources[0] == nil {
+			return newZset()
+		}
+		sources[0].forEach(func(member string, score float64) bool {
+			for _, src := range sources[1:] {
+				if src == nil {
+					continue
+				}
+				if _, ok := src.score(member); ok {
+					return true
+				}
+			}
+			scores[member] = score
+			return true
+		})
+	}
+
+	result := newZset()
+	for member, score := range scores {
+		result.add(member, score)
+	}
+	return result
+}
+
+// zsetOperationGeneric implements ZUNION, ZINTER, ZDIFF and their STORE
+// variants, whose numkeys argument is args[numKeysAt]. With store, the
+// result goes to the key args[1] instead of the reply.
+func zsetOperationGeneric(c *client, args [][]byte, numKeysAt int, store bool, op setOperation) {
+	numKeys, ok := parseInt(args[numKeysAt])
+	if !ok {
+		c.w.WriteError(errNotInteger.Error())
+		return
+	}
+	if numKeys < 1 {
+		c.w.WriteError(fmt.Sprintf("ERR at least 1 input key is needed for '%s' command", strings.ToLower(string(args[0]))))
+		return
+	}
+	if numKeys > int64(len(args)-numKeysAt-1) {
+		c.w.WriteError(errSyntax.Error())
+		return
+	}
+	keys := args[numKeysAt+1 : numKeysAt+1+int(numKeys)]
+	// Like Redis, look the keys up before the options
+	sources, err := c.db.getZsetSources(keys)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	agg := zsetAggregateSum
+	withScores := false
+	options := args[numKeysAt+1+int(numKeys):]
+	for j := 0; j < len(options); j++ {
+		remaining := len(options) - j - 1
+		switch option := strings.ToUpper(string(options[j])); {
+		case option == "WEIGHTS" && op != setDiff && remaining >= len(weights):
+			for i := range weights {
+				if weights[i], ok = parseFloat(options[j+1+i]); !ok {
+					c.w.WriteError("ERR weight value is not a float")
+					return
+				}
+			}
+			j += len(weights)
+		case option == "AGGREGATE" && op != setDiff && remaining >= 1:
+			switch strings.ToUpper(string(options[j+1])) {
+			case "SUM":
+				agg = zsetAggregateSum
+			case "MIN":
+				agg = zsetAggregateMin
+			case "MAX":
+				agg = zsetAggregateMax
+			default:
+				c.w.WriteError(errSyntax.Error())
+				return
+			}
+			j++
+		case option == "WITHSCORES" && !store:
+			withScores = true
+		default:
+			c.w.WriteError(errSyntax.Error())
+			return
+		}
+	}
+
+	result := combineZsets(op, sources, weights, agg, 0)
+	if !store {
+		writeZsetElements(c, result.elements(), withScores)
+		return
+	}
+	dst := string(args[1])
+	if result.len() == 0 {
+		c.db.remove(dst)
+		c.w.WriteInteger(0)
+		return
+	}
+	c.db.set(dst, result, false)
+	c.db.signalKeyAsReady(dst)
+	c.w.WriteInteger(int64(result.len()))
+}
+
+// zunionCommand implements
+// ZUNION numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX] [WITHSCORES]
+func zunionCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 1, false, setUnion)
+}
+
+// zinterCommand implements
+// ZINTER numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX] [WITHSCORES]
+func zinterCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 1, false, setInter)
+}
+
+// zdiffCommand implements ZDIFF numkeys key [key ...] [WITHSCORES]
+func zdiffCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 1, false, setDiff)
+}
+
+// zunionstoreCommand implements
+// ZUNIONSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX]
+func zunionstoreCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 2, true, setUnion)
+}
+
+// zinterstoreCommand implements
+// ZINTERSTORE destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM | MIN | MAX]
+func zinterstoreCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 2, true, setInter)
+}
+
+// zdiffstoreCommand implements ZDIFFSTORE destination numkeys key [key ...]
+func zdiffstoreCommand(c *client, args [][]byte) {
+	zsetOperationGeneric(c, args, 2, true, setDiff)
+}
+
+// zintercardCommand implements ZINTERCARD numkeys key [key ...] [LIMIT limit]
+func zintercardCommand(c *client, args [][]byte) {
+	keys, limit, err := parseIntercardArgs(args[1:])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	sources, err := c.db.getZsetSources(keys)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	c.w.WriteInteger(int64(combineZsets(setInter, sources, nil, zsetAggregateSum, int(limit)).len()))
+}
+
+// zlexcountCommand implements ZLEXCOUNT key min max
+func zlexcountCommand(c *client, args [][]byte) {
+	r, err := parseLexRange(args[2], args[3])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	z, err := c.db.getZset(string(args[1]))
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	c.w.WriteInteger(int64(z.lexCount(r)))
+}
+
+// zremrangeGeneric implements ZREMRANGEBYRANK, ZREMRANGEBYSCORE and
+// ZREMRANGEBYLEX, which remove the members in the range args[2] to args[3]
+// of rangeType
+func zremrangeGeneric(c *client, args [][]byte, rangeType zrangeType) {
+	var start, stop int64
+	var sr scoreRange
+	var lr lexRange
+	var err error
+	switch rangeType {
+	case zrangeRank:
+		var startOk, stopOk bool
+		start, startOk = parseInt(args[2])
+		stop, stopOk = parseInt(args[3])
+		if !startOk || !stopOk {
+			err = errNotInteger
+		}
+	case zrangeScore:
+		sr, err = parseScoreRange(args[2], args[3])
+	case zrangeLex:
+		lr, err = parseLexRange(args[2], args[3])
+	}
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+
+	key := string(args[1])
+	z, err := c.db.getZset(key)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	if z == nil {
+		c.w.WriteInteger(0)
+		return
+	}
+	var elements []zsetElement
+	switch rangeType {
+	case zrangeRank:
+		elements = z.rangeByRank(start, stop, false)
+	case zrangeScore:
+		elements = z.rangeByScore(sr, false, 0, -1)
+	case zrangeLex:
+		elements = z.rangeByLex(lr, false, 0, -1)
+	}
+	for _, e := range elements {
+		z.remove(e.member)
+	}
+	if z.len() == 0 {
+		c.db.remove(key)
+	}
+	c.w.WriteInteger(int64(len(elements)))
+}
+
+// zremrangebyrankCommand implements ZREMRANGEBYRANK key start stop
+func zremrangebyrankCommand(c *client, args [][]byte) {
+	zremrangeGeneric(c, args, zrangeRank)
+}
+
+// zremrangebyscoreCommand implements ZREMRANGEBYSCORE key min max
+func zremrangebyscoreCommand(c *client, args [][]byte) {
+	zremrangeGeneric(c, args, zrangeScore)
+}
+
+// zremrangebylexCommand implements ZREMRANGEBYLEX key min max
+func zremrangebylexCommand(c *client, args [][]byte) {
+	zremrangeGeneric(c, args, zrangeLex)
+}
+
+// bzpopGeneric implements BZPOPMIN key [key ...] timeout and BZPOPMAX. It
+// pops from the first of the keys holding a sorted set, or blocks until one
+// of them does.
+func bzpopGeneric(c *client, args [][]byte, max bool) {
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	keys := make([]string, 0, len(args)-2)
+	for _, key := range args[1 : len(args)-1] {
+		keys = append(keys, string(key))
+	}
+	serve := func(key string) (resp.Value, bool) {
+		z, err := c.db.getZset(key)
+		if err != nil || z == nil {
+			return resp.Value{}, false
+		}
+		e := popZset(c.db, key, z, 1, max)[0]
+		return resp.NewArray(resp.NewBulkString(key), resp.NewBulkString(e.member), resp.NewDouble(e.score)), true
+	}
+	for _, key := range keys {
+		if _, err := c.db.getZset(key); err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+		if reply, ok := serve(key); ok {
+			c.w.WriteValue(reply)
+			return
+		}
+	}
+	c.blockForKeys(keys, timeout, serve, resp.NewNullArray())
+}
+
+// bzpopminCommand implements BZPOPMIN key [key ...] timeout
+func bzpopminCommand(c *client, args [][]byte) {
+	bzpopGeneric(c, args, false)
+}
+
+// bzpopmaxCommand implements BZPOPMAX key [key ...] timeout
+func bzpopmaxCommand(c *client, args [][]byte) {
+	bzpopGeneric(c, args, true)
+}
+
+// parseZsetEnd parses the MIN | MAX argument of ZMPOP and BZMPOP, and
+// reports whether it is MAX
+func parseZsetEnd(arg []byte) (bool, error) {
+	switch strings.ToUpper(string(arg)) {
+	case "MIN":
+		return false, nil
+	case "MAX":
+		return true, nil
+	}
+	return false, errSyntax
+}
+
+// zmpop pops up to count elements from the sorted set at key, and returns
+// the reply of ZMPOP. It returns false if there is no sorted set at key.
+func zmpop(ks *keyspace, key string, max bool, count int64) (resp.Value, bool, error) {
+	z, err := ks.getZset(key)
+	if err != nil || z == nil {
+		return resp.Value{}, false, err
+	}
+	elements := popZset(ks, key, z, count, max)
+	pairs := make([]resp.Value, len(elements))
+	for i, e := range elements {
+		pairs[i] = resp.NewArray(resp.NewBulkString(e.member), resp.NewDouble(e.score))
+	}
+	return resp.NewArray(resp.NewBulkString(key), resp.NewArray(pairs...)), true, nil
+}
+
+// zmpopGeneric implements ZMPOP and BZMPOP, whose arguments from numkeys on
+// are args. It pops from the first of the keys holding a sorted set, and
+// blocks until one of them does if blocking is set.
+func zmpopGeneric(c *client, args [][]byte, blocking bool, timeout time.Duration) {
+	keys, max, count, err := parseMpopArgs(args, parseZsetEnd)
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	for _, key := range keys {
+		reply, ok, err := zmpop(c.db, key, max, count)
+		if err != nil {
+			c.w.WriteError(err.Error())
+			return
+		}
+		if ok {
+			c.w.WriteValue(reply)
+			return
+		}
+	}
+	if !blocking {
+		c.w.WriteNullArray()
+		return
+	}
+	c.blockForKeys(keys, timeout, func(key string) (resp.Value, bool) {
+		reply, ok, _ := zmpop(c.db, key, max, count)
+		return reply, ok
+	}, resp.NewNullArray())
+}
+
+// zmpopCommand implements ZMPOP numkeys key [key ...] MIN | MAX [COUNT count]
+func zmpopCommand(c *client, args [][]byte) {
+	zmpopGeneric(c, args[1:], false, 0)
+}
+
+// bzmpopCommand implements
+// BZMPOP timeout numkeys key [key ...] MIN | MAX [COUNT count]
+func bzmpopCommand(c *client, args [][]byte) {
+	timeout, err := parseTimeout(args[1])
+	if err != nil {
+		c.w.WriteError(err.Error())
+		return
+	}
+	zmpopGeneric(c, args[2:], true, timeout)
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nilayrajderkar/redis-implementation/resp"
+)
+
+func Test_SortedSetCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "It should add members and count the new ones",
+			input: "ZADD z 1 a 2 b\r\nZADD z 3 a 4 c\r\nZCARD z\r\nZSCORE z a\r\nZSCORE z x\r\nZMSCORE z a x c\r\n",
+			want:  ":2\r\n:1\r\n:3\r\n$1\r\n3\r\n$-1\r\n*3\r\n$1\r\n3\r\n$-1\r\n$1\r\n4\r\n",
+		},
+		{
+			name:  "It should format large and fractional scores like Redis",
+			input: "ZADD z 1700000000000 a 1234567 b 1234567.125 c 0.1 d\r\nZSCORE z a\r\nZRANGE z 0 -1 WITHSCORES\r\n",
+			want: ":4\r\n$13\r\n1700000000000\r\n*8\r\n$1\r\nd\r\n$3\r\n0.1\r\n$1\r\nb\r\n$7\r\n1234567\r\n" +
+				"$1\r\nc\r\n$11\r\n1234567.125\r\n$1\r\na\r\n$13\r\n1700000000000\r\n",
+		},
+		{
+			name: "It should only add or update the members the options allow",
+			input: "ZADD z 1 a\r\nZADD z NX 5 a 1 b\r\nZADD z XX 5 a 1 c\r\nZADD z CH 6 a 1 b 1 d\r\nZADD z GT CH 2 a 7 b\r\n" +
+				"ZADD z LT CH 9 a 0 b\r\nZRANGE z 0 -1 WITHSCORES\r\n",
+			want: ":1\r\n:1\r\n:0\r\n:2\r\n:1\r\n:1\r\n" +
+				"*6\r\n$1\r\nb\r\n$1\r\n0\r\n$1\r\nd\r\n$1\r\n1\r\n$1\r\na\r\n$1\r\n6\r\n",
+		},
+		{
+			name: "It should increment scores",
+			input: "ZADD z INCR 1.5 a\r\nZADD z INCR 1 a\r\nZADD z NX INCR 1 a\r\nZINCRBY z -3 a\r\nZINCRBY z 1 b\r\n" +
+				"ZADD missing XX INCR 1 a\r\nEXISTS missing\r\nZADD n inf a\r\nZINCRBY n -inf a\r\nZSCORE n a\r\n",
+			want: "$3\r\n1.5\r\n$3\r\n2.5\r\n$-1\r\n$4\r\n-0.5\r\n$1\r\n1\r\n$-1\r\n:0\r\n" +
+				":1\r\n-ERR resulting score is not a number (NaN)\r\n$3\r\ninf\r\n",
+		},
+		{
+			name: "It should reject invalid ZADD arguments",
+			input: "ZADD z 1 a 2\r\nZADD z NX CH\r\nZADD z NX XX 1 a\r\nZADD z GT LT 1 a\r\nZADD z NX GT 1 a\r\nZADD z INCR 1 a 2 b\r\n" +
+				"ZADD z x a\r\nZADD z nan a\r\nZADD z 1_0 a\r\nZINCRBY z x a\r\nEXISTS z\r\n",
+			want: "-ERR syntax error\r\n-ERR syntax error\r\n-ERR XX and NX options at the same time are not compatible\r\n" +
+				"-ERR GT, LT, and/or NX options at the same time are not compatible\r\n" +
+				"-ERR GT, LT, and/or NX options at the same time are not compatible\r\n" +
+				"-ERR INCR option supports a single increment-element pair\r\n" +
+				"-ERR value is not a valid float\r\n-ERR value is not a valid float\r\n-ERR value is not a valid float\r\n" +
+				"-ERR value is not a valid float\r\n:0\r\n",
+		},
+		{
+			name:  "It should remove members and delete the emptied sorted set",
+			input: "ZADD z 1 a 2 b\r\nZREM z a x\r\nZREM z b\r\nEXISTS z\r\nZREM z b\r\n",
+			want:  ":2\r\n:1\r\n:1\r\n:0\r\n:0\r\n",
+		},
+		{
+			name: "It should describe a missing key as an empty sorted set",
+			input: "ZCARD z\r\nZSCORE z a\r\nZMSCORE z a\r\nZRANK z a\r\nZRANK z a WITHSCORE\r\nZRANGE z 0 -1\r\n" +
+				"ZCOUNT z -inf +inf\r\nZPOPMIN z\r\nZRANDMEMBER z\r\nZRANDMEMBER z 2\r\n",
+			want: ":0\r\n$-1\r\n*1\r\n$-1\r\n$-1\r\n*-1\r\n*0\r\n:0\r\n*0\r\n$-1\r\n*0\r\n",
+		},
+		{
+			name: "It should rank members by score and then by member",
+			input: "ZADD z 1 a 2 c 2 b 3 d\r\nZRANK z a\r\nZRANK z c\r\nZREVRANK z a\r\nZRANK z c WITHSCORE\r\n" +
+				"ZREVRANK z d withscore\r\nZRANK z x\r\nZRANK z a FOO\r\n",
+			want: ":4\r\n:0\r\n:2\r\n:3\r\n*2\r\n:2\r\n$1\r\n2\r\n*2\r\n:0\r\n$1\r\n3\r\n$-1\r\n-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should count the members in a range of scores",
+			input: "ZADD z 1 a 2 b 3 c 4 d\r\nZCOUNT z 2 3\r\nZCOUNT z (2 3\r\nZCOUNT z -inf +inf\r\nZCOUNT z (4 +inf\r\nZCOUNT z 3 2\r\nZCOUNT z x 1\r\n",
+			want:  ":4\r\n:2\r\n:1\r\n:4\r\n:0\r\n:0\r\n-ERR min or max is not a float\r\n",
+		},
+		{
+			name: "It should return ranges of ranks",
+			input: "ZADD z 1 a 2 b 3 c 4 d\r\nZRANGE z 1 2\r\nZRANGE z -2 -1\r\nZRANGE z 0 -1 REV\r\nZREVRANGE z 0 1 WITHSCORES\r\n" +
+				"ZRANGE z 3 1\r\nZRANGE z 2 100\r\nZRANGE z -100 0\r\n",
+			want: ":4\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n*2\r\n$1\r\nc\r\n$1\r\nd\r\n*4\r\n$1\r\nd\r\n$1\r\nc\r\n$1\r\nb\r\n$1\r\na\r\n" +
+				"*4\r\n$1\r\nd\r\n$1\r\n4\r\n$1\r\nc\r\n$1\r\n3\r\n*0\r\n*2\r\n$1\r\nc\r\n$1\r\nd\r\n*1\r\n$1\r\na\r\n",
+		},
+		{
+			name: "It should return ranges of scores",
+			input: "ZADD z 1 a 2 b 3 c 4 d\r\nZRANGE z (1 3 BYSCORE\r\nZRANGE z +inf -inf BYSCORE REV LIMIT 1 2\r\n" +
+				"ZRANGEBYSCORE z -inf +inf LIMIT 2 -1\r\nZREVRANGEBYSCORE z 3 (1 WITHSCORES\r\nZRANGE z 1 4 BYSCORE LIMIT -1 2\r\n",
+			want: ":4\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n*2\r\n$1\r\nc\r\n$1\r\nb\r\n*2\r\n$1\r\nc\r\n$1\r\nd\r\n" +
+				"*4\r\n$1\r\nc\r\n$1\r\n3\r\n$1\r\nb\r\n$1\r\n2\r\n*0\r\n",
+		},
+		{
+			name: "It should return ranges of members",
+			input: "ZADD z 0 a 0 b 0 c 0 d\r\nZRANGE z [b (d BYLEX\r\nZRANGE z - + BYLEX LIMIT 1 2\r\nZRANGE z + [c BYLEX REV\r\n" +
+				"ZRANGEBYLEX z (a [c\r\nZREVRANGEBYLEX z + - LIMIT 0 1\r\nZRANGE z + - BYLEX\r\n",
+			want: ":4\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n*2\r\n$1\r\nb\r\n$1\r\nc\r\n*2\r\n$1\r\nd\r\n$1\r\nc\r\n" +
+				"*2\r\n$1\r\nb\r\n$1\r\nc\r\n*1\r\n$1\r\nd\r\n*0\r\n",
+		},
+		{
+			name: "It should reject invalid ZRANGE arguments",
+			input: "ZRANGE z 0 1 LIMIT 0 1\r\nZRANGE z [a [b BYLEX WITHSCORES\r\nZRANGE z x 1\r\nZRANGE z x 1 BYSCORE\r\n" +
+				"ZRANGE z a b BYLEX\r\nZRANGE z 0 1 FOO\r\nZRANGE z 0 1 BYSCORE BYLEX\r\nZRANGEBYSCORE z 0 1 REV\r\n" +
+				"ZRANGE z 0 1 BYSCORE LIMIT x 1\r\nZRANGESTORE d z 0 1 WITHSCORES\r\n",
+			want: "-ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX\r\n" +
+				"-ERR syntax error, WITHSCORES not supported in combination with BYLEX\r\n" +
+				"-ERR value is not an integer or out of range\r\n-ERR min or max is not a float\r\n" +
+				"-ERR min or max not valid string range item\r\n-ERR syntax error\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n",
+		},
+		{
+			name: "It should store ranges whatever the destination held",
+			input: "ZADD z 1 a 2 b 3 c\r\nSET d v\r\nZRANGESTORE d z 0 1\r\nZRANGE d 0 -1 WITHSCORES\r\n" +
+				"ZRANGESTORE d z +inf (1 BYSCORE REV LIMIT 0 1\r\nZRANGE d 0 -1 WITHSCORES\r\nZRANGESTORE d z 5 10\r\nEXISTS d\r\n" +
+				"ZRANGESTORE d missing 0 -1\r\n",
+			want: ":3\r\n+OK\r\n:2\r\n*4\r\n$1\r\na\r\n$1\r\n1\r\n$1\r\nb\r\n$1\r\n2\r\n:1\r\n*2\r\n$1\r\nc\r\n$1\r\n3\r\n" +
+				":0\r\n:0\r\n:0\r\n",
+		},
+		{
+			name:  "It should pop the members with the lowest and highest scores",
+			input: "ZADD z 1 a 2 b 3 c 4 d\r\nZPOPMIN z\r\nZPOPMAX z 2\r\nZPOPMIN z 0\r\nZPOPMIN z 5\r\nEXISTS z\r\n",
+			want: ":4\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n*4\r\n$1\r\nd\r\n$1\r\n4\r\n$1\r\nc\r\n$1\r\n3\r\n*0\r\n" +
+				"*2\r\n$1\r\nb\r\n$1\r\n2\r\n:0\r\n",
+		},
+		{
+			name:  "It should reject invalid ZPOPMIN counts",
+			input: "ZPOPMIN z -1\r\nZPOPMIN z x\r\nZPOPMIN z 1 2\r\n",
+			want:  "-ERR value is out of range, must be positive\r\n-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n",
+		},
+		{
+			name:  "It should return random members",
+			input: "ZADD z 1 a\r\nZRANDMEMBER z\r\nZRANDMEMBER z -3\r\nZRANDMEMBER z 3 WITHSCORES\r\nZRANDMEMBER z 0\r\n",
+			want:  ":1\r\n$1\r\na\r\n*3\r\n$1\r\na\r\n$1\r\na\r\n$1\r\na\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n*0\r\n",
+		},
+		{
+			name: "It should reject invalid ZRANDMEMBER arguments",
+			input: "ZRANDMEMBER z x\r\nZRANDMEMBER z 1 FOO\r\nZRANDMEMBER z -9223372036854775808\r\n" +
+				"ZADD z 1 a\r\nZRANDMEMBER z -9223372036854775807\r\nZRANDMEMBER z -9223372036854775807 WITHSCORES\r\n",
+			want: "-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n" +
+				"-ERR value is out of range, must be between -9223372036854775807 and 9223372036854775807\r\n" +
+				":1\r\n-ERR value is out of range\r\n-ERR value is out of range\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SortedSetOperationCommands(t *testing.T) {
+	const setup = "ZADD a 1 x 2 y 3 z\r\nZADD b 10 y 20 z 30 w\r\nSADD s x w\r\n"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "It should combine sorted sets and sets",
+			input: setup + "ZUNION 2 a b WITHSCORES\r\nZINTER 2 a b WEIGHTS 2 1 AGGREGATE MAX WITHSCORES\r\nZINTER 2 a s WITHSCORES\r\n" +
+				"ZDIFF 2 b a WITHSCORES\r\nZUNION 3 a b s AGGREGATE MIN\r\n",
+			want: ":3\r\n:3\r\n:2\r\n" +
+				"*8\r\n$1\r\nx\r\n$1\r\n1\r\n$1\r\ny\r\n$2\r\n12\r\n$1\r\nz\r\n$2\r\n23\r\n$1\r\nw\r\n$2\r\n30\r\n" +
+				"*4\r\n$1\r\ny\r\n$2\r\n10\r\n$1\r\nz\r\n$2\r\n20\r\n*2\r\n$1\r\nx\r\n$1\r\n2\r\n*2\r\n$1\r\nw\r\n$2\r\n30\r\n" +
+				"*4\r\n$1\r\nw\r\n$1\r\nx\r\n$1\r\ny\r\n$1\r\nz\r\n",
+		},
+		{
+			name: "It should store the result whatever the destination held",
+			input: setup + "SET d v\r\nZUNIONSTORE d 2 a b WEIGHTS 1 0\r\nZRANGE d 0 -1 WITHSCORES\r\nZINTERSTORE d 2 a missing\r\nEXISTS d\r\n" +
+				"ZDIFFSTORE d 1 a\r\nZCARD d\r\n",
+			want: ":3\r\n:3\r\n:2\r\n+OK\r\n:4\r\n" +
+				"*8\r\n$1\r\nw\r\n$1\r\n0\r\n$1\r\nx\r\n$1\r\n1\r\n$1\r\ny\r\n$1\r\n2\r\n$1\r\nz\r\n$1\r\n3\r\n" +
+				":0\r\n:0\r\n:3\r\n:3\r\n",
+		},
+		{
+			name:  "It should not let infinite scores make NaN",
+			input: "ZADD p inf m\r\nZADD n -inf m\r\nZUNION 2 p n WITHSCORES\r\nZUNION 2 p n WEIGHTS 0 1 WITHSCORES\r\n",
+			want:  ":1\r\n:1\r\n*2\r\n$1\r\nm\r\n$1\r\n0\r\n*2\r\n$1\r\nm\r\n$4\r\n-inf\r\n",
+		},
+		{
+			name:  "It should count the members of an intersection",
+			input: setup + "ZINTERCARD 2 a b\r\nZINTERCARD 2 a b LIMIT 1\r\nZINTERCARD 2 a missing\r\n",
+			want:  ":3\r\n:3\r\n:2\r\n:2\r\n:1\r\n:0\r\n",
+		},
+		{
+			name: "It should reject invalid arguments",
+			input: "ZUNION 0 a\r\nZUNIONSTORE d 0 a\r\nZUNION x a\r\nZUNION 3 a b\r\nZUNION 2 a b WEIGHTS 1\r\nZUNION 2 a b WEIGHTS 1 x\r\n" +
+				"ZUNION 2 a b AGGREGATE AVG\r\nZUNIONSTORE d 1 a WITHSCORES\r\nZDIFF 2 a b WEIGHTS 1 1\r\nZDIFF 1 a AGGREGATE MIN\r\n" +
+				"SET str v\r\nZUNION 2 a str FOO\r\nZINTERCARD 0 a\r\nZINTERCARD 1 a LIMIT -1\r\n",
+			want: "-ERR at least 1 input key is needed for 'zunion' command\r\n-ERR at least 1 input key is needed for 'zunionstore' command\r\n" +
+				"-ERR value is not an integer or out of range\r\n-ERR syntax error\r\n-ERR syntax error\r\n-ERR weight value is not a float\r\n" +
+				"-ERR syntax error\r\n-ERR syntax error\r\n-ERR syntax error\r\n-ERR syntax error\r\n" +
+				"+OK\r\n-" + errWrongType.Error() + "\r\n-ERR numkeys should be greater than 0\r\n-ERR LIMIT can't be negative\r\n",
+		},
+		{
+			name: "It should count and remove ranges",
+			input: "ZADD z 0 a 0 b 0 c 0 d 0 e\r\nZLEXCOUNT z - +\r\nZLEXCOUNT z [b (d\r\nZLEXCOUNT z x y\r\nZREMRANGEBYLEX z (a [c\r\n" +
+				"ZRANGE z 0 -1\r\nZREMRANGEBYRANK z -1 -1\r\nZRANGE z 0 -1\r\nZREMRANGEBYSCORE z -inf (0\r\nZREMRANGEBYSCORE z 0 0\r\n" +
+				"EXISTS z\r\nZREMRANGEBYRANK z 0 -1\r\n",
+			want: ":5\r\n:5\r\n:2\r\n-ERR min or max not valid string range item\r\n:2\r\n" +
+				"*3\r\n$1\r\na\r\n$1\r\nd\r\n$1\r\ne\r\n:1\r\n*2\r\n$1\r\na\r\n$1\r\nd\r\n:0\r\n:2\r\n" +
+				":0\r\n:0\r\n",
+		},
+		{
+			name:  "It should reject invalid ranges to remove",
+			input: "ZREMRANGEBYRANK z x 1\r\nZREMRANGEBYSCORE z x 1\r\n",
+			want:  "-ERR value is not an integer or out of range\r\n-ERR min or max is not a float\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(Config{}).HandleRequest(tt.input); got != tt.want {
+				t.Errorf("HandleRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_SortedSetCommandsWrongType(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("SET str v\r\nZADD z 1 a\r\n")
+
+	wrongType := "-" + errWrongType.Error() + "\r\n"
+	for _, input := range []string{
+		"ZADD str 1 a", "ZINCRBY str 1 a", "ZREM str a", "ZSCORE str a", "ZMSCORE str a", "ZCARD str", "ZCOUNT str 0 1",
+		"ZRANK str a", "ZREVRANK str a", "ZRANGE str 0 1", "ZRANGESTORE d str 0 1", "ZRANGEBYSCORE str 0 1",
+		"ZRANGEBYLEX str - +", "ZPOPMIN str", "ZPOPMAX str 1", "ZRANDMEMBER str", "ZUNION 1 str", "ZINTERSTORE d 2 z str",
+		"ZDIFF 1 str", "ZINTERCARD 1 str", "ZLEXCOUNT str - +", "ZREMRANGEBYRANK str 0 1", "ZREMRANGEBYSCORE str 0 1",
+		"ZREMRANGEBYLEX str - +", "ZMPOP 1 str MIN", "BZPOPMIN str 0", "BZMPOP 0 1 str MAX", "GET z", "SADD z a",
+	} {
+		if got := srv.HandleRequest(input + "\r\n"); got != wrongType {
+			t.Errorf("%s = %q, want %q", input, got, wrongType)
+		}
+	}
+}
+
+func Test_SortedSetCommandsReplyWithDoublesInRESP3(t *testing.T) {
+	got := New(Config{}).HandleRequest("HELLO 3\r\nZADD z 1 a 2.5 b\r\nZSCORE z b\r\nZRANGE z 0 -1 WITHSCORES\r\nZPOPMIN z\r\nZPOPMAX z 1\r\n")
+	want := ":2\r\n,2.5\r\n*2\r\n*2\r\n$1\r\na\r\n,1\r\n*2\r\n$1\r\nb\r\n,2.5\r\n" +
+		"*2\r\n$1\r\na\r\n,1\r\n*1\r\n*2\r\n$1\r\nb\r\n,2.5\r\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("HandleRequest() = %q, want it to end with %q", got, want)
+	}
+}
+
+func Test_SortedSetCommandsRandomMembersAreDistinct(t *testing.T) {
+	srv := New(Config{})
+	srv.HandleRequest("ZADD z 1 a 2 b 3 c 4 d 5 e 6 f\r\n")
+
+	reply, err := resp.Deserialize(srv.HandleRequest("ZRANDMEMBER z 4\r\n"))
+	if err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	var members []string
+	for _, member := range reply.Elems() {
+		members = append(members, member.Str())
+	}
+	sort.Strings(members)
+	if len(members) != 4 {
+		t.Fatalf("ZRANDMEMBER z 4 = %v, want 4 members", members)
+	}
+	for i := 1; i < len(members); i++ {
+		if members[i] == members[i-1] {
+			t.Errorf("ZRANDMEMBER z 4 = %v, want distinct members", members)
+		}
+	}
+}